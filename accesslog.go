@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Access log formats
+const (
+	AccessLogCommon   = "common"
+	AccessLogCombined = "combined"
+	AccessLogJSON     = "json"
+)
+
+// accessLogTimeFormat is the timestamp layout of NCSA access logs
+const accessLogTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// WithAccessLog writes one access log line per request to w, separate
+// from application logs, in AccessLogCommon, AccessLogCombined
+// (Apache/NGINX) or AccessLogJSON format
+func WithAccessLog(format string, w io.Writer) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.accessLog = &accessLogger{format: format, w: w}
+	}
+}
+
+// responseRecorder captures the status code and size of a response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	// beforeHeader runs once before the header is written
+	beforeHeader func()
+}
+
+// WriteHeader implements http.ResponseWriter
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+		if r.beforeHeader != nil {
+			r.beforeHeader()
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// statusCode returns the recorded status, defaulting to 200
+func (r *responseRecorder) statusCode() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// accessLogger writes access log lines
+type accessLogger struct {
+	format string
+	w      io.Writer
+	mu     sync.Mutex
+}
+
+// log writes the access log line for a completed request. The request
+// URI and referer pass through redact, so query tokens are scrubbed.
+func (a *accessLogger) log(r *http.Request, rec *responseRecorder, start time.Time, duration time.Duration, requestID string, redact redactor) error {
+	uri := redact.requestURI(r.RequestURI)
+	referer := redact.string(r.Referer())
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	user := "-"
+	if r.URL.User != nil && r.URL.User.Username() != "" {
+		user = r.URL.User.Username()
+	} else if username, _, ok := r.BasicAuth(); ok && username != "" {
+		user = username
+	}
+
+	var line []byte
+	switch a.format {
+	case AccessLogJSON:
+		line, err = json.Marshal(map[string]interface{}{
+			"time":        start.Format(time.RFC3339Nano),
+			"remote_addr": host,
+			"user":        user,
+			"method":      r.Method,
+			"uri":         uri,
+			"proto":       r.Proto,
+			"status":      rec.statusCode(),
+			"bytes":       rec.bytes,
+			"referer":     referer,
+			"user_agent":  r.UserAgent(),
+			"duration_ms": float64(duration.Microseconds()) / 1000,
+			"request_id":  requestID,
+		})
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+	default:
+		size := "-"
+		if rec.bytes > 0 {
+			size = strconv.Itoa(rec.bytes)
+		}
+		line = []byte(fmt.Sprintf("%s - %s [%s] %s %d %s",
+			host, user, start.Format(accessLogTimeFormat),
+			strconv.Quote(r.Method+" "+uri+" "+r.Proto),
+			rec.statusCode(), size))
+		if a.format == AccessLogCombined {
+			line = append(line, fmt.Sprintf(" %s %s", strconv.Quote(referer), strconv.Quote(r.UserAgent()))...)
+		}
+		line = append(line, '\n')
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, err = a.w.Write(line)
+	return err
+}
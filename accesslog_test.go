@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestAccessLogRedactsRequestURI(t *testing.T) {
+	l := NewLogger("api", zapcore.InfoLevel)
+	l.EnableURLScrubbing(URLScrubConfig{})
+	l.AddRedaction(regexp.MustCompile(`\d{3}-\d{2}-\d{4}`), "[SSN]")
+
+	var out bytes.Buffer
+	handler := HTTPMiddleware(l, WithAccessLog(AccessLogCombined, &out))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/callback?token=s3cr3t&ssn=123-45-6789&page=2", nil)
+	req.Header.Set("Referer", "https://example.com/login?access_token=abc")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := out.String()
+	for _, leaked := range []string{"s3cr3t", "123-45-6789", "access_token=abc"} {
+		if strings.Contains(line, leaked) {
+			t.Errorf("access log leaks %q: %s", leaked, line)
+		}
+	}
+	if !strings.Contains(line, "/callback?token=REDACTED&ssn=[SSN]&page=2") {
+		t.Errorf("access log lost the scrubbed URI: %s", line)
+	}
+}
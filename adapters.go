@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// PrintLogger adapts a Logger to Print-style logger interfaces such as
+// sarama.StdLogger and kafka-go's Logger, so library output flows
+// through this logger's levels and redaction
+type PrintLogger struct {
+	logger *Logger
+	level  LogLevel
+}
+
+// LibraryLogger returns a Print-style adapter logging at level through
+// a child logger named after the library, e.g. "sarama"
+func (l *Logger) LibraryLogger(library string, level LogLevel) *PrintLogger {
+	return &PrintLogger{logger: l.Child(library), level: level}
+}
+
+// Print implements sarama.StdLogger
+func (p *PrintLogger) Print(v ...interface{}) {
+	p.logger.log(p.level, strings.TrimSuffix(fmt.Sprint(v...), "\n"))
+}
+
+// Printf implements sarama.StdLogger and kafka-go's Logger
+func (p *PrintLogger) Printf(format string, v ...interface{}) {
+	p.logger.log(p.level, strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+// Println implements sarama.StdLogger
+func (p *PrintLogger) Println(v ...interface{}) {
+	p.logger.log(p.level, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+// RedisLogger adapts a Logger to go-redis's internal logging interface,
+// set with redis.SetLogger
+type RedisLogger struct {
+	logger *Logger
+	level  LogLevel
+}
+
+// RedisLogger returns an adapter for go-redis logging at level
+func (l *Logger) RedisLogger(level LogLevel) *RedisLogger {
+	return &RedisLogger{logger: l.Child("redis"), level: level}
+}
+
+// Printf implements go-redis's internal.Logging
+func (r *RedisLogger) Printf(ctx context.Context, format string, v ...interface{}) {
+	FromContext(ctx, r.logger).log(r.level, strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+// ElasticTransportLogger adapts a Logger to the elastictransport.Logger
+// interface used by the Elasticsearch client
+type ElasticTransportLogger struct {
+	logger *Logger
+}
+
+// ElasticTransportLogger returns an adapter logging Elasticsearch
+// requests at Debug and failures at Warn
+func (l *Logger) ElasticTransportLogger() *ElasticTransportLogger {
+	return &ElasticTransportLogger{logger: l.Child("elasticsearch")}
+}
+
+// LogRoundTrip implements elastictransport.Logger
+func (e *ElasticTransportLogger) LogRoundTrip(req *http.Request, res *http.Response, err error, start time.Time, dur time.Duration) error {
+	fields := map[string]interface{}{
+		"duration": dur,
+	}
+	if req != nil {
+		fields["method"] = req.Method
+		fields["url"] = req.URL.String()
+	}
+	if res != nil {
+		fields["status"] = res.StatusCode
+	}
+
+	level := zapcore.DebugLevel
+	if err != nil {
+		fields["error"] = err
+		level = zapcore.WarnLevel
+	} else if res != nil && res.StatusCode >= 500 {
+		level = zapcore.WarnLevel
+	}
+
+	e.logger.log(level, "Elasticsearch request", fields)
+	return nil
+}
+
+// RequestBodyEnabled implements elastictransport.Logger
+func (e *ElasticTransportLogger) RequestBodyEnabled() bool {
+	return false
+}
+
+// ResponseBodyEnabled implements elastictransport.Logger
+func (e *ElasticTransportLogger) ResponseBodyEnabled() bool {
+	return false
+}
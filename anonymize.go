@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// AnonymizeIPs truncates IP addresses in the given fields, such as
+// client_ip, zeroing the last octet of IPv4 and the last 80 bits of IPv6
+// addresses, as commonly required for analytics. Ports and IPv6 zones
+// are kept; values that aren't addresses are logged unchanged.
+func (l *Logger) AnonymizeIPs(fields ...string) {
+	l.transforms.set(fieldTransform{kind: "anonymize_ip", apply: AnonymizeIP}, fields...)
+}
+
+// AnonymizeIP truncates an IP address, or the address of an address and
+// port, see AnonymizeIPs
+func AnonymizeIP(s string) string {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return netip.AddrPortFrom(anonymizeAddr(addrPort.Addr()), addrPort.Port()).String()
+	}
+	if addr, err := netip.ParseAddr(strings.TrimSpace(s)); err == nil {
+		return anonymizeAddr(addr).String()
+	}
+	return s
+}
+
+// anonymizeAddr zeroes the host part of an address
+func anonymizeAddr(addr netip.Addr) netip.Addr {
+	bits := 48
+	if addr.Is4() || addr.Is4In6() {
+		bits = 24
+		if addr.Is4In6() {
+			bits += 96
+		}
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return addr
+	}
+	return prefix.Addr().WithZone(addr.Zone())
+}
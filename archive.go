@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ObjectUploader uploads an object to a bucket, e.g. backed by the S3
+// or GCS client libraries
+type ObjectUploader interface {
+	Upload(ctx context.Context, key string, data []byte) error
+}
+
+// ArchiveConfig configures an object store archive handler
+type ArchiveConfig struct {
+	Uploader ObjectUploader
+	// Prefix is prepended to object keys, e.g. "logs/my-service"
+	Prefix string
+	// ChunkSize is the uncompressed size that triggers an upload,
+	// defaults to 16 MiB
+	ChunkSize int
+	// FlushInterval uploads pending entries at least this often,
+	// defaults to 5 minutes
+	FlushInterval time.Duration
+	// UploadTimeout bounds each upload, defaults to 1 minute
+	UploadTimeout time.Duration
+}
+
+// AddArchiveHandler adds a handler that accumulates entries into
+// gzip-compressed NDJSON chunks and uploads them to an object store
+func (l *Logger) AddArchiveHandler(cfg ArchiveConfig, level LogLevel, opts ...HandlerOption) error {
+	if cfg.Uploader == nil {
+		return fmt.Errorf("archive uploader must not be nil")
+	}
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 16 << 20
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Minute
+	}
+	if cfg.UploadTimeout <= 0 {
+		cfg.UploadTimeout = time.Minute
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("archive", true); err != nil {
+		return err
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	archiver := &archiver{cfg: cfg, hostname: hostname}
+	sink := newBatchWriter(batchConfig{
+		maxEntries:    math.MaxInt,
+		maxBytes:      cfg.ChunkSize,
+		flushInterval: cfg.FlushInterval,
+	}, archiver.upload, l.internalErrors.report)
+
+	spec := handlerSpec{kind: "archive", sink: cfg.Prefix, encoder: "json", level: level}
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), sink, handlerOpts).onClose(sink)
+	return nil
+}
+
+// archiver compresses and uploads chunks
+type archiver struct {
+	cfg      ArchiveConfig
+	hostname string
+}
+
+// upload compresses a chunk and uploads it under a time-partitioned key
+func (a *archiver) upload(batch [][]byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, line := range batch {
+		if _, err := zw.Write(line); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	key := path.Join(a.cfg.Prefix, now.Format("2006/01/02/15"),
+		fmt.Sprintf("%d-%s.ndjson.gz", now.UnixNano(), a.hostname))
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.UploadTimeout)
+	defer cancel()
+
+	return a.cfg.Uploader.Upload(ctx, key, buf.Bytes())
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AsyncConfig configures the per-handler workers of EnableAsync
+type AsyncConfig struct {
+	// Capacity is the number of entries queued per handler, defaults
+	// to 1024
+	Capacity int
+	// Policy applies when a handler's queue is full: OverflowBlock,
+	// OverflowDropNewest or OverflowDropOldest. OverflowSpill isn't
+	// supported for queued entries and blocks.
+	Policy OverflowPolicy
+	// Ordered guarantees that every handler receives the entries of a
+	// logger in the order they were logged, stamped with a per-logger
+	// sequence number under logger_seq. Entries are stamped and queued
+	// to all handlers atomically, which serializes logging calls; a
+	// full queue is waited on afterwards. Panic and Fatal entries are
+	// written synchronously outside the order.
+	Ordered bool
+}
+
+// LoggerSequenceKey holds the per-logger sequence number of ordered
+// async entries
+const LoggerSequenceKey = "logger_seq"
+
+// entryOrder stamps entries with per-logger sequence numbers. It is
+// shared by a logger and its children.
+type entryOrder struct {
+	counters map[string]uint64
+	mu       sync.Mutex
+}
+
+// next locks the order and returns the next sequence number of the
+// named logger. The caller must unlock the order once the entry has been
+// queued, before waiting for room in the queues.
+func (o *entryOrder) next(logger string) uint64 {
+	o.mu.Lock()
+	if o.counters == nil {
+		o.counters = map[string]uint64{}
+	}
+	o.counters[logger]++
+	return o.counters[logger]
+}
+
+// EnableAsync runs the writes of each handler added afterwards in its
+// own worker with an independent queue, so a slow file sync or network
+// stall in one handler can't delay entries reaching the console and
+// other handlers. Entries are redacted before they are queued. Values of
+// fields are encoded by the worker, so objects logged by reference must
+// not change afterwards. Sync waits for queued entries, and entries
+// above Error level are written synchronously.
+func (l *Logger) EnableAsync(cfg AsyncConfig) {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1024
+	}
+	l.async.Store(&cfg)
+}
+
+// asyncEntry is an entry queued for a handler
+type asyncEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// handlerWorker writes the queued entries of one handler in order
+type handlerWorker struct {
+	cfg     AsyncConfig
+	queue   []asyncEntry
+	busy    bool
+	stopped bool
+	dropped atomic.Int64
+	changed *sync.Cond
+	mu      sync.Mutex
+	onError func(error)
+}
+
+// newHandlerWorker creates a worker and starts it
+func newHandlerWorker(cfg AsyncConfig, onError func(error)) *handlerWorker {
+	w := &handlerWorker{cfg: cfg, onError: onError}
+	w.changed = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// push queues an entry, applying the overflow policy if the queue is
+// full, and reports whether it was queued
+func (w *handlerWorker) push(entry asyncEntry) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+full:
+	for len(w.queue) >= w.cfg.Capacity && !w.stopped {
+		switch w.cfg.Policy {
+		case OverflowDropNewest:
+			w.dropped.Add(1)
+			return false
+		case OverflowDropOldest:
+			w.queue = w.queue[1:]
+			w.dropped.Add(1)
+		default:
+			// Ordered entries are queued under the order's lock and
+			// wait for room once it is released, see waitForRoom
+			if w.cfg.Ordered {
+				break full
+			}
+			w.changed.Wait()
+		}
+	}
+	if w.stopped {
+		return false
+	}
+
+	w.queue = append(w.queue, entry)
+	w.changed.Broadcast()
+	return true
+}
+
+// run writes queued entries until the worker is stopped
+func (w *handlerWorker) run() {
+	for {
+		w.mu.Lock()
+		for len(w.queue) == 0 && !w.stopped {
+			w.changed.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		e := w.queue[0]
+		w.queue[0] = asyncEntry{}
+		w.queue = w.queue[1:]
+		w.busy = true
+		w.changed.Broadcast()
+		w.mu.Unlock()
+
+		if err := e.core.Write(e.ent, e.fields); err != nil {
+			w.onError(err)
+		}
+
+		w.mu.Lock()
+		w.busy = false
+		w.changed.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// wait blocks until every queued entry has been written
+func (w *handlerWorker) wait() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) > 0 || w.busy {
+		w.changed.Wait()
+	}
+}
+
+// waitForRoom blocks until the queue is below capacity
+func (w *handlerWorker) waitForRoom() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) >= w.cfg.Capacity && !w.stopped {
+		w.changed.Wait()
+	}
+}
+
+// stop writes the remaining entries and ends the worker
+func (w *handlerWorker) stop() {
+	w.mu.Lock()
+	w.stopped = true
+	w.changed.Broadcast()
+	w.mu.Unlock()
+	w.wait()
+}
+
+// queueDepth returns the number of queued entries
+func (w *handlerWorker) queueDepth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.queue)
+}
+
+// droppedCount returns the number of entries discarded by the policy
+func (w *handlerWorker) droppedCount() int64 {
+	return w.dropped.Load()
+}
+
+// workerCore hands writes to a handler's worker
+type workerCore struct {
+	zapcore.Core
+	worker *handlerWorker
+}
+
+// With implements zapcore.Core
+func (c *workerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &workerCore{Core: c.Core.With(fields), worker: c.worker}
+}
+
+// Check implements zapcore.Core
+func (c *workerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (c *workerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// Panic and Fatal entries end the goroutine or process on return
+	if ent.Level > zapcore.ErrorLevel {
+		c.worker.wait()
+		return c.Core.Write(ent, fields)
+	}
+	c.worker.push(asyncEntry{core: c.Core, ent: ent, fields: append([]zapcore.Field{}, fields...)})
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (c *workerCore) Sync() error {
+	c.worker.wait()
+	return c.Core.Sync()
+}
+
+// writeOrdered stamps an entry with the logger's next sequence number
+// and queues it to every handler under the order's lock, then waits for
+// room in the queues
+func (l *Logger) writeOrdered(ce *zapcore.CheckedEntry, fields []zapcore.Field) {
+	func() {
+		seq := l.order.next(l.name)
+		// Marshalers run while queueing and may panic
+		defer l.order.mu.Unlock()
+		ce.Write(append(fields, zap.Uint64(l.mapKey(LoggerSequenceKey), seq))...)
+	}()
+	l.waitForQueues()
+}
+
+// waitForQueues blocks until every handler's queue has room, applying
+// the backpressure that ordered entries skip while the order is locked
+func (l *Logger) waitForQueues() {
+	for _, h := range l.handlers.all() {
+		if h.worker != nil {
+			h.worker.waitForRoom()
+		}
+	}
+}
+
+// newWorkerCore gives a handler its own worker. The caller must hold
+// the lock.
+func (l *Logger) newWorkerCore(state *handlerState, cfg AsyncConfig, core zapcore.Core) zapcore.Core {
+	state.worker = newHandlerWorker(cfg, l.internalErrors.report)
+	queue, dropped := state.queue, state.dropped
+	state.queue = func() int {
+		depth := state.worker.queueDepth()
+		if queue != nil {
+			depth += queue()
+		}
+		return depth
+	}
+	state.dropped = func() int64 {
+		n := state.worker.droppedCount()
+		if dropped != nil {
+			n += dropped()
+		}
+		return n
+	}
+	return &workerCore{Core: core, worker: state.worker}
+}
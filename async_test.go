@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestEnableAsyncWhileLogging(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	unlocked := l.Unlocked()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			unlocked.Info("entry")
+		}
+	}()
+	l.EnableAsync(AsyncConfig{Ordered: true})
+	wg.Wait()
+
+	if unlocked.async.Load() == nil {
+		t.Error("async mode not shared with relatives")
+	}
+}
+
+func TestOrderedFullQueueReleasesOrder(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.EnableAsync(AsyncConfig{Capacity: 1, Ordered: true})
+	observed, logs := observer.New(zapcore.InfoLevel)
+	stalled := &stalledCore{Core: observed, release: make(chan struct{})}
+	l.mu.Lock()
+	l.registerHandler(newHandlerState(handlerSpec{kind: "stalled"}, handlerOptions{}), stalled)
+	l.mu.Unlock()
+
+	// One entry is written, one queued and the rest wait for room
+	const n = 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Info("entry")
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for l.handlers.all()[0].worker.queueDepth() < n-1 {
+		if time.Now().After(deadline) {
+			t.Fatal("entries were not queued while the queue was full")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !l.order.mu.TryLock() {
+		t.Fatal("order is locked while waiting for room")
+	}
+	l.order.mu.Unlock()
+
+	close(stalled.release)
+	wg.Wait()
+	if err := l.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	for i, entry := range logs.All() {
+		if got := entry.ContextMap()[LoggerSequenceKey]; got != uint64(i+1) {
+			t.Errorf("entry %d has sequence %v", i, got)
+		}
+	}
+}
+
+// panickingObject panics when encoded
+type panickingObject struct{}
+
+func (panickingObject) MarshalLogObject(zapcore.ObjectEncoder) error {
+	panic("marshal failed")
+}
+
+func TestOrderedPanicReleasesOrder(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.InfoLevel)
+	l.mu.Lock()
+	l.registerHandler(newHandlerState(handlerSpec{kind: "json"}, handlerOptions{}), core)
+	l.mu.Unlock()
+	l.EnableAsync(AsyncConfig{Ordered: true})
+
+	// Handlers added before async mode encode while the entry is queued
+	func() {
+		defer func() { recover() }()
+		l.WithoutRedaction().Info("boom", map[string]interface{}{"obj": panickingObject{}})
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Info("after")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("order stayed locked after a panic")
+	}
+}
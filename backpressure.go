@@ -0,0 +1,357 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// OverflowPolicy decides what happens to entries written to a handler
+// whose buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the writer wait for space in the buffer
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the entry being written
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest buffered entry
+	OverflowDropOldest
+	// OverflowSpill appends entries to a file until the buffer drains
+	OverflowSpill
+)
+
+// BackpressureConfig configures a handler's buffer
+type BackpressureConfig struct {
+	Policy OverflowPolicy
+	// Capacity is the number of buffered entries, defaults to 1024
+	Capacity int
+	// SpillPath is the file used by OverflowSpill
+	SpillPath string
+	// Acknowledge persists how far the spill file was confirmed by the
+	// sink in SpillPath+".ack", so spilled entries are neither lost nor
+	// repeated across restarts: entries left over from a previous run
+	// are delivered first on startup. Entries are acknowledged once the
+	// sink's Write succeeds.
+	Acknowledge bool
+	// DrainTimeout bounds how long Sync and removing the handler wait for
+	// buffered entries while the sink is down, defaults to 5 seconds
+	DrainTimeout time.Duration
+}
+
+// WithBackpressure decouples a handler from the logging pipeline with a
+// bounded buffer drained in the background, so a slow sink doesn't stall
+// other handlers. It applies to handlers writing encoded entries to a
+// sink; others, such as fluentd and journald, fail to be added with it.
+func WithBackpressure(cfg BackpressureConfig) HandlerOption {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = 1024
+	}
+	if cfg.DrainTimeout <= 0 {
+		cfg.DrainTimeout = 5 * time.Second
+	}
+	return func(o *handlerOptions) {
+		o.backpressure = &cfg
+	}
+}
+
+// boundedBuffer is a FIFO of encoded entries applying an overflow policy
+// when it reaches capacity
+type boundedBuffer struct {
+	cfg      BackpressureConfig
+	entries  [][]byte
+	spill    *os.File
+	ack      *spillAck
+	spilling bool
+	busy     bool
+	closed   bool
+	dropped  atomic.Int64
+	changed  *sync.Cond
+	mu       sync.Mutex
+}
+
+// newBoundedBuffer creates an empty buffer
+func newBoundedBuffer(cfg BackpressureConfig) *boundedBuffer {
+	b := &boundedBuffer{cfg: cfg}
+	b.changed = sync.NewCond(&b.mu)
+	return b
+}
+
+// errBufferClosed is returned for entries written after a handler's
+// buffer was closed
+var errBufferClosed = errors.New("backpressure buffer closed")
+
+// errDrainTimeout is returned when buffered entries weren't written
+// within the drain timeout
+var errDrainTimeout = errors.New("backpressure buffer not drained in time")
+
+// push adds an entry, applying the overflow policy if the buffer is full
+func (b *boundedBuffer) push(entry []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errBufferClosed
+	}
+
+	// Keep spilling until the spill file is drained to preserve ordering
+	if b.spilling {
+		_, err := b.spill.Write(spillRecord(entry))
+		return err
+	}
+
+	for len(b.entries) >= b.cfg.Capacity {
+		switch b.cfg.Policy {
+		case OverflowDropNewest:
+			b.dropped.Add(1)
+			return nil
+		case OverflowDropOldest:
+			b.entries = b.entries[1:]
+			b.dropped.Add(1)
+		case OverflowSpill:
+			if b.spill == nil {
+				spill, err := os.OpenFile(b.cfg.SpillPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+				if err != nil {
+					return err
+				}
+				b.spill = spill
+			}
+			b.spilling = true
+			_, err := b.spill.Write(spillRecord(entry))
+			return err
+		default:
+			b.changed.Wait()
+			if b.closed {
+				return errBufferClosed
+			}
+		}
+	}
+
+	b.entries = append(b.entries, entry)
+	b.changed.Broadcast()
+	return nil
+}
+
+// pop waits for entries and removes them from the buffer together with
+// any spilled entries, which are older than the buffered ones once the
+// buffer is empty. The buffer is busy until done is called. It returns
+// errBufferClosed once the buffer is closed.
+func (b *boundedBuffer) pop() (entries [][]byte, spilled []byte, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.entries) == 0 && !b.spilling && !b.closed {
+		b.changed.Wait()
+	}
+	if b.closed {
+		return nil, nil, errBufferClosed
+	}
+	b.busy = true
+
+	if len(b.entries) > 0 {
+		entries = b.entries
+		b.entries = nil
+		b.changed.Broadcast()
+		return entries, nil, nil
+	}
+
+	// Drain the spill file, keeping it until the sink confirms entries
+	// if they are acknowledged. Acknowledging buffers keep spilling until
+	// the file is confirmed, so entries being retried stay ahead of
+	// newer ones.
+	if b.ack != nil {
+		spilled, err = b.readUnacked()
+		return nil, spilled, err
+	}
+	b.spilling = false
+	spilled, err = os.ReadFile(b.cfg.SpillPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, spilled, b.spill.Truncate(0)
+}
+
+// done marks popped entries as written
+func (b *boundedBuffer) done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.busy = false
+	b.changed.Broadcast()
+}
+
+// wait blocks until every entry has been written, or the drain timeout
+// expires
+func (b *boundedBuffer) wait() error {
+	deadline := time.Now().Add(b.cfg.DrainTimeout)
+	timer := time.AfterFunc(b.cfg.DrainTimeout, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.changed.Broadcast()
+	})
+	defer timer.Stop()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for len(b.entries) > 0 || b.spilling || b.busy {
+		if !time.Now().Before(deadline) {
+			return errDrainTimeout
+		}
+		b.changed.Wait()
+	}
+	return nil
+}
+
+// close stops the buffer, releasing blocked writers and the drain loop,
+// and closes the spill files
+func (b *boundedBuffer) close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	b.changed.Broadcast()
+
+	var errs []error
+	if b.spill != nil {
+		errs = append(errs, b.spill.Close())
+	}
+	if b.ack != nil {
+		errs = append(errs, b.ack.file.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// queueDepth returns the number of buffered entries
+func (b *boundedBuffer) queueDepth() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.entries)
+}
+
+// droppedCount returns the number of entries discarded by the policy
+func (b *boundedBuffer) droppedCount() int64 {
+	return b.dropped.Load()
+}
+
+// asyncWriter buffers encoded entries and writes them to its sink in the
+// background. It implements zapcore.WriteSyncer.
+type asyncWriter struct {
+	*boundedBuffer
+	sink    zapcore.WriteSyncer
+	onError func(error)
+}
+
+// newAsyncWriter creates an async writer and starts draining its buffer
+func newAsyncWriter(sink zapcore.WriteSyncer, cfg BackpressureConfig, onError func(error)) *asyncWriter {
+	w := &asyncWriter{boundedBuffer: newBoundedBuffer(cfg), sink: sink, onError: onError}
+	if cfg.Policy == OverflowSpill && cfg.Acknowledge {
+		if err := w.resumeSpill(); err != nil {
+			onError(err)
+		}
+	}
+	go w.run()
+	return w
+}
+
+// run writes buffered entries to the sink
+func (w *asyncWriter) run() {
+	for {
+		entries, spilled, err := w.pop()
+		if err == errBufferClosed {
+			return
+		}
+		if err != nil {
+			w.onError(err)
+			if w.ack != nil {
+				time.Sleep(spillAckRetry)
+			}
+		}
+		if w.ack != nil && spilled != nil {
+			w.writeAcked(spilled)
+		} else if len(spilled) > 0 {
+			w.writeSpilled(spilled)
+		}
+		for _, entry := range entries {
+			if _, err := w.sink.Write(entry); err != nil {
+				w.onError(err)
+			}
+		}
+		w.done()
+	}
+}
+
+// writeSpilled writes spilled entries one by one, since sinks such as
+// HTTP and message queue clients expect a single entry per write
+func (w *asyncWriter) writeSpilled(spilled []byte) {
+	for len(spilled) > 0 {
+		entry, n, ok := spilledEntry(spilled)
+		if !ok {
+			w.onError(errSpillTruncated)
+			return
+		}
+		if _, err := w.sink.Write(entry); err != nil {
+			w.onError(err)
+		}
+		spilled = spilled[n:]
+	}
+}
+
+// spillHeaderLen is the size of the length prefix of spilled entries,
+// which keeps entries containing newlines whole
+const spillHeaderLen = 4
+
+// errSpillTruncated is reported for a spilled entry cut short, as left
+// by a crash while spilling
+var errSpillTruncated = errors.New("spill file ends with a truncated entry")
+
+// spillRecord prefixes an entry with its length for the spill file
+func spillRecord(entry []byte) []byte {
+	record := make([]byte, spillHeaderLen, spillHeaderLen+len(entry))
+	binary.BigEndian.PutUint32(record, uint32(len(entry)))
+	return append(record, entry...)
+}
+
+// spilledEntry returns the first entry in spilled and the length of its
+// record, or false if the record is incomplete
+func spilledEntry(spilled []byte) (entry []byte, n int, ok bool) {
+	if len(spilled) < spillHeaderLen {
+		return nil, len(spilled), false
+	}
+	size := binary.BigEndian.Uint32(spilled)
+	if uint64(size) > uint64(len(spilled)-spillHeaderLen) {
+		return nil, len(spilled), false
+	}
+	n = spillHeaderLen + int(size)
+	return spilled[spillHeaderLen:n], n, true
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	// The encoder reuses its buffer, so keep a copy
+	if err := w.push(append([]byte{}, p...)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync waits for buffered entries to be written and syncs the sink
+func (w *asyncWriter) Sync() error {
+	return errors.Join(w.wait(), w.sink.Sync())
+}
+
+// Close writes buffered entries, then stops draining the buffer.
+// Acknowledged entries not written in time are kept in the spill file
+// for the next run.
+func (w *asyncWriter) Close() error {
+	return errors.Join(w.wait(), w.close())
+}
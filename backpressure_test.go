@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingSink records each write, blocking until released
+type recordingSink struct {
+	release chan struct{}
+	writes  []string
+	mu      sync.Mutex
+}
+
+func (s *recordingSink) Write(p []byte) (int, error) {
+	<-s.release
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writes = append(s.writes, string(p))
+	return len(p), nil
+}
+
+func (s *recordingSink) Sync() error { return nil }
+
+func TestSpilledEntriesAreWrittenOneByOne(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	w := newAsyncWriter(sink, BackpressureConfig{
+		Policy:       OverflowSpill,
+		Capacity:     1,
+		SpillPath:    filepath.Join(t.TempDir(), "spill.log"),
+		DrainTimeout: 5 * time.Second,
+	}, func(err error) { t.Error(err) })
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		entry := fmt.Sprintf("entry %d\n", i)
+		want = append(want, entry)
+		if _, err := w.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(sink.release)
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(sink.writes, ""); got != strings.Join(want, "") {
+		t.Fatalf("sink got %q, want entries in order", got)
+	}
+	for _, write := range sink.writes {
+		if strings.Count(write, "\n") != 1 {
+			t.Errorf("write %q holds more than one entry", write)
+		}
+	}
+}
+
+func TestBackpressureOptionsAreValidated(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := l.AddFileHandler(path, zapcore.InfoLevel, WithBackpressure(BackpressureConfig{Policy: OverflowSpill})); err == nil {
+		t.Error("OverflowSpill without SpillPath was accepted")
+	}
+	if err := l.AddJournaldHandler(zapcore.InfoLevel, WithBackpressure(BackpressureConfig{})); err == nil {
+		t.Error("WithBackpressure was accepted by a journald handler")
+	}
+	if n := len(l.Handlers()); n != 0 {
+		t.Errorf("%d handlers added with invalid options", n)
+	}
+}
+
+// flakySink fails its first write, signalling it, and records the rest
+type flakySink struct {
+	failed chan struct{}
+	writes []string
+	mu     sync.Mutex
+}
+
+func (s *flakySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failed != nil {
+		close(s.failed)
+		s.failed = nil
+		return 0, errors.New("sink down")
+	}
+	s.writes = append(s.writes, string(p))
+	return len(p), nil
+}
+
+func (s *flakySink) Sync() error { return nil }
+
+func TestAcknowledgedEntriesAreRetriedInOrder(t *testing.T) {
+	spillPath := filepath.Join(t.TempDir(), "spill.log")
+	spilled := append(spillRecord([]byte("old 0\n")), spillRecord([]byte("old 1\n"))...)
+	if err := os.WriteFile(spillPath, spilled, 0644); err != nil {
+		t.Fatal(err)
+	}
+	failed := make(chan struct{})
+	sink := &flakySink{failed: failed}
+	w := newAsyncWriter(sink, BackpressureConfig{
+		Policy:       OverflowSpill,
+		Capacity:     4,
+		SpillPath:    spillPath,
+		Acknowledge:  true,
+		DrainTimeout: 5 * time.Second,
+	}, func(error) {})
+	defer w.Close()
+
+	<-failed
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write([]byte(fmt.Sprintf("new %d\n", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "old 0\nold 1\nnew 0\nnew 1\n"
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if got := strings.Join(sink.writes, ""); got != want {
+		t.Errorf("sink got %q, want %q", got, want)
+	}
+}
+
+func TestSyncGivesUpWhileSinkIsDown(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	defer close(sink.release)
+	w := newAsyncWriter(sink, BackpressureConfig{Capacity: 4, DrainTimeout: 10 * time.Millisecond}, func(error) {})
+
+	if _, err := w.Write([]byte("entry\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Sync(); !errors.Is(err, errDrainTimeout) {
+		t.Errorf("Sync = %v, want drain timeout", err)
+	}
+}
+
+func TestSpilledMultilineEntriesStayWhole(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	w := newAsyncWriter(sink, BackpressureConfig{
+		Policy:       OverflowSpill,
+		Capacity:     1,
+		SpillPath:    filepath.Join(t.TempDir(), "spill.log"),
+		DrainTimeout: 5 * time.Second,
+	}, func(err error) { t.Error(err) })
+
+	want := []string{"first\n", "panic: boom\n\tmain.go:1\n", "last\n"}
+	for _, entry := range want {
+		if _, err := w.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(sink.release)
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(sink.writes) != fmt.Sprint(want) {
+		t.Errorf("sink got %q, want %q", sink.writes, want)
+	}
+}
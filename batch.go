@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// batchConfig configures a batchWriter
+type batchConfig struct {
+	maxEntries    int
+	maxBytes      int
+	flushInterval time.Duration
+}
+
+// maxPendingBatches bounds the entries kept for a failing sink, in full
+// batches; older entries are dropped beyond it
+const maxPendingBatches = 10
+
+// batchWriter accumulates encoded entries and hands them to flush in
+// batches when the batch is full, on a timer, and on Sync. Full batches
+// are flushed by a background goroutine, so slow sinks don't stall
+// logging calls. Failed batches are kept and retried on the next tick.
+// It implements zapcore.WriteSyncer.
+type batchWriter struct {
+	cfg     batchConfig
+	flush   func(batch [][]byte) error
+	onError func(error)
+	entries [][]byte
+	size    int
+	dropped atomic.Int64
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	closed  sync.Once
+	mu      sync.Mutex
+	// flushMu serializes flushes, keeping batches in order
+	flushMu sync.Mutex
+}
+
+// newBatchWriter creates a batch writer and starts its flusher
+func newBatchWriter(cfg batchConfig, flush func([][]byte) error, onError func(error)) *batchWriter {
+	if cfg.maxEntries <= 0 {
+		cfg.maxEntries = 500
+	}
+	if cfg.maxBytes <= 0 {
+		cfg.maxBytes = 1 << 20
+	}
+	if cfg.flushInterval <= 0 {
+		cfg.flushInterval = 5 * time.Second
+	}
+
+	w := &batchWriter{
+		cfg:     cfg,
+		flush:   flush,
+		onError: onError,
+		full:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run flushes full batches and the pending batch periodically until
+// Close. After a failure, it waits for the next tick to retry.
+func (w *batchWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.cfg.flushInterval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		full := w.full
+		if failing {
+			full = nil
+		}
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		case <-full:
+		}
+
+		err := w.flushPending()
+		if err != nil && w.onError != nil {
+			w.onError(err)
+		}
+		failing = err != nil
+	}
+}
+
+// Write adds an encoded entry to the batch, waking the flusher if it is
+// full
+func (w *batchWriter) Write(p []byte) (int, error) {
+	// The encoder reuses its buffer, so keep a copy
+	entry := append([]byte{}, p...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.entries = append(w.entries, entry)
+	w.size += len(entry)
+	w.trimLocked()
+	if len(w.entries) >= w.cfg.maxEntries || w.size >= w.cfg.maxBytes {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync flushes the pending batch
+func (w *batchWriter) Sync() error {
+	return w.flushPending()
+}
+
+// queueDepth returns the number of entries waiting to be flushed
+func (w *batchWriter) queueDepth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.entries)
+}
+
+// droppedCount returns the number of entries dropped while the sink failed
+func (w *batchWriter) droppedCount() int64 {
+	return w.dropped.Load()
+}
+
+// Close stops the flusher and flushes the pending batch
+func (w *batchWriter) Close() error {
+	w.closed.Do(func() { close(w.done) })
+	<-w.stopped
+	return w.Sync()
+}
+
+// flushPending hands the pending batch to flush, keeping it for a retry
+// if flushing fails
+func (w *batchWriter) flushPending() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	batch := w.entries
+	w.entries = nil
+	w.size = 0
+	w.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+	err := w.flush(batch)
+	var partial *partialFlushError
+	switch {
+	case errors.As(err, &partial):
+		w.requeue(partial.rest)
+	case err != nil:
+		w.requeue(batch)
+	}
+	return err
+}
+
+// partialFlushError is returned by a flush that wrote part of a batch,
+// so only the unwritten rest is retried
+type partialFlushError struct {
+	err  error
+	rest [][]byte
+}
+
+// Error implements error
+func (e *partialFlushError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *partialFlushError) Unwrap() error {
+	return e.err
+}
+
+// requeue puts a failed batch back in front of the entries written since
+func (w *batchWriter) requeue(batch [][]byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, entry := range batch {
+		w.size += len(entry)
+	}
+	w.entries = append(batch, w.entries...)
+	w.trimLocked()
+}
+
+// trimLocked drops the oldest entries beyond maxPendingBatches full
+// batches. The caller must hold the lock.
+func (w *batchWriter) trimLocked() {
+	maxEntries, maxBytes := maxPendingBatches*w.cfg.maxEntries, maxPendingBatches*w.cfg.maxBytes
+	n := 0
+	for len(w.entries)-n > maxEntries || (w.size > maxBytes && n < len(w.entries)-1) {
+		w.size -= len(w.entries[n])
+		n++
+	}
+	if n > 0 {
+		w.entries = append([][]byte{}, w.entries[n:]...)
+		w.dropped.Add(int64(n))
+	}
+}
+
+// BatchConfig configures WithBatching
+type BatchConfig struct {
+	// MaxEntries flushes the batch once it holds this many entries,
+	// defaults to 500
+	MaxEntries int
+	// MaxBytes flushes the batch once it reaches this size, defaults to 1MB
+	MaxBytes int
+	// FlushInterval flushes the batch periodically, defaults to 5s
+	FlushInterval time.Duration
+}
+
+// WithBatching groups entries written to a handler's sink, writing each
+// batch with a single call. This reduces syscalls for file and network
+// sinks at high rates, at the cost of entries reaching the sink later.
+// Batches are written in the background; entries above Error level and
+// Sync flush the batch before returning. Failed batches are retried
+// with the next flush, keeping up to ten batches of entries.
+func WithBatching(cfg BatchConfig) HandlerOption {
+	return func(o *handlerOptions) {
+		o.batching = &cfg
+	}
+}
+
+// batchSink joins batches of entries into single writes to a sink.
+// It implements zapcore.WriteSyncer.
+type batchSink struct {
+	*batchWriter
+	sink    zapcore.WriteSyncer
+	scratch []byte
+}
+
+// newBatchSink creates a batch sink writing to sink
+func newBatchSink(sink zapcore.WriteSyncer, cfg BatchConfig, onError func(error)) *batchSink {
+	s := &batchSink{sink: sink}
+	s.batchWriter = newBatchWriter(batchConfig{
+		maxEntries:    cfg.MaxEntries,
+		maxBytes:      cfg.MaxBytes,
+		flushInterval: cfg.FlushInterval,
+	}, s.write, onError)
+	return s
+}
+
+// write joins a batch of newline-terminated entries into one write.
+// Batches are flushed one at a time, so the scratch buffer is not shared.
+// After a short write, only the bytes not written are retried.
+func (s *batchSink) write(batch [][]byte) error {
+	s.scratch = s.scratch[:0]
+	for _, entry := range batch {
+		s.scratch = append(s.scratch, entry...)
+	}
+	n, err := s.sink.Write(s.scratch)
+	if err == nil || n <= 0 {
+		return err
+	}
+
+	for i, entry := range batch {
+		if n < len(entry) {
+			rest := append([][]byte{entry[n:]}, batch[i+1:]...)
+			return &partialFlushError{err: err, rest: rest}
+		}
+		n -= len(entry)
+	}
+	return &partialFlushError{err: err}
+}
+
+// Sync flushes the pending batch and syncs the sink
+func (s *batchSink) Sync() error {
+	if err := s.batchWriter.Sync(); err != nil {
+		return err
+	}
+	return s.sink.Sync()
+}
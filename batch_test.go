@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchWriterFlushesInBackground(t *testing.T) {
+	release := make(chan struct{})
+	flushed := make(chan [][]byte, 4)
+	w := newBatchWriter(batchConfig{maxEntries: 2, flushInterval: time.Hour}, func(batch [][]byte) error {
+		<-release
+		flushed <- batch
+		return nil
+	}, nil)
+	defer w.Close()
+
+	// A slow sink must not block writers
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 4; i++ {
+			w.Write([]byte("entry\n"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Write blocked on a slow flush")
+	}
+
+	close(release)
+	if batch := <-flushed; len(batch) < 2 {
+		t.Errorf("flushed %d entries, want a full batch", len(batch))
+	}
+}
+
+func TestBatchWriterRetriesFailedBatches(t *testing.T) {
+	var mu sync.Mutex
+	var written []string
+	fail := true
+	w := newBatchWriter(batchConfig{maxEntries: 100, flushInterval: time.Hour}, func(batch [][]byte) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return errors.New("sink down")
+		}
+		for _, entry := range batch {
+			written = append(written, string(entry))
+		}
+		return nil
+	}, nil)
+	defer w.Close()
+
+	w.Write([]byte("a"))
+	if err := w.Sync(); err == nil {
+		t.Fatal("Sync succeeded while the sink was down")
+	}
+	w.Write([]byte("b"))
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(written, ","); got != "a,b" {
+		t.Errorf("written %q, want the failed entry retried in order", got)
+	}
+}
+
+func TestBatchWriterBoundsPendingEntries(t *testing.T) {
+	w := newBatchWriter(batchConfig{maxEntries: 1, flushInterval: time.Hour}, func([][]byte) error {
+		return errors.New("sink down")
+	}, nil)
+	defer w.Close()
+
+	for i := 0; i < 3*maxPendingBatches; i++ {
+		w.Write([]byte("x"))
+	}
+	if depth := w.queueDepth(); depth > maxPendingBatches {
+		t.Errorf("queue holds %d entries, want at most %d", depth, maxPendingBatches)
+	}
+	if w.droppedCount() == 0 {
+		t.Error("no entries counted as dropped")
+	}
+}
+
+// shortSink accepts limit bytes of its first write, failing it, and
+// everything after
+type shortSink struct {
+	limit   int
+	written strings.Builder
+	failed  bool
+}
+
+func (s *shortSink) Write(p []byte) (int, error) {
+	if !s.failed {
+		s.failed = true
+		s.written.Write(p[:s.limit])
+		return s.limit, errors.New("short write")
+	}
+	return s.written.Write(p)
+}
+
+func (s *shortSink) Sync() error { return nil }
+
+func TestBatchSinkRetriesOnlyUnwrittenRest(t *testing.T) {
+	sink := &shortSink{limit: len("entry 0\nent")}
+	w := newBatchSink(sink, BatchConfig{MaxEntries: 100, FlushInterval: time.Hour}, nil)
+	defer w.Close()
+
+	for i := 0; i < 3; i++ {
+		w.Write([]byte("entry " + string(rune('0'+i)) + "\n"))
+	}
+	if err := w.Sync(); err == nil {
+		t.Fatal("Sync hid the short write")
+	}
+	if err := w.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := sink.written.String(), "entry 0\nentry 1\nentry 2\n"; got != want {
+		t.Errorf("sink got %q, want %q", got, want)
+	}
+}
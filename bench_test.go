@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// discard registers a JSON handler writing to io.Discard, as the file
+// handler would without the I/O
+func discard(l *Logger) {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.InfoLevel)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	spec := handlerSpec{kind: "discard", level: zapcore.InfoLevel}
+	l.registerHandler(newHandlerState(spec, newHandlerOptions(nil)), core)
+}
+
+// benchLogger returns a logger writing to the given number of discard
+// handlers, optionally with typical redaction rules
+func benchLogger(handlers int, redact bool) *Logger {
+	l := NewLogger("bench", zapcore.InfoLevel)
+	if redact {
+		l.AddRedaction(regexp.MustCompile(`\b\d{16}\b`), "[CARD]")
+		l.AddRedactFields("password", "token")
+	}
+	for i := 0; i < handlers; i++ {
+		discard(l)
+	}
+	return l
+}
+
+var benchFields = map[string]interface{}{
+	"user":     "alice",
+	"attempt":  3,
+	"path":     "/api/orders",
+	"password": "hunter2",
+}
+
+func BenchmarkRawZap(b *testing.B) {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.InfoLevel)
+	logger := zap.New(core).With(zap.String("logger", "bench"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request handled",
+			zap.String("user", "alice"),
+			zap.Int("attempt", 3),
+			zap.String("path", "/api/orders"),
+			zap.String("password", "hunter2"))
+	}
+}
+
+func BenchmarkMapAPI(b *testing.B) {
+	l := benchLogger(1, false)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", benchFields)
+	}
+}
+
+func BenchmarkMapAPIWithRedaction(b *testing.B) {
+	l := benchLogger(1, true)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", benchFields)
+	}
+}
+
+func BenchmarkMapAPIWithoutRedaction(b *testing.B) {
+	l := benchLogger(1, true).WithoutRedaction()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", benchFields)
+	}
+}
+
+func BenchmarkMapAPIUnlocked(b *testing.B) {
+	l := benchLogger(1, true).Unlocked()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", benchFields)
+	}
+}
+
+func BenchmarkTypedAPI(b *testing.B) {
+	logger := benchLogger(1, false).Sugar().Desugar()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request handled",
+			zap.String("user", "alice"),
+			zap.Int("attempt", 3),
+			zap.String("path", "/api/orders"),
+			zap.String("password", "hunter2"))
+	}
+}
+
+func BenchmarkTypedAPIWithRedaction(b *testing.B) {
+	logger := benchLogger(1, true).Sugar().Desugar()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request handled",
+			zap.String("user", "alice"),
+			zap.Int("attempt", 3),
+			zap.String("path", "/api/orders"),
+			zap.String("password", "hunter2"))
+	}
+}
+
+func BenchmarkTypedAPISkipRedaction(b *testing.B) {
+	logger := benchLogger(1, true).Sugar().Desugar()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request handled",
+			zap.String("user", "alice"),
+			zap.Int("attempt", 3),
+			zap.String("path", "/api/orders"),
+			zap.String("password", "hunter2"),
+			SkipRedaction())
+	}
+}
+
+func BenchmarkMultipleHandlers(b *testing.B) {
+	for _, handlers := range []int{1, 3, 5} {
+		l := benchLogger(handlers, true)
+		b.Run(fmt.Sprintf("%d handlers", handlers), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				l.Info("request handled", benchFields)
+			}
+		})
+	}
+}
+
+func BenchmarkRawZapWith(b *testing.B) {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), zapcore.InfoLevel)
+	logger := zap.New(core).With(zap.String("logger", "bench"), zap.String("request_id", "abc123"), zap.String("user", "alice"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Info("request handled", zap.Int("attempt", 3))
+	}
+}
+
+func BenchmarkWithContext(b *testing.B) {
+	l := benchLogger(1, true).WithContext(map[string]interface{}{"request_id": "abc123", "user": "alice"})
+	fields := map[string]interface{}{"attempt": 3}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", fields)
+	}
+}
+
+func BenchmarkWith(b *testing.B) {
+	l := benchLogger(1, true).With(zap.String("request_id", "abc123"), zap.String("user", "alice"))
+	fields := map[string]interface{}{"attempt": 3}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.Info("request handled", fields)
+	}
+}
+
+func BenchmarkWithContextCreated(b *testing.B) {
+	l := benchLogger(1, true)
+	context := map[string]interface{}{"request_id": "abc123", "user": "alice"}
+	fields := map[string]interface{}{"attempt": 3}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.WithContext(context).Info("request handled", fields)
+	}
+}
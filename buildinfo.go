@@ -0,0 +1,49 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+)
+
+// Field keys for build and environment information
+const (
+	GitCommitKey = "git_commit"
+	BuildTimeKey = "build_time"
+	GoVersionKey = "go_version"
+	EnvKey       = "env"
+)
+
+// buildInfoFields returns build information read from the binary and
+// the deployment environment
+func buildInfoFields(env string) []zap.Field {
+	fields := []zap.Field{zap.String(GoVersionKey, runtime.Version())}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				fields = append(fields, zap.String(GitCommitKey, setting.Value))
+			case "vcs.time":
+				fields = append(fields, zap.String(BuildTimeKey, setting.Value))
+			}
+		}
+	}
+
+	if env != "" {
+		fields = append(fields, zap.String(EnvKey, env))
+	}
+	return fields
+}
+
+// AddBuildInfo attaches git_commit, build_time, go_version and env fields
+// to every entry of this logger and loggers derived from it afterwards
+func (l *Logger) AddBuildInfo(env string) {
+	fields := buildInfoFields(env)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.context = append(l.context, fields...)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// callerFilters drop entries by the package or file of the code that
+// logged them. They are shared by a logger and its children.
+type callerFilters struct {
+	filters atomic.Pointer[[]callerFilter]
+	mu      sync.Mutex
+}
+
+// callerFilter sets the minimum level of entries logged from callers
+// matching pattern
+type callerFilter struct {
+	pattern string
+	level   LogLevel
+}
+
+// matches reports whether a caller's package or file matches the filter.
+// Packages match by path prefix and files by substring.
+func (f callerFilter) matches(pkg, file string) bool {
+	return pkg == f.pattern || strings.HasPrefix(pkg, f.pattern+"/") || strings.Contains(file, f.pattern)
+}
+
+// load returns the configured filters
+func (c *callerFilters) load() []callerFilter {
+	if filters := c.filters.Load(); filters != nil {
+		return *filters
+	}
+	return nil
+}
+
+// set replaces the filter for pattern, removing it if remove is set
+func (c *callerFilters) set(pattern string, level LogLevel, remove bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := c.load()
+	filters := make([]callerFilter, 0, len(current)+1)
+	for _, f := range current {
+		if f.pattern != pattern {
+			filters = append(filters, f)
+		}
+	}
+	if !remove {
+		filters = append(filters, callerFilter{pattern: pattern, level: level})
+	}
+	c.filters.Store(&filters)
+}
+
+// drop reports whether an entry at level from the current caller is
+// filtered out. The stack is only walked when filters exist.
+func (c *callerFilters) drop(level LogLevel) bool {
+	filters := c.load()
+	if len(filters) == 0 {
+		return false
+	}
+	frame, ok := callerFrame()
+	if !ok {
+		return false
+	}
+
+	pkg := functionPackage(frame.Function)
+	for _, f := range filters {
+		if f.matches(pkg, frame.File) && level < f.level {
+			return true
+		}
+	}
+	return false
+}
+
+// SetCallerFilter drops entries below level logged from code matching
+// pattern, before they reach any handler. Patterns match package paths
+// and their subpackages, e.g. "github.com/noisy/lib", or any part of the
+// file path, e.g. "vendor/noisy-lib/". Entries through adapters such as
+// LibraryLogger are attributed to the code calling the adapter.
+func (l *Logger) SetCallerFilter(pattern string, level LogLevel) {
+	l.callerFilters.set(pattern, level, false)
+}
+
+// SilenceCaller drops all entries logged from code matching pattern
+func (l *Logger) SilenceCaller(pattern string) {
+	l.SetCallerFilter(pattern, zapcore.FatalLevel+1)
+}
+
+// ClearCallerFilter removes the filter for pattern
+func (l *Logger) ClearCallerFilter(pattern string) {
+	l.callerFilters.set(pattern, 0, true)
+}
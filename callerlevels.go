@@ -0,0 +1,95 @@
+package main
+
+import (
+	"runtime"
+	"strings"
+)
+
+// EnableCallerLevels derives an implicit logger name from the package
+// of the code calling the logger, so levels set with SetPackageLevel
+// apply per package even when packages share one logger. Packages
+// without a level fall back to the logger's own level.
+func (l *Logger) EnableCallerLevels(enabled bool) {
+	l.callerLevels.Store(enabled)
+}
+
+// SetPackageLevel sets the level for entries logged from a package and
+// its subpackages, e.g. "github.com/acme/app/auth". It requires
+// EnableCallerLevels.
+func (l *Logger) SetPackageLevel(pkgPath string, level LogLevel) {
+	l.packageLevels.set(pkgPath, level)
+}
+
+// ClearPackageLevel removes a level set by SetPackageLevel
+func (l *Logger) ClearPackageLevel(pkgPath string) {
+	l.packageLevels.clear(pkgPath)
+}
+
+// callerLevel returns the level configured for the calling package, if
+// caller levels are enabled
+func (l *Logger) callerLevel() (LogLevel, bool) {
+	if !l.callerLevels.Load() {
+		return 0, false
+	}
+	pkg := callerPackage()
+	if pkg == "" {
+		return 0, false
+	}
+	return l.packageLevels.lookup(pkg)
+}
+
+// callerPackage returns the package path of the code calling the logger
+func callerPackage() string {
+	frame, ok := callerFrame()
+	if !ok {
+		return ""
+	}
+	return functionPackage(frame.Function)
+}
+
+// loggerPackage is the package path of this logger
+var loggerPackage = functionPackage(strings.TrimSuffix(loggerMethodPrefix, ".(*Logger)."))
+
+// callerFrame returns the first frame on the stack outside the logger:
+// its Logger methods, its adapters such as PrintLogger, and the standard
+// log package, so entries routed through adapters keep their origin
+func callerFrame() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 32)
+	// Skip runtime.Callers and callerFrame
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	// Skip internal helpers until the logging path, then leave it
+	inLogger := false
+	for {
+		frame, more := frames.Next()
+		if isLoggerFrame(frame.Function) {
+			inLogger = true
+		} else if inLogger {
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+// isLoggerFrame reports whether a function belongs to the logging path:
+// methods of Logger or of adapter types named *Logger in this package,
+// zap itself for the Sugar API, or the standard log package
+func isLoggerFrame(function string) bool {
+	if strings.HasPrefix(function, loggerMethodPrefix) || strings.HasPrefix(function, "go.uber.org/zap") || functionPackage(function) == "log" {
+		return true
+	}
+	return functionPackage(function) == loggerPackage && strings.Contains(function, "Logger).")
+}
+
+// functionPackage returns the package path of a qualified function name
+// such as "github.com/acme/app.(*T).Method"
+func functionPackage(name string) string {
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
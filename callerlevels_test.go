@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The tests call the logger from its own package, named like the
+// loggers below
+
+func TestPackageLevelsAreSeparateFromLoggerLevels(t *testing.T) {
+	l := NewLogger(loggerPackage, zapcore.DebugLevel)
+	l.EnableCallerLevels(true)
+	logs := observe(l, zapcore.DebugLevel)
+
+	l.SetPackageLevel(loggerPackage, zapcore.ErrorLevel)
+	l.SetLoggerLevel(loggerPackage, zapcore.DebugLevel)
+	l.Warn("from the logger package")
+
+	if logs.Len() != 0 {
+		t.Errorf("package level was overridden by the logger level")
+	}
+	if levels := l.LoggerLevels(); len(levels) != 1 || levels[loggerPackage] != zapcore.DebugLevel {
+		t.Errorf("LoggerLevels = %v, want only the logger level", levels)
+	}
+}
+
+func TestClearLoggerLevelKeepsPackageLevel(t *testing.T) {
+	l := NewLogger(loggerPackage, zapcore.DebugLevel)
+	l.EnableCallerLevels(true)
+	logs := observe(l, zapcore.DebugLevel)
+
+	l.SetPackageLevel(loggerPackage, zapcore.ErrorLevel)
+	l.SetLoggerLevel(loggerPackage, zapcore.InfoLevel)
+	l.ClearLoggerLevel(loggerPackage)
+	l.Warn("from the logger package")
+
+	if logs.Len() != 0 {
+		t.Errorf("package level was cleared with the logger level")
+	}
+}
+
+func TestPackageLevelsMatchPathSegments(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.SetPackageLevel("github.com/acme/foo", zapcore.ErrorLevel)
+	l.SetPackageLevel("github", zapcore.DebugLevel)
+
+	for pkg, want := range map[string]bool{
+		"github.com/acme/foo":     true,
+		"github.com/acme/foo/bar": true,
+		"github.com/acme/foo.v2":  false,
+		"github.com/acme/other":   false,
+	} {
+		level, ok := l.packageLevels.lookup(pkg)
+		if ok != want || ok && level != zapcore.ErrorLevel {
+			t.Errorf("%s: level %v set %v, want package level set %v", pkg, level, ok, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// catalogRegistry holds the registered message catalogs by locale
+var catalogRegistry = struct {
+	catalogs map[string]map[string]string
+	mu       sync.RWMutex
+}{catalogs: map[string]map[string]string{}}
+
+// RegisterCatalog adds translated event messages keyed by event code for
+// a locale such as "de" or "pt-BR", merging them into any messages
+// already registered for it
+func RegisterCatalog(locale string, messages map[string]string) error {
+	locale = normalizeLocale(locale)
+	if locale == "" {
+		return fmt.Errorf("catalog locale must not be empty")
+	}
+
+	catalogRegistry.mu.Lock()
+	defer catalogRegistry.mu.Unlock()
+
+	catalog := catalogRegistry.catalogs[locale]
+	if catalog == nil {
+		catalog = map[string]string{}
+		catalogRegistry.catalogs[locale] = catalog
+	}
+	for code, msg := range messages {
+		catalog[code] = msg
+	}
+	return nil
+}
+
+// LoadCatalog registers a catalog read from a JSON object mapping event
+// codes to messages
+func LoadCatalog(locale string, r io.Reader) error {
+	var messages map[string]string
+	if err := json.NewDecoder(r).Decode(&messages); err != nil {
+		return fmt.Errorf("catalog %s: %w", locale, err)
+	}
+	return RegisterCatalog(locale, messages)
+}
+
+// SetLocale renders event messages of the logger and its relatives in
+// locale, falling back to its base language (e.g. "pt" for "pt-BR") and
+// then to the event's registered message. Event codes and fields are
+// unaffected. An empty locale disables translation.
+func (l *Logger) SetLocale(locale string) {
+	locale = normalizeLocale(locale)
+	l.locale.Store(&locale)
+}
+
+// translate returns the message for an event code in the logger's locale
+func (l *Logger) translate(code, fallback string) string {
+	locale := l.locale.Load()
+	if locale == nil || *locale == "" {
+		return fallback
+	}
+
+	catalogRegistry.mu.RLock()
+	defer catalogRegistry.mu.RUnlock()
+
+	for tag := *locale; tag != ""; {
+		if msg, ok := catalogRegistry.catalogs[tag][code]; ok {
+			return msg
+		}
+		i := strings.LastIndexByte(tag, '-')
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return fallback
+}
+
+// normalizeLocale lowercases a locale and uses hyphens, so "pt_BR" and
+// "pt-br" match
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ClockOffsetKey is the field holding the estimated offset of the local
+// clock from the reference clock, in milliseconds. Positive values mean
+// the local clock is behind.
+const ClockOffsetKey = "clock_offset_ms"
+
+// ClockSkewConfig configures WatchClockSkew
+type ClockSkewConfig struct {
+	// Server is the NTP server, defaults to "pool.ntp.org:123"
+	Server string
+	// Interval between measurements, defaults to 10 minutes
+	Interval time.Duration
+	// Timeout bounds each measurement, defaults to 5 seconds
+	Timeout time.Duration
+	// WarnAbove logs a warning when the offset exceeds it; zero never
+	// warns
+	WarnAbove time.Duration
+}
+
+// clockSkew holds the latest clock offset measurement. It is shared by a
+// logger and its children.
+type clockSkew struct {
+	measured atomic.Bool
+	offset   atomic.Int64
+}
+
+// UseUTC makes every handler that has no explicit time zone write UTC
+// timestamps, taking effect immediately. It eases correlating logs
+// across hosts in different zones.
+func (l *Logger) UseUTC(enabled bool) {
+	l.utc.Store(enabled)
+}
+
+// utcTimeEncoder converts timestamps to UTC while UseUTC is enabled
+func utcTimeEncoder(utc *atomic.Bool, enc zapcore.TimeEncoder) zapcore.TimeEncoder {
+	return func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		if utc.Load() {
+			t = t.UTC()
+		}
+		enc(t, pae)
+	}
+}
+
+// WatchClockSkew measures the local clock's offset from an NTP server
+// every interval until ctx is done, annotating entries with the latest
+// estimate so logs from hosts with skewed clocks can be aligned. Failed
+// measurements keep the last estimate.
+func (l *Logger) WatchClockSkew(ctx context.Context, cfg ClockSkewConfig) error {
+	if cfg.Server == "" {
+		cfg.Server = "pool.ntp.org:123"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		offset, err := queryNTPOffset(ctx, cfg.Server, cfg.Timeout)
+		if err != nil {
+			l.Warn("Clock skew measurement failed", map[string]interface{}{
+				"server": cfg.Server,
+				"error":  err.Error(),
+			})
+		} else {
+			l.clock.offset.Store(int64(offset))
+			l.clock.measured.Store(true)
+			if cfg.WarnAbove > 0 && (offset > cfg.WarnAbove || offset < -cfg.WarnAbove) {
+				l.Warn("Clock skew exceeds threshold", map[string]interface{}{
+					"server":    cfg.Server,
+					"threshold": cfg.WarnAbove.String(),
+				})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			l.clock.measured.Store(false)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ClockOffset returns the latest estimated clock offset, and false if
+// none was measured
+func (l *Logger) ClockOffset() (time.Duration, bool) {
+	return time.Duration(l.clock.offset.Load()), l.clock.measured.Load()
+}
+
+// fields returns the clock offset field, once measured
+func (c *clockSkew) fields() []zap.Field {
+	if !c.measured.Load() {
+		return nil
+	}
+	offset := time.Duration(c.offset.Load())
+	return []zap.Field{zap.Float64(ClockOffsetKey, float64(offset.Microseconds())/1000)}
+}
+
+// ntpEpochOffset is the number of seconds between 1900 and 1970
+const ntpEpochOffset = 2208988800
+
+// queryNTPOffset estimates the local clock's offset from an NTP server
+// with a single SNTP request
+func queryNTPOffset(ctx context.Context, server string, timeout time.Duration) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// Version 4, client mode
+	req := make([]byte, 48)
+	req[0] = 4<<3 | 3
+
+	sent := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, err
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	received := time.Now()
+	if err != nil {
+		return 0, err
+	}
+	if n < 48 || resp[0]&0x7 != 4 {
+		return 0, fmt.Errorf("invalid NTP response from %s", server)
+	}
+
+	serverReceived := ntpTime(resp[32:40])
+	serverSent := ntpTime(resp[40:48])
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// ntpTime decodes a 64-bit NTP timestamp
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:])) * 1e9 >> 32
+	return time.Unix(secs, frac)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ColorMode selects whether console handlers color their output
+type ColorMode int
+
+const (
+	// ColorAuto colors output written to a terminal. NO_COLOR disables
+	// colors and FORCE_COLOR or CLICOLOR_FORCE enable them regardless of
+	// the terminal, NO_COLOR taking precedence.
+	ColorAuto ColorMode = iota
+	// ColorAlways colors output, e.g. for CI logs rendering ANSI colors
+	ColorAlways
+	// ColorNever writes plain output
+	ColorNever
+)
+
+// String returns the lowercase name of the mode
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAuto:
+		return "auto"
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	}
+	return fmt.Sprintf("ColorMode(%d)", int(m))
+}
+
+// ParseColorMode parses a mode name such as "never"
+func ParseColorMode(name string) (ColorMode, error) {
+	for m := ColorAuto; m <= ColorNever; m++ {
+		if strings.EqualFold(name, m.String()) {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown color mode %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (m ColorMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for config files
+func (m *ColorMode) UnmarshalText(text []byte) error {
+	parsed, err := ParseColorMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// SetColorMode sets the color mode of console handlers added afterwards
+func (l *Logger) SetColorMode(mode ColorMode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.colorMode = mode
+}
+
+// WithColorMode overrides the logger's color mode for a console handler
+func WithColorMode(mode ColorMode) HandlerOption {
+	return func(o *handlerOptions) {
+		o.colorMode = &mode
+	}
+}
+
+// useColor reports whether a console handler writing to f colors its
+// output, falling back to plain output on consoles that can't render
+// escape codes
+func useColor(mode ColorMode, f *os.File) bool {
+	return wantsColor(mode, f) && enableANSI(f)
+}
+
+// wantsColor applies the color mode and environment conventions
+func wantsColor(mode ColorMode, f *os.File) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if envForcesColor("FORCE_COLOR") || envForcesColor("CLICOLOR_FORCE") {
+		return true
+	}
+	return isTerminal(f)
+}
+
+// envForcesColor reports whether an environment variable is set to
+// anything other than 0 or false
+func envForcesColor(name string) bool {
+	value, ok := os.LookupEnv(name)
+	return ok && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// enableANSI is a no-op, terminals outside Windows interpret escape codes
+func enableANSI(f *os.File) bool {
+	return true
+}
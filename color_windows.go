@@ -0,0 +1,43 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Console API not covered by package syscall
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode     = kernel32.NewProc("SetConsoleMode")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+)
+
+const (
+	enableVirtualTerminalProcessing = 0x0004
+	codePageUTF8                    = 65001
+)
+
+// enableANSI prepares a Windows console for the handler's output: it
+// switches the output code page to UTF-8, so symbols and other
+// non-ASCII text render instead of mojibake, and enables virtual
+// terminal processing so cmd.exe and PowerShell interpret escape codes.
+// It reports false on consoles without virtual terminal support, such
+// as those of Windows versions before 10. Redirected output is left
+// alone.
+func enableANSI(f *os.File) bool {
+	handle := syscall.Handle(f.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		// Not a console: a pipe, file or a terminal emulator like mintty
+		return true
+	}
+
+	procSetConsoleOutputCP.Call(codePageUTF8)
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := procSetConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+}
@@ -1,13 +1,207 @@
 package main
 
-import "regexp"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
 
 type Config struct {
-	Name         string
+	Name string
+	Env  string
+	// Profile presets defaults for settings left unset: development
+	// for colored console output at Debug level, staging for JSON on
+	// stdout, production for JSON on stdout sampled during log storms
+	Profile string
+	// TwelveFactor writes a single JSON stream to stdout and disables
+	// file handlers, see EnableTwelveFactor
+	TwelveFactor bool
 	Level        LogLevel
 	Development  bool
+	// ColorMode colors console output: auto, always or never
+	ColorMode    ColorMode
 	ConsoleLevel *LogLevel
 	FileConfig   map[string]LogLevel
 	RedactRegex  map[*regexp.Regexp]string
 	RedactFields []string
+	KeyMapping   map[string]string
+	TimeFormat   string
+	TimeZone     string
+	// UTC writes timestamps in UTC unless TimeZone is set
+	UTC          bool
+	FieldClasses map[string]Sensitivity
+	// PseudonymizeFields are identifier fields logged as keyed hashes,
+	// using the key in the environment variable named by PseudonymKeyEnv
+	PseudonymizeFields []string
+	PseudonymKeyEnv    string
+	// AnonymizeIPFields are fields whose IP addresses are truncated
+	AnonymizeIPFields []string
+	// Sanitize strips terminal escapes and control characters from
+	// messages and string values
+	Sanitize bool
+	// EscapeNewlines escapes line breaks in messages and string values
+	EscapeNewlines bool
+	// Locale selects the message catalog event messages are rendered in
+	Locale string
+	// Resource is an OpenTelemetry resource, e.g. *resource.Resource,
+	// whose attributes are added to every entry
+	Resource fmt.Stringer
+	// Handlers adds console and file handlers, each with its own level,
+	// name and encoding
+	Handlers []HandlerConfig
+}
+
+// Validate checks the configuration, returning an error per problem that
+// names the offending setting and how to fix it
+func (c Config) Validate() error {
+	var errs []error
+
+	if err := validateProfile(c.Profile); err != nil {
+		errs = append(errs, err)
+	}
+	if c.TwelveFactor && len(c.FileConfig) > 0 {
+		errs = append(errs, fmt.Errorf("FileConfig: file handlers are disabled by TwelveFactor; remove them or turn TwelveFactor off"))
+	}
+	if c.TwelveFactor && len(c.Handlers) > 0 {
+		errs = append(errs, fmt.Errorf("Handlers: TwelveFactor writes only to stdout; remove them and set ConsoleLevel instead"))
+	}
+	if err := validateLevel("Level", c.Level); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ConsoleLevel != nil {
+		if err := validateLevel("ConsoleLevel", *c.ConsoleLevel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	paths := make([]string, 0, len(c.FileConfig))
+	for path := range c.FileConfig {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		level := c.FileConfig[path]
+		setting := fmt.Sprintf("FileConfig[%q]", path)
+		if err := validateLevel(setting, level); err != nil {
+			errs = append(errs, err)
+		}
+		if err := checkWritable(path); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", setting, err))
+		}
+	}
+
+	for regex, replacement := range c.RedactRegex {
+		if regex == nil || regex.String() == "" {
+			errs = append(errs, fmt.Errorf("RedactRegex: pattern for replacement %q is empty and would match everywhere; remove it or set a pattern", replacement))
+		}
+	}
+	for i, key := range c.RedactFields {
+		if strings.TrimSpace(key) == "" {
+			errs = append(errs, fmt.Errorf("RedactFields[%d]: field key is empty; remove it", i))
+		}
+	}
+
+	for from, to := range c.KeyMapping {
+		if from == "" || to == "" {
+			errs = append(errs, fmt.Errorf("KeyMapping: mapping %q to %q has an empty key; both sides must be set", from, to))
+		}
+	}
+
+	if c.ColorMode < ColorAuto || c.ColorMode > ColorNever {
+		errs = append(errs, fmt.Errorf("ColorMode: unknown color mode %d; use auto, always or never", c.ColorMode))
+	}
+
+	if _, err := newTimeEncoder(c.TimeFormat, c.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("TimeFormat/TimeZone: %w", err))
+	}
+
+	for i, h := range c.Handlers {
+		setting := fmt.Sprintf("Handlers[%d]", i)
+		if err := validateLevel(setting, h.Level); err != nil {
+			errs = append(errs, err)
+		}
+		switch h.Encoder {
+		case "", EncodingJSON, EncodingConsole, EncodingLogfmt, EncodingECS:
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown encoder %q; use json, console, logfmt or ecs", setting, h.Encoder))
+		}
+		if h.Theme != "" {
+			if _, err := ThemeByName(h.Theme); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", setting, err))
+			}
+		}
+		switch h.Kind {
+		case "console":
+		case "file":
+			if err := checkWritable(h.Sink); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", setting, err))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: unknown kind %q; use console or file", setting, h.Kind))
+		}
+	}
+
+	for key, class := range c.FieldClasses {
+		if class < SensitivityPublic || class > SensitivitySecret {
+			errs = append(errs, fmt.Errorf("FieldClasses[%q]: unknown sensitivity %d; use public, internal, pii or secret", key, class))
+		}
+	}
+
+	if len(c.PseudonymizeFields) > 0 {
+		if c.PseudonymKeyEnv == "" {
+			errs = append(errs, fmt.Errorf("PseudonymKeyEnv: required by PseudonymizeFields; name the environment variable holding the key"))
+		} else if os.Getenv(c.PseudonymKeyEnv) == "" {
+			errs = append(errs, fmt.Errorf("PseudonymKeyEnv: environment variable %s is not set; set it to the key shared by all services", c.PseudonymKeyEnv))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateLevel checks that level is one of zap's levels
+func validateLevel(setting string, level LogLevel) error {
+	if level < zapcore.DebugLevel || level > zapcore.FatalLevel {
+		return fmt.Errorf("%s: unknown level %d; use debug, info, warn, error, dpanic, panic or fatal", setting, level)
+	}
+	return nil
+}
+
+// checkWritable reports why a log file could not be written, without
+// creating it
+func checkWritable(path string) error {
+	if path == "" {
+		return fmt.Errorf("path is empty")
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory; name a file inside it", path)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+		if err != nil {
+			return fmt.Errorf("file is not writable: %w", err)
+		}
+		return f.Close()
+	}
+
+	dir := filepath.Dir(path)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory %s does not exist; create it first", dir)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	probe, err := os.CreateTemp(dir, ".zap_logger_probe_*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
 }
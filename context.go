@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type loggerContextKey struct{}
+
+// ContextWithLogger returns a copy of ctx carrying the logger
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx, or fallback if there is none
+func FromContext(ctx context.Context, fallback *Logger) *Logger {
+	if l, ok := ctx.Value(loggerContextKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return fallback
+}
+
+// DebugContext logs a message at Debug level in the scope of ctx
+func (l *Logger) DebugContext(ctx context.Context, msg string, fields ...map[string]interface{}) {
+	l.logContext(ctx, zapcore.DebugLevel, msg, fields...)
+}
+
+// InfoContext logs a message at Info level in the scope of ctx
+func (l *Logger) InfoContext(ctx context.Context, msg string, fields ...map[string]interface{}) {
+	l.logContext(ctx, zapcore.InfoLevel, msg, fields...)
+}
+
+// WarnContext logs a message at Warn level in the scope of ctx
+func (l *Logger) WarnContext(ctx context.Context, msg string, fields ...map[string]interface{}) {
+	l.logContext(ctx, zapcore.WarnLevel, msg, fields...)
+}
+
+// ErrorContext logs a message at Error level in the scope of ctx
+func (l *Logger) ErrorContext(ctx context.Context, msg string, fields ...map[string]interface{}) {
+	l.logContext(ctx, zapcore.ErrorLevel, msg, fields...)
+}
+
+// FatalContext logs a message at Fatal level in the scope of ctx
+func (l *Logger) FatalContext(ctx context.Context, msg string, fields ...map[string]interface{}) {
+	l.logContext(ctx, zapcore.FatalLevel, msg, fields...)
+}
+
+// logContext writes a message at the given level, applying
+// context-aware features before the entry is logged
+func (l *Logger) logContext(ctx context.Context, level LogLevel, msg string, fields ...map[string]interface{}) {
+	var entryFields map[string]interface{}
+	if len(fields) > 0 {
+		entryFields = fields[0]
+	}
+
+	// Mirror failures onto the active span before a Fatal entry exits
+	if level >= zapcore.ErrorLevel {
+		l.mirrorToSpan(ctx, level, msg, entryFields)
+	}
+
+	l.write(ctx, level, msg, entryFields)
+}
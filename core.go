@@ -1,85 +1,259 @@
 package main
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 
 	"go.uber.org/zap/zapcore"
 )
 
 // multiCoreSyncWrapper wraps multiple zapcore.Core implementations
-// and provides thread-safe access to the collection
+// and provides thread-safe access to the collection. The collection is
+// immutable and swapped atomically on change, so entries are written
+// without locking.
 type multiCoreSyncWrapper struct {
-	cores []zapcore.Core
-	mu    sync.RWMutex
+	set atomic.Pointer[coreSet]
+	mu  sync.Mutex
+}
+
+// coreSet is an immutable collection of cores
+type coreSet struct {
+	cores   []zapcore.Core
+	version uint64
 }
 
 // Enabled implements zapcore.Core
 func (m *multiCoreSyncWrapper) Enabled(lvl zapcore.Level) bool {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return coresEnabled(m.load().cores, lvl)
+}
 
-	for _, core := range m.cores {
-		if core.Enabled(lvl) {
-			return true
+// With implements zapcore.Core. The returned core bakes fields into each
+// handler once and follows handlers added to the wrapper later.
+func (m *multiCoreSyncWrapper) With(fields []zapcore.Field) zapcore.Core {
+	return &contextCore{parent: m, layers: []func() []zapcore.Field{staticFields(fields)}}
+}
+
+// Check implements zapcore.Core
+func (m *multiCoreSyncWrapper) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkCores(m.load().cores, ent, ce)
+}
+
+// Write implements zapcore.Core
+func (m *multiCoreSyncWrapper) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeCores(m.load().cores, ent, fields)
+}
+
+// writeAt writes to every core accepting lvl
+func (m *multiCoreSyncWrapper) writeAt(lvl zapcore.Level, ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeCoresAt(m.load().cores, lvl, ent, fields)
+}
+
+// Sync implements zapcore.Core
+func (m *multiCoreSyncWrapper) Sync() error {
+	return syncCores(m.load().cores)
+}
+
+// AddCore adds a new zapcore.Core to the wrapper
+func (m *multiCoreSyncWrapper) AddCore(core zapcore.Core) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.load()
+	cores := make([]zapcore.Core, 0, len(current.cores)+1)
+	cores = append(append(cores, current.cores...), core)
+	m.set.Store(&coreSet{cores: cores, version: current.version + 1})
+}
+
+// RemoveCore removes a core from the wrapper, reporting whether it was
+// present
+func (m *multiCoreSyncWrapper) RemoveCore(core zapcore.Core) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	current := m.load()
+	cores := make([]zapcore.Core, 0, len(current.cores))
+	for _, c := range current.cores {
+		if c != core {
+			cores = append(cores, c)
 		}
 	}
-	return false
+	if len(cores) == len(current.cores) {
+		return false
+	}
+	m.set.Store(&coreSet{cores: cores, version: current.version + 1})
+	return true
 }
 
-// With implements zapcore.Core
-func (m *multiCoreSyncWrapper) With(fields []zapcore.Field) zapcore.Core {
+// ReplaceCore swaps a core for another in place, reporting whether it
+// was present
+func (m *multiCoreSyncWrapper) ReplaceCore(old, core zapcore.Core) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	cores := make([]zapcore.Core, 0, len(m.cores))
-	for _, core := range m.cores {
+	current := m.load()
+	cores := append([]zapcore.Core{}, current.cores...)
+	for i, c := range cores {
+		if c == old {
+			cores[i] = core
+			m.set.Store(&coreSet{cores: cores, version: current.version + 1})
+			return true
+		}
+	}
+	return false
+}
+
+// load returns the current cores
+func (m *multiCoreSyncWrapper) load() *coreSet {
+	if set := m.set.Load(); set != nil {
+		return set
+	}
+	return &coreSet{}
+}
+
+// contextCore is a multiCoreSyncWrapper with fields baked into each of
+// its cores, so they are encoded once rather than on every entry.
+// Cores are rebuilt when handlers change, or when redaction rules change
+// so that baked fields are redacted with the current rules.
+type contextCore struct {
+	parent *multiCoreSyncWrapper
+	// layers return the fields of each With call, in order
+	layers []func() []zapcore.Field
+	baked  atomic.Pointer[bakedCores]
+}
+
+// bakedCores are the parent's cores of a given version with fields added
+// under a given rules generation
+type bakedCores struct {
+	cores      []zapcore.Core
+	version    uint64
+	generation uint64
+}
+
+// withBakedFields returns core with the fields returned by bake attached.
+// Context cores call bake again whenever they are rebuilt; other cores
+// get its current fields.
+func withBakedFields(core zapcore.Core, bake func() []zapcore.Field) zapcore.Core {
+	switch c := core.(type) {
+	case *multiCoreSyncWrapper:
+		return &contextCore{parent: c, layers: []func() []zapcore.Field{bake}}
+	case *contextCore:
+		return c.withLayer(bake)
+	}
+	return core.With(bake())
+}
+
+// staticFields returns a layer of fixed fields
+func staticFields(fields []zapcore.Field) func() []zapcore.Field {
+	fields = append([]zapcore.Field{}, fields...)
+	return func() []zapcore.Field { return fields }
+}
+
+// withLayer returns a context core with an additional layer of fields
+func (c *contextCore) withLayer(layer func() []zapcore.Field) *contextCore {
+	layers := make([]func() []zapcore.Field, 0, len(c.layers)+1)
+	layers = append(append(layers, c.layers...), layer)
+	return &contextCore{parent: c.parent, layers: layers}
+}
+
+// current returns the baked cores, rebuilding them if handlers or
+// redaction rules changed
+func (c *contextCore) current() []zapcore.Core {
+	set := c.parent.load()
+	generation := rulesGeneration.Load()
+	if baked := c.baked.Load(); baked != nil && baked.version == set.version && baked.generation == generation {
+		return baked.cores
+	}
+
+	var fields []zapcore.Field
+	for _, layer := range c.layers {
+		fields = append(fields, layer()...)
+	}
+	cores := make([]zapcore.Core, 0, len(set.cores))
+	for _, core := range set.cores {
 		cores = append(cores, core.With(fields))
 	}
+	c.baked.Store(&bakedCores{cores: cores, version: set.version, generation: generation})
+	return cores
+}
 
-	return &multiCoreSyncWrapper{cores: cores}
+// Enabled implements zapcore.Core
+func (c *contextCore) Enabled(lvl zapcore.Level) bool {
+	return c.parent.Enabled(lvl)
+}
+
+// With implements zapcore.Core
+func (c *contextCore) With(fields []zapcore.Field) zapcore.Core {
+	return c.withLayer(staticFields(fields))
 }
 
 // Check implements zapcore.Core
-func (m *multiCoreSyncWrapper) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+func (c *contextCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkCores(c.current(), ent, ce)
+}
+
+// Write implements zapcore.Core
+func (c *contextCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeCores(c.current(), ent, fields)
+}
+
+// writeAt writes to every core accepting lvl
+func (c *contextCore) writeAt(lvl zapcore.Level, ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeCoresAt(c.current(), lvl, ent, fields)
+}
+
+// Sync implements zapcore.Core
+func (c *contextCore) Sync() error {
+	return c.parent.Sync()
+}
+
+// coresEnabled reports whether any core accepts the level
+func coresEnabled(cores []zapcore.Core, lvl zapcore.Level) bool {
+	for _, core := range cores {
+		if core.Enabled(lvl) {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, core := range m.cores {
-		ce = core.Check(ent, ce)
+// checkCores adds the cores accepting the entry's level to ce
+func checkCores(cores []zapcore.Core, ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	for _, core := range cores {
+		if core.Enabled(ent.Level) {
+			ce = core.Check(ent, ce)
+		}
 	}
 	return ce
 }
 
-// Write implements zapcore.Core
-func (m *multiCoreSyncWrapper) Write(ent zapcore.Entry, fields []zapcore.Field) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+// writeCores writes to every core accepting the entry's level, as Check
+// would, joining their errors
+func writeCores(cores []zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeCoresAt(cores, ent.Level, ent, fields)
+}
 
-	for _, core := range m.cores {
+// writeCoresAt writes to every core accepting lvl, joining their errors
+func writeCoresAt(cores []zapcore.Core, lvl zapcore.Level, ent zapcore.Entry, fields []zapcore.Field) error {
+	var errs []error
+	for _, core := range cores {
+		if !core.Enabled(lvl) {
+			continue
+		}
 		if err := core.Write(ent, fields); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
-// Sync implements zapcore.Core
-func (m *multiCoreSyncWrapper) Sync() error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	for _, core := range m.cores {
+// syncCores syncs every core, joining their errors
+func syncCores(cores []zapcore.Core) error {
+	var errs []error
+	for _, core := range cores {
 		if err := core.Sync(); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
-	return nil
-}
-
-// AddCore adds a new zapcore.Core to the wrapper
-func (m *multiCoreSyncWrapper) AddCore(core zapcore.Core) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	m.cores = append(m.cores, core)
+	return errors.Join(errs...)
 }
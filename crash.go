@@ -0,0 +1,248 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultCrashRecent is the number of recent entries kept for crash
+// reports when CrashConfig.Recent is zero
+const DefaultCrashRecent = 100
+
+// CrashConfig configures crash reports
+type CrashConfig struct {
+	// Dir receives one crash-<time>-<pid>.log file per crash; empty
+	// writes no file
+	Dir string
+	// Handler names a handler that receives the report as a single entry;
+	// empty writes no entry
+	Handler string
+	// Recent is the number of recent entries included in the report
+	Recent int
+}
+
+// crashReporter writes crash reports and keeps the recent entries they
+// include. It is shared by a logger and its children.
+type crashReporter struct {
+	enabled atomic.Bool
+	cfg     CrashConfig
+	recent  *recentEntries
+	core    zapcore.Core
+	mu      sync.Mutex
+}
+
+// recentEntries is a ring buffer of encoded entries
+type recentEntries struct {
+	lines []string
+	next  int
+	full  bool
+	mu    sync.Mutex
+}
+
+// Write implements io.Writer, keeping a copy of each encoded entry
+func (r *recentEntries) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lines[r.next] = strings.TrimRight(string(p), "\n")
+	r.next = (r.next + 1) % len(r.lines)
+	if r.next == 0 {
+		r.full = true
+	}
+	return len(p), nil
+}
+
+// snapshot returns the kept entries, oldest first
+func (r *recentEntries) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]string{}, r.lines[:r.next]...)
+	}
+	return append(append([]string{}, r.lines[r.next:]...), r.lines[:r.next]...)
+}
+
+// EnableCrashReports writes a crash report when a Panic or Fatal entry is
+// logged, including panics logged by RecoverAndLogFatal, before the
+// process exits. The report holds a dump of all goroutines, memory
+// statistics and the most recent entries, and goes to a file in cfg.Dir
+// and/or to the handler named cfg.Handler.
+func (l *Logger) EnableCrashReports(cfg CrashConfig) error {
+	if cfg.Dir != "" {
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if cfg.Recent <= 0 {
+		cfg.Recent = DefaultCrashRecent
+	}
+
+	l.mu.Lock()
+	encoderConfig, _ := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOptions{})
+	l.mu.Unlock()
+
+	recent := &recentEntries{lines: make([]string, cfg.Recent)}
+	core := l.createRedactingCore(zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(recent), zapcore.DebugLevel))
+
+	l.crash.mu.Lock()
+	defer l.crash.mu.Unlock()
+
+	if l.crash.core != nil {
+		l.coreWrapper.ReplaceCore(l.crash.core, core)
+	} else {
+		l.coreWrapper.AddCore(core)
+	}
+	l.crash.cfg, l.crash.recent, l.crash.core = cfg, recent, core
+	l.crash.enabled.Store(true)
+	return nil
+}
+
+// DisableCrashReports stops writing crash reports and keeping recent
+// entries
+func (l *Logger) DisableCrashReports() {
+	l.crash.mu.Lock()
+	defer l.crash.mu.Unlock()
+
+	l.crash.enabled.Store(false)
+	if l.crash.core != nil {
+		l.coreWrapper.RemoveCore(l.crash.core)
+	}
+	l.crash.core, l.crash.recent = nil, nil
+}
+
+// reportCrash writes a crash report for an entry at level with the
+// given message, if crash reports are enabled
+func (l *Logger) reportCrash(level LogLevel, msg string) {
+	if !l.crash.enabled.Load() {
+		return
+	}
+
+	l.crash.mu.Lock()
+	cfg, recent := l.crash.cfg, l.crash.recent
+	l.crash.mu.Unlock()
+	if recent == nil {
+		return
+	}
+
+	now := time.Now()
+	goroutines := goroutineDump()
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	entries := recent.snapshot()
+
+	var path string
+	if cfg.Dir != "" {
+		path = filepath.Join(cfg.Dir, fmt.Sprintf("crash-%s-%d.log", now.Format("20060102T150405"), os.Getpid()))
+		report := formatCrashReport(now, l.name, level, msg, &mem, entries, goroutines)
+		if err := os.WriteFile(path, []byte(report), 0o644); err != nil {
+			l.internalErrors.report(fmt.Errorf("crash report: %w", err))
+			path = ""
+		}
+	}
+
+	if cfg.Handler != "" {
+		state := l.handlers.lookup(cfg.Handler)
+		if state == nil {
+			l.internalErrors.report(fmt.Errorf("crash report: unknown handler %q", cfg.Handler))
+			return
+		}
+		fields := []zap.Field{
+			zap.String("logger", l.name),
+			zap.String("reason", msg),
+			zap.Object("memory", crashMemStats{&mem}),
+			zap.Strings("recent", entries),
+			zap.String("goroutines", goroutines),
+		}
+		if path != "" {
+			fields = append(fields, zap.String("crash_file", path))
+		}
+		ent := zapcore.Entry{Level: level, Time: now, LoggerName: l.Logger.Name(), Message: "Crash report"}
+		if err := state.core.Write(ent, fields); err != nil {
+			l.internalErrors.report(fmt.Errorf("crash report: %w", err))
+		}
+		state.core.Sync()
+	}
+}
+
+// goroutineDump returns the stacks of all goroutines
+func goroutineDump() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// formatCrashReport renders a crash report file
+func formatCrashReport(now time.Time, name string, level LogLevel, msg string, mem *runtime.MemStats, entries []string, goroutines string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "crash report: %s\n", msg)
+	fmt.Fprintf(&b, "time: %s\n", now.Format(time.RFC3339Nano))
+	fmt.Fprintf(&b, "level: %s\n", level.CapitalString())
+	fmt.Fprintf(&b, "logger: %s\n", name)
+	fmt.Fprintf(&b, "pid: %d\n", os.Getpid())
+	fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+
+	b.WriteString("\n== memory ==\n")
+	for _, stat := range (crashMemStats{mem}).list() {
+		fmt.Fprintf(&b, "%s: %s\n", stat.key, strconv.FormatUint(stat.value, 10))
+	}
+
+	fmt.Fprintf(&b, "\n== recent entries (%d) ==\n", len(entries))
+	for _, entry := range entries {
+		b.WriteString(entry)
+		b.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&b, "\n== goroutines (%d) ==\n", runtime.NumGoroutine())
+	b.WriteString(goroutines)
+	return b.String()
+}
+
+// crashMemStats marshals the memory statistics of a crash report
+type crashMemStats struct {
+	*runtime.MemStats
+}
+
+// crashMemStat is a named memory statistic
+type crashMemStat struct {
+	key   string
+	value uint64
+}
+
+// list returns the reported statistics in a fixed order
+func (m crashMemStats) list() []crashMemStat {
+	return []crashMemStat{
+		{"alloc_bytes", m.Alloc},
+		{"total_alloc_bytes", m.TotalAlloc},
+		{"sys_bytes", m.Sys},
+		{"heap_alloc_bytes", m.HeapAlloc},
+		{"heap_inuse_bytes", m.HeapInuse},
+		{"heap_objects", m.HeapObjects},
+		{"stack_inuse_bytes", m.StackInuse},
+		{"num_gc", uint64(m.NumGC)},
+		{"goroutines", uint64(runtime.NumGoroutine())},
+	}
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (m crashMemStats) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, stat := range m.list() {
+		enc.AddUint64(stat.key, stat.value)
+	}
+	return nil
+}
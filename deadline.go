@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultDeadlineWarnRatio is the share of its context deadline an
+// operation may use before CheckDeadline warns
+const DefaultDeadlineWarnRatio = 0.8
+
+// CheckDeadline starts watching an operation bound to ctx and returns a
+// function to call when it completes, which logs a Warn if the operation
+// used more than DefaultDeadlineWarnRatio of the time its context had
+// left when it started. Contexts without a deadline are ignored.
+//
+//	defer logger.CheckDeadline(ctx, "db_query")()
+func (l *Logger) CheckDeadline(ctx context.Context, op string) func() {
+	return l.CheckDeadlineRatio(ctx, op, DefaultDeadlineWarnRatio)
+}
+
+// CheckDeadlineRatio is CheckDeadline warning once the operation used
+// more than ratio of its deadline, e.g. 0.5 for half
+func (l *Logger) CheckDeadlineRatio(ctx context.Context, op string, ratio float64) func() {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	budget := deadline.Sub(start)
+
+	return func() {
+		elapsed := time.Since(start)
+		if budget > 0 && float64(elapsed) <= ratio*float64(budget) {
+			return
+		}
+
+		used := 100
+		if budget > 0 {
+			used = int(100 * float64(elapsed) / float64(budget))
+		}
+		fields := map[string]interface{}{
+			OperationKey:          op,
+			DurationKey:           float64(elapsed.Microseconds()) / 1000,
+			"deadline_budget_ms":  float64(budget.Microseconds()) / 1000,
+			"deadline_used_pct":   used,
+			"deadline_exceeded":   errors.Is(ctx.Err(), context.DeadlineExceeded),
+			"deadline_warn_ratio": ratio,
+		}
+		l.WarnContext(ctx, "Operation used most of its deadline", fields)
+	}
+}
+
+// WithDeadlineWarning makes HTTPMiddleware warn about requests that use
+// more than ratio of the deadline of their context, such as one set by
+// an enclosing http.TimeoutHandler or the server's base context
+func WithDeadlineWarning(ratio float64) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.deadlineRatio = ratio
+	}
+}
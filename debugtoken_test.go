@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestDebugTokenRaisesInfoHandlers(t *testing.T) {
+	secret := []byte("debug-secret")
+	l := NewLogger("api", zapcore.InfoLevel)
+	info := observe(l, zapcore.InfoLevel)
+	alerts := observe(l, zapcore.WarnLevel)
+
+	handler := HTTPMiddleware(l, WithDebugTokenSecret(secret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		FromContext(r.Context(), l).Debug("request detail")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if n := info.FilterMessage("request detail").Len(); n != 0 {
+		t.Fatalf("Debug entry without token written %d times", n)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(DebugTokenHeader, SignDebugToken(secret, time.Now().Add(time.Minute)))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := info.FilterMessage("request detail").All()
+	if len(entries) != 1 {
+		t.Fatalf("Info handler got %d Debug entries with token, want 1", len(entries))
+	}
+	if entries[0].Level != zapcore.DebugLevel {
+		t.Errorf("raised entry has level %v, want debug", entries[0].Level)
+	}
+	if n := alerts.Len(); n != 0 {
+		t.Errorf("Warn handler got %d entries, want none", n)
+	}
+}
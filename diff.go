@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DiffKey is the field holding the changes logged by Diff
+const DiffKey = "changes"
+
+// Diff logs the paths that differ between before and after at Info
+// level, e.g. "db.pool.size" or "hosts[1]". Values are compared in
+// their JSON form, so struct tags apply. Values are masked if their path,
+// such as "db.password", or any key along it is redacted.
+func (l *Logger) Diff(msg string, before, after interface{}) {
+	changes, err := diffValues(before, after)
+	if err != nil {
+		l.internalErrors.report(fmt.Errorf("diff %q: %w", msg, err))
+		return
+	}
+
+	// Mask values under redacted keys, as the path hides them from
+	// key redaction
+	l.mu.RLock()
+	r := l.entryRedactor()
+	l.mu.RUnlock()
+	for i, c := range changes {
+		if c.redactedBy(r) {
+			changes[i].from, changes[i].to = maskDiffValue(c.from), maskDiffValue(c.to)
+		}
+	}
+
+	l.log(zapcore.InfoLevel, msg, map[string]interface{}{
+		DiffKey:   changes,
+		"changed": len(changes),
+	})
+}
+
+// diffChange is a single changed path. A missing side is nil with its
+// has flag unset.
+type diffChange struct {
+	path    string
+	from    interface{}
+	to      interface{}
+	hasFrom bool
+	hasTo   bool
+}
+
+// redactedBy reports whether r redacts the full path, with or without
+// array indexes, or any key along it, since redacting a key masks
+// everything below it
+func (c diffChange) redactedBy(r redactor) bool {
+	unindexed := arrayIndexes.ReplaceAllString(c.path, "")
+	if r.redactsKey(c.path) || r.redactsKey(unindexed) {
+		return true
+	}
+	for _, key := range strings.Split(unindexed, ".") {
+		if r.redactsKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// arrayIndexes matches the array indexes of a path such as "hosts[1]"
+var arrayIndexes = regexp.MustCompile(`\[\d+\]`)
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (c diffChange) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("path", c.path)
+	if c.hasFrom {
+		if err := addDiffValue(enc, "from", c.from); err != nil {
+			return err
+		}
+	}
+	if c.hasTo {
+		return addDiffValue(enc, "to", c.to)
+	}
+	return nil
+}
+
+// diffChanges encodes changes in path order
+type diffChanges []diffChange
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (d diffChanges) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, c := range d {
+		if err := enc.AppendObject(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addDiffValue adds a leaf value, as a string where possible so that
+// redaction applies to it
+func addDiffValue(enc zapcore.ObjectEncoder, key string, value interface{}) error {
+	if s, ok := value.(string); ok {
+		enc.AddString(key, s)
+		return nil
+	}
+	return enc.AddReflected(key, value)
+}
+
+// maskDiffValue replaces a present value with RedactedValue
+func maskDiffValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return RedactedValue
+}
+
+// diffValues returns the leaf paths that differ between before and after
+func diffValues(before, after interface{}) (diffChanges, error) {
+	from, err := diffLeaves(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := diffLeaves(after)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes diffChanges
+	for path, value := range from {
+		next, ok := to[path]
+		if !ok || !reflect.DeepEqual(value, next) {
+			changes = append(changes, diffChange{path: path, from: value, to: next, hasFrom: true, hasTo: ok})
+		}
+	}
+	for path, value := range to {
+		if _, ok := from[path]; !ok {
+			changes = append(changes, diffChange{path: path, to: value, hasTo: true})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].path < changes[j].path })
+	return changes, nil
+}
+
+// diffLeaves flattens the JSON form of v to its leaf values by path
+func diffLeaves(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	leaves := map[string]interface{}{}
+	flattenLeaves("", generic, leaves)
+	return leaves, nil
+}
+
+// flattenLeaves records the leaves of v under prefix. Empty objects and
+// arrays are leaves themselves.
+func flattenLeaves(prefix string, v interface{}, leaves map[string]interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			leaves[prefix] = value
+		}
+		for k, child := range value {
+			path := k
+			if prefix != "" {
+				path = prefix + "." + k
+			}
+			flattenLeaves(path, child, leaves)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			leaves[prefix] = value
+		}
+		for i, child := range value {
+			flattenLeaves(fmt.Sprintf("%s[%d]", prefix, i), child, leaves)
+		}
+	default:
+		leaves[prefix] = value
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// diffed logs a diff and returns its changes by path
+func diffed(t *testing.T, l *Logger, before, after interface{}) map[string]map[string]interface{} {
+	t.Helper()
+	logs := observe(l, zapcore.InfoLevel)
+	l.Diff("config changed", before, after)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	changes := map[string]map[string]interface{}{}
+	for _, c := range entries[0].ContextMap()[DiffKey].([]interface{}) {
+		change := c.(map[string]interface{})
+		changes[change["path"].(string)] = change
+	}
+	return changes
+}
+
+func TestDiffMasksRedactedParentKeys(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("credentials")
+
+	changes := diffed(t, l,
+		map[string]interface{}{"credentials": map[string]interface{}{"user": "a", "pass": "x"}, "port": 1},
+		map[string]interface{}{"credentials": map[string]interface{}{"user": "b", "pass": "y"}, "port": 2})
+
+	for _, path := range []string{"credentials.user", "credentials.pass"} {
+		if got := changes[path]["to"]; got != RedactedValue {
+			t.Errorf("%s to = %v, want masked", path, got)
+		}
+	}
+	if got := fmt.Sprint(changes["port"]["to"]); got != "2" {
+		t.Errorf("port to = %v, want unmasked", got)
+	}
+}
+
+func TestDiffMasksRedactedFullPaths(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("db.password")
+
+	changes := diffed(t, l,
+		map[string]interface{}{"db": map[string]interface{}{"password": "old"}, "cache": map[string]interface{}{"password": "a"}},
+		map[string]interface{}{"db": map[string]interface{}{"password": "new"}, "cache": map[string]interface{}{"password": "b"}})
+
+	if got := changes["db.password"]["to"]; got != RedactedValue {
+		t.Errorf("db.password to = %v, want masked", got)
+	}
+	if got := changes["cache.password"]["to"]; got != "b" {
+		t.Errorf("cache.password to = %v, want unmasked", got)
+	}
+}
+
+func TestDiffMasksIndexedPaths(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("tokens")
+
+	changes := diffed(t, l,
+		map[string]interface{}{"tokens": []string{"a"}},
+		map[string]interface{}{"tokens": []string{"b"}})
+
+	if got := changes["tokens[0]"]["to"]; got != RedactedValue {
+		t.Errorf("tokens[0] to = %v, want masked", got)
+	}
+}
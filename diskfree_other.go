@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "errors"
+
+// diskFree is not supported on this platform
+func diskFree(dir string) (uint64, error) {
+	return 0, errors.New("disk space is not available on this platform")
+}
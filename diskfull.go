@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DiskFullPolicy selects what a file handler does with entries while its
+// disk is full
+type DiskFullPolicy int
+
+const (
+	// DiskFullDrop discards entries, counting them as dropped
+	DiskFullDrop DiskFullPolicy = iota
+	// DiskFullStderr writes entries to stderr instead
+	DiskFullStderr
+)
+
+// DefaultDiskFullAlertInterval is how often a degraded file handler
+// repeats its alert and retries the disk
+const DefaultDiskFullAlertInterval = time.Minute
+
+// diskFullConfig is the disk full behaviour of a file handler
+type diskFullConfig struct {
+	policy   DiskFullPolicy
+	interval time.Duration
+}
+
+// WithDiskFullPolicy sets how a file handler degrades when a write fails
+// with ENOSPC. Until a retry succeeds, entries are dropped or redirected
+// to stderr and an alert entry is logged every alertInterval, instead of
+// failing every write. File handlers drop entries with alerts every
+// DefaultDiskFullAlertInterval by default.
+func WithDiskFullPolicy(policy DiskFullPolicy, alertInterval time.Duration) HandlerOption {
+	if alertInterval <= 0 {
+		alertInterval = DefaultDiskFullAlertInterval
+	}
+	return func(o *handlerOptions) {
+		o.diskFull = &diskFullConfig{policy: policy, interval: alertInterval}
+	}
+}
+
+// diskFullWriter degrades a file sink while its disk is full. It
+// implements zapcore.WriteSyncer.
+type diskFullWriter struct {
+	zapcore.WriteSyncer
+	cfg       diskFullConfig
+	path      string
+	logger    *Logger
+	degraded  atomic.Bool
+	dropped   atomic.Int64
+	diverted  atomic.Int64
+	lastProbe time.Time
+	mu        sync.Mutex
+}
+
+// newDiskFullWriter wraps the sink of the file at path
+func (l *Logger) newDiskFullWriter(sink zapcore.WriteSyncer, path string, cfg *diskFullConfig) *diskFullWriter {
+	w := &diskFullWriter{WriteSyncer: sink, path: path, logger: l}
+	w.cfg = diskFullConfig{policy: DiskFullDrop, interval: DefaultDiskFullAlertInterval}
+	if cfg != nil {
+		w.cfg = *cfg
+	}
+	return w
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *diskFullWriter) Write(p []byte) (int, error) {
+	if w.degraded.Load() && !w.probe() {
+		return w.divert(p)
+	}
+
+	n, err := w.WriteSyncer.Write(p)
+	if err == nil {
+		if w.degraded.CompareAndSwap(true, false) {
+			go w.alert("Log file writable again, handler recovered")
+		}
+		return n, nil
+	}
+	if !errors.Is(err, syscall.ENOSPC) {
+		return n, err
+	}
+
+	if !w.degraded.Swap(true) {
+		w.mu.Lock()
+		w.lastProbe = time.Now()
+		w.mu.Unlock()
+	}
+	// Alert from another goroutine, as the entry may reach this handler
+	go w.alert("Disk full, log handler degraded")
+	return w.divert(p)
+}
+
+// probe reports whether a degraded writer should retry the disk, which
+// it does once per alert interval
+func (w *diskFullWriter) probe() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if time.Since(w.lastProbe) < w.cfg.interval {
+		return false
+	}
+	w.lastProbe = time.Now()
+	return true
+}
+
+// divert handles an entry that could not be written to the file
+func (w *diskFullWriter) divert(p []byte) (int, error) {
+	if w.cfg.policy == DiskFullStderr {
+		w.diverted.Add(1)
+		os.Stderr.Write(p)
+	} else {
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// alert logs the writer's state through the logger's handlers
+func (w *diskFullWriter) alert(msg string) {
+	fields := append([]zap.Field{}, w.logger.context...)
+	fields = append(fields,
+		zap.String("path", w.path),
+		zap.Bool("degraded", w.degraded.Load()),
+		zap.Int64("dropped", w.dropped.Load()),
+		zap.Int64("redirected_to_stderr", w.diverted.Load()),
+	)
+	if w.degraded.Load() {
+		w.logger.Logger.Error(msg, fields...)
+	} else {
+		w.logger.Logger.Warn(msg, fields...)
+	}
+}
+
+// droppedCount returns the number of entries discarded while degraded
+func (w *diskFullWriter) droppedCount() int64 {
+	return w.dropped.Load()
+}
+
+// isDegraded reports whether the disk is currently considered full
+func (w *diskFullWriter) isDegraded() bool {
+	return w.degraded.Load()
+}
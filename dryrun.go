@@ -0,0 +1,141 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// maxDryRunMessages bounds the number of distinct messages tracked
+const maxDryRunMessages = 1000
+
+// DryRunReport collects what a dry-run handler would have written
+type DryRunReport struct {
+	sampleSize int
+	entries    int64
+	bytes      int64
+	levels     map[zapcore.Level]int64
+	messages   map[string]int64
+	samples    []string
+	mu         sync.Mutex
+}
+
+// MessageCount is the number of entries logged with a message
+type MessageCount struct {
+	Message string
+	Count   int64
+}
+
+// DryRunSummary is a point-in-time view of a DryRunReport
+type DryRunSummary struct {
+	Entries     int64
+	Bytes       int64
+	ByLevel     map[string]int64
+	TopMessages []MessageCount
+	Samples     []string
+}
+
+// NewDryRunReport creates a report keeping up to sampleSize encoded entries
+func NewDryRunReport(sampleSize int) *DryRunReport {
+	return &DryRunReport{
+		sampleSize: sampleSize,
+		levels:     map[zapcore.Level]int64{},
+		messages:   map[string]int64{},
+	}
+}
+
+// DryRun makes a handler count and sample what it would write instead
+// of writing it, e.g. to estimate the volume of an expensive sink
+func DryRun(report *DryRunReport) HandlerOption {
+	return func(o *handlerOptions) {
+		o.dryRun = report
+	}
+}
+
+// Write records the size of an encoded entry and keeps it as a sample
+func (r *DryRunReport) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.bytes += int64(len(p))
+	if len(r.samples) < r.sampleSize {
+		r.samples = append(r.samples, strings.TrimRight(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer
+func (r *DryRunReport) Sync() error {
+	return nil
+}
+
+// record counts an entry by level and message
+func (r *DryRunReport) record(ent zapcore.Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries++
+	r.levels[ent.Level]++
+	if _, ok := r.messages[ent.Message]; ok || len(r.messages) < maxDryRunMessages {
+		r.messages[ent.Message]++
+	}
+}
+
+// Summary returns the collected statistics with the n most frequent messages
+func (r *DryRunReport) Summary(n int) DryRunSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := DryRunSummary{
+		Entries: r.entries,
+		Bytes:   r.bytes,
+		ByLevel: make(map[string]int64, len(r.levels)),
+		Samples: append([]string{}, r.samples...),
+	}
+	for level, count := range r.levels {
+		summary.ByLevel[level.String()] = count
+	}
+
+	for msg, count := range r.messages {
+		summary.TopMessages = append(summary.TopMessages, MessageCount{Message: msg, Count: count})
+	}
+	sort.Slice(summary.TopMessages, func(i, j int) bool {
+		if summary.TopMessages[i].Count != summary.TopMessages[j].Count {
+			return summary.TopMessages[i].Count > summary.TopMessages[j].Count
+		}
+		return summary.TopMessages[i].Message < summary.TopMessages[j].Message
+	})
+	if len(summary.TopMessages) > n {
+		summary.TopMessages = summary.TopMessages[:n]
+	}
+
+	return summary
+}
+
+// dryRunCore records entries in a DryRunReport before encoding them
+// into the report instead of a real sink
+type dryRunCore struct {
+	zapcore.Core
+	report *DryRunReport
+}
+
+// With implements zapcore.Core
+func (d *dryRunCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dryRunCore{Core: d.Core.With(fields), report: d.report}
+}
+
+// Check implements zapcore.Core
+func (d *dryRunCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if d.Enabled(ent.Level) {
+		return ce.AddCore(ent, d)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (d *dryRunCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	d.report.record(ent)
+	return d.Core.Write(ent, fields)
+}
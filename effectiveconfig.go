@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+)
+
+// EffectiveConfig is a serializable view of a logger's live
+// configuration, e.g. for support bundles. Redaction patterns are listed
+// by name, or pattern if unnamed; secrets are never included.
+type EffectiveConfig struct {
+	Name           string                 `json:"name"`
+	Level          LogLevel               `json:"level"`
+	LoggerLevels   map[string]LogLevel    `json:"logger_levels,omitempty"`
+	ColorMode      ColorMode              `json:"color_mode"`
+	Handlers       []HandlerConfig        `json:"handlers"`
+	Redactions     []string               `json:"redactions,omitempty"`
+	RedactFields   []string               `json:"redact_fields,omitempty"`
+	KeyMapping     map[string]string      `json:"key_mapping,omitempty"`
+	TimeFormat     string                 `json:"time_format,omitempty"`
+	TimeZone       string                 `json:"time_zone,omitempty"`
+	UTC            bool                   `json:"utc,omitempty"`
+	FieldClasses   map[string]Sensitivity `json:"field_classes,omitempty"`
+	Pseudonymized  []string               `json:"pseudonymize_fields,omitempty"`
+	AnonymizedIPs  []string               `json:"anonymize_ip_fields,omitempty"`
+	Sanitize       bool                   `json:"sanitize,omitempty"`
+	EscapeNewlines bool                   `json:"escape_newlines,omitempty"`
+	Locale         string                 `json:"locale,omitempty"`
+}
+
+// HandlerConfig describes a handler, as registered or configured in
+// Config.Handlers. Kind is console or file, whose Sink is the path.
+// Console handlers may use a built-in Theme: dark, light or monochrome.
+type HandlerConfig struct {
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Sink    string   `json:"sink,omitempty"`
+	Encoder string   `json:"encoder,omitempty"`
+	Level   LogLevel `json:"level"`
+	Theme   string   `json:"theme,omitempty"`
+}
+
+// EffectiveConfig returns the configuration currently in effect
+func (l *Logger) EffectiveConfig() EffectiveConfig {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	cfg := EffectiveConfig{
+		Name:           l.name,
+		Level:          l.Level(),
+		LoggerLevels:   l.levels.snapshot(),
+		ColorMode:      l.colorMode,
+		Handlers:       []HandlerConfig{},
+		KeyMapping:     l.keyMapping,
+		TimeFormat:     l.timeFormat,
+		TimeZone:       l.timeZone,
+		UTC:            l.utc.Load(),
+		Sanitize:       l.sanitizer.control.Load(),
+		EscapeNewlines: l.sanitizer.newlines.Load(),
+	}
+	if locale := l.locale.Load(); locale != nil {
+		cfg.Locale = *locale
+	}
+
+	for _, h := range l.handlers.all() {
+		cfg.Handlers = append(cfg.Handlers, HandlerConfig{
+			Name:    h.name,
+			Kind:    h.kind,
+			Sink:    h.sink,
+			Encoder: h.encoder,
+			Level:   h.level,
+		})
+	}
+
+	for _, rd := range l.redactions {
+		cfg.Redactions = append(cfg.Redactions, rd.ruleName())
+	}
+	for key := range l.redactKeys {
+		cfg.RedactFields = append(cfg.RedactFields, key)
+	}
+	sort.Strings(cfg.RedactFields)
+
+	cfg.Pseudonymized = l.transforms.keys("pseudonymize")
+	cfg.AnonymizedIPs = l.transforms.keys("anonymize_ip")
+
+	if classes := l.classes.classes.Load(); classes != nil && len(*classes) > 0 {
+		cfg.FieldClasses = *classes
+	}
+	return cfg
+}
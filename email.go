@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Default templates for digest emails
+const (
+	defaultEmailSubject = `[{{.Level}}] {{.Count}} log entr{{if eq .Count 1}}y{{else}}ies{{end}} from {{.Hostname}}`
+	defaultEmailBody    = `{{range .Entries}}{{.Time.Format "2006-01-02T15:04:05.000Z07:00"}} {{.Level}} {{.Logger}}: {{.Message}}
+{{range $k, $v := .Fields}}    {{$k}}: {{$v}}
+{{end}}
+{{end}}`
+)
+
+// EmailConfig configures an SMTP alert handler
+type EmailConfig struct {
+	// Addr is the SMTP server address, e.g. "smtp.example.com:587"
+	Addr string
+	Auth smtp.Auth
+	From string
+	// To receives entries that match no route
+	To []string
+	// Routes maps logger name prefixes (e.g. "app.billing") to recipients;
+	// the longest matching prefix wins
+	Routes map[string][]string
+	// Window collects entries into one digest email, defaults to 1 minute
+	Window time.Duration
+	// Subject and Body are text/template templates executed with an
+	// EmailDigest; defaults list every entry
+	Subject string
+	Body    string
+	// SendMail defaults to smtp.SendMail
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// EmailDigest is the data passed to email templates
+type EmailDigest struct {
+	Hostname string
+	Level    string
+	Count    int
+	Entries  []EmailEntry
+}
+
+// EmailEntry is a single entry in an EmailDigest
+type EmailEntry struct {
+	Time    time.Time
+	Level   string
+	Logger  string
+	Message string
+	Fields  map[string]interface{}
+}
+
+// AddEmailHandler adds a handler that emails entries at or above level
+// (typically Error or Fatal), digesting entries within a window into a
+// single email per recipient group
+func (l *Logger) AddEmailHandler(cfg EmailConfig, level LogLevel) error {
+	if cfg.Addr == "" || cfg.From == "" {
+		return fmt.Errorf("email server address and sender are required")
+	}
+	if len(cfg.To) == 0 && len(cfg.Routes) == 0 {
+		return fmt.Errorf("email recipients are required")
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.Subject == "" {
+		cfg.Subject = defaultEmailSubject
+	}
+	if cfg.Body == "" {
+		cfg.Body = defaultEmailBody
+	}
+	if cfg.SendMail == nil {
+		cfg.SendMail = smtp.SendMail
+	}
+
+	subject, err := template.New("subject").Parse(cfg.Subject)
+	if err != nil {
+		return fmt.Errorf("invalid email subject template: %w", err)
+	}
+	body, err := template.New("body").Parse(cfg.Body)
+	if err != nil {
+		return fmt.Errorf("invalid email body template: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	hostname, _ := os.Hostname()
+	core := &emailCore{
+		LevelEnabler: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return lvl >= level
+		}),
+		digester: &emailDigester{
+			cfg:      cfg,
+			subject:  subject,
+			body:     body,
+			hostname: hostname,
+			onError:  l.internalErrors.report,
+			pending:  map[string]*pendingDigest{},
+		},
+		loggerKey: l.mapKey("logger"),
+	}
+
+	spec := handlerSpec{kind: "email", sink: cfg.Addr, encoder: "text", level: level}
+	l.registerHandler(newHandlerState(spec, handlerOptions{}), core)
+	return nil
+}
+
+// emailCore hands entries to the digester
+type emailCore struct {
+	zapcore.LevelEnabler
+	digester  *emailDigester
+	loggerKey string
+	fields    []zapcore.Field
+}
+
+// With implements zapcore.Core
+func (e *emailCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *e
+	clone.fields = append(append([]zapcore.Field{}, e.fields...), fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (e *emailCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if e.Enabled(ent.Level) {
+		return ce.AddCore(ent, e)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (e *emailCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range e.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	logger, _ := enc.Fields[e.loggerKey].(string)
+	delete(enc.Fields, e.loggerKey)
+
+	e.digester.add(EmailEntry{
+		Time:    ent.Time,
+		Level:   ent.Level.CapitalString(),
+		Logger:  logger,
+		Message: ent.Message,
+		Fields:  enc.Fields,
+	})
+
+	// Fatal entries exit the process, so send them right away
+	if ent.Level >= zapcore.FatalLevel {
+		return e.digester.flush()
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (e *emailCore) Sync() error {
+	return e.digester.flush()
+}
+
+// pendingDigest collects entries for one recipient group
+type pendingDigest struct {
+	to      []string
+	entries []EmailEntry
+	timer   *time.Timer
+}
+
+// emailDigester groups entries by recipients and sends digests
+type emailDigester struct {
+	cfg      EmailConfig
+	subject  *template.Template
+	body     *template.Template
+	hostname string
+	onError  func(error)
+	pending  map[string]*pendingDigest
+	mu       sync.Mutex
+}
+
+// add queues an entry, starting the digest window for its recipients
+func (d *emailDigester) add(entry EmailEntry) {
+	to := d.recipients(entry.Logger)
+	key := strings.Join(to, ",")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	digest, ok := d.pending[key]
+	if !ok {
+		digest = &pendingDigest{to: to}
+		digest.timer = time.AfterFunc(d.cfg.Window, func() {
+			if err := d.send(key); err != nil {
+				d.onError(err)
+			}
+		})
+		d.pending[key] = digest
+	}
+	digest.entries = append(digest.entries, entry)
+}
+
+// flush sends all pending digests immediately
+func (d *emailDigester) flush() error {
+	d.mu.Lock()
+	keys := make([]string, 0, len(d.pending))
+	for key, digest := range d.pending {
+		digest.timer.Stop()
+		keys = append(keys, key)
+	}
+	d.mu.Unlock()
+
+	var firstErr error
+	for _, key := range keys {
+		if err := d.send(key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// send renders and sends the pending digest for a recipient group
+func (d *emailDigester) send(key string) error {
+	d.mu.Lock()
+	digest, ok := d.pending[key]
+	delete(d.pending, key)
+	d.mu.Unlock()
+
+	if !ok || len(digest.entries) == 0 {
+		return nil
+	}
+
+	data := EmailDigest{
+		Hostname: d.hostname,
+		Level:    highestLevel(digest.entries),
+		Count:    len(digest.entries),
+		Entries:  digest.entries,
+	}
+
+	var subject, body bytes.Buffer
+	if err := d.subject.Execute(&subject, data); err != nil {
+		return err
+	}
+	if err := d.body.Execute(&body, data); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", d.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(digest.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(subject.String()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.Write(body.Bytes())
+
+	return d.cfg.SendMail(d.cfg.Addr, d.cfg.Auth, d.cfg.From, digest.to, msg.Bytes())
+}
+
+// recipients returns the recipients for the longest matching route
+func (d *emailDigester) recipients(logger string) []string {
+	best := ""
+	to := d.cfg.To
+	for prefix, recipients := range d.cfg.Routes {
+		if (logger == prefix || strings.HasPrefix(logger, prefix+".")) && len(prefix) > len(best) {
+			best = prefix
+			to = recipients
+		}
+	}
+
+	sorted := append([]string{}, to...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// highestLevel returns the most severe level among entries
+func highestLevel(entries []EmailEntry) string {
+	highest := zapcore.DebugLevel
+	for _, entry := range entries {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(entry.Level)); err == nil && level > highest {
+			highest = level
+		}
+	}
+	return highest.CapitalString()
+}
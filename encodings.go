@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Encodings selectable per handler with WithEncoding
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+	EncodingLogfmt  = "logfmt"
+	EncodingECS     = "ecs"
+)
+
+// ECSVersion is the Elastic Common Schema version written by the ECS
+// encoding
+const ECSVersion = "8.11.0"
+
+// WithEncoding makes a console or file handler encode entries as json,
+// console, logfmt or ecs regardless of its default
+func WithEncoding(encoding string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.encoding = encoding
+	}
+}
+
+// addConfiguredHandler adds a handler from Config.Handlers
+func (l *Logger) addConfiguredHandler(h HandlerConfig, development bool) error {
+	var opts []HandlerOption
+	if h.Name != "" {
+		opts = append(opts, WithName(h.Name))
+	}
+	if h.Encoder != "" {
+		opts = append(opts, WithEncoding(h.Encoder))
+	}
+	if h.Theme != "" {
+		theme, err := ThemeByName(h.Theme)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, WithTheme(theme))
+	}
+
+	switch h.Kind {
+	case "console":
+		l.AddConsoleHandler(h.Level, development, opts...)
+		return nil
+	case "file":
+		return l.AddFileHandler(h.Sink, h.Level, opts...)
+	}
+	return fmt.Errorf("unknown handler kind %q, use console or file", h.Kind)
+}
+
+// newEncoding creates an encoder for the named encoding, colouring
+// json and console levels if color is set
+func (l *Logger) newEncoding(encoding string, color bool, opts handlerOptions) (zapcore.Encoder, error) {
+	levelEncoder := zapcore.CapitalLevelEncoder
+	if color {
+		levelEncoder = zapcore.CapitalColorLevelEncoder
+	}
+
+	switch encoding {
+	case EncodingJSON:
+		cfg, err := l.newEncoderConfig(levelEncoder, opts)
+		return zapcore.NewJSONEncoder(cfg), err
+	case EncodingConsole:
+		cfg, err := l.newEncoderConfig(levelEncoder, opts)
+		return zapcore.NewConsoleEncoder(cfg), err
+	case EncodingLogfmt:
+		cfg, err := l.newEncoderConfig(zapcore.LowercaseLevelEncoder, opts)
+		return newLogfmtEncoder(cfg), err
+	case EncodingECS:
+		return newECSEncoder(), nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q, use json, console, logfmt or ecs", encoding)
+}
+
+// newECSEncoder creates a JSON encoder using Elastic Common Schema field
+// names and UTC timestamps
+func newECSEncoder() zapcore.Encoder {
+	enc := zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		TimeKey:        "@timestamp",
+		LevelKey:       "log.level",
+		NameKey:        "log.logger",
+		CallerKey:      "log.origin.file.name",
+		MessageKey:     "message",
+		StacktraceKey:  "error.stack_trace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     ecsTimeEncoder,
+		EncodeDuration: zapcore.NanosDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	})
+	enc.AddString("ecs.version", ECSVersion)
+	return enc
+}
+
+// ecsTimeEncoder writes timestamps in UTC with millisecond precision
+func ecsTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+}
+
+// logfmtEncoder writes entries as logfmt lines such as
+// `time=2024-01-02T03:04:05Z level=info msg="user login" user=42`,
+// sharing the fast console encoder's field formatting
+type logfmtEncoder struct {
+	*fastConsoleEncoder
+}
+
+// newLogfmtEncoder creates a logfmt encoder
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	cfg.ConsoleSeparator = " "
+	return logfmtEncoder{newFastConsoleEncoder(cfg).(*fastConsoleEncoder)}
+}
+
+// Clone implements zapcore.Encoder
+func (e logfmtEncoder) Clone() zapcore.Encoder {
+	return logfmtEncoder{e.fastConsoleEncoder.Clone().(*fastConsoleEncoder)}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (e logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := fastConsoleBufferPool.Get()
+	enc := &fastConsoleEncoder{cfg: e.cfg, buf: line}
+	cfg := e.cfg
+
+	// Render entry metadata with the configured encoders, quoting as needed
+	scratch := fastConsoleBufferPool.Get()
+	defer scratch.Free()
+	addEncoded := func(key string, encode func(zapcore.PrimitiveArrayEncoder)) {
+		scratch.Reset()
+		encode(primitiveAppender{scratch})
+		enc.AddString(key, scratch.String())
+	}
+
+	if cfg.TimeKey != "" && cfg.EncodeTime != nil {
+		addEncoded(cfg.TimeKey, func(p zapcore.PrimitiveArrayEncoder) { cfg.EncodeTime(ent.Time, p) })
+	}
+	if cfg.LevelKey != "" && cfg.EncodeLevel != nil {
+		addEncoded(cfg.LevelKey, func(p zapcore.PrimitiveArrayEncoder) { cfg.EncodeLevel(ent.Level, p) })
+	}
+	if ent.LoggerName != "" && cfg.NameKey != "" {
+		enc.AddString(cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && cfg.CallerKey != "" && cfg.EncodeCaller != nil {
+		addEncoded(cfg.CallerKey, func(p zapcore.PrimitiveArrayEncoder) { cfg.EncodeCaller(ent.Caller, p) })
+	}
+	if cfg.MessageKey != "" {
+		enc.AddString(cfg.MessageKey, ent.Message)
+	}
+
+	if e.buf.Len() > 0 {
+		line.AppendByte(' ')
+		line.Write(e.buf.Bytes())
+	}
+	enc.namespace = e.namespace
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	if ent.Stack != "" && cfg.StacktraceKey != "" {
+		enc.namespace = ""
+		enc.AddString(cfg.StacktraceKey, ent.Stack)
+	}
+
+	line.AppendString(cfg.LineEnding)
+	return line, nil
+}
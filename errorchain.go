@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxErrorCauses bounds how many wrapped errors are expanded
+const maxErrorCauses = 32
+
+// errorCause is a single wrapped error in an error chain
+type errorCause struct {
+	message string
+	errType string
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (c errorCause) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("message", c.message)
+	enc.AddString("type", c.errType)
+	return nil
+}
+
+// errorCauses is the list of wrapped errors in an error chain
+type errorCauses []errorCause
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (cs errorCauses) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, c := range cs {
+		if err := enc.AppendObject(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandError expands an error into key.message, key.type, key.causes
+// and, for errors that format a stack trace with %+v, key.stack
+func expandError(key string, err error) []zap.Field {
+	fields := []zap.Field{
+		zap.String(key+".message", err.Error()),
+		zap.String(key+".type", fmt.Sprintf("%T", err)),
+	}
+
+	if causes := unwrapCauses(err); len(causes) > 0 {
+		fields = append(fields, zap.Array(key+".causes", causes))
+	}
+
+	// pkg/errors-style errors print their stack trace with %+v
+	if _, ok := err.(fmt.Formatter); ok {
+		verbose := fmt.Sprintf("%+v", err)
+		if verbose != err.Error() && strings.Contains(verbose, "\n") {
+			fields = append(fields, zap.String(key+".stack", verbose))
+		}
+	}
+
+	return fields
+}
+
+// unwrapCauses walks errors.Unwrap and Unwrap() []error depth-first,
+// returning every wrapped error below err
+func unwrapCauses(err error) errorCauses {
+	var causes errorCauses
+
+	var walk func(error)
+	walk = func(e error) {
+		var wrapped []error
+		switch u := e.(type) {
+		case interface{ Unwrap() []error }:
+			wrapped = u.Unwrap()
+		default:
+			if next := errors.Unwrap(e); next != nil {
+				wrapped = []error{next}
+			}
+		}
+
+		for _, w := range wrapped {
+			if w == nil || len(causes) >= maxErrorCauses {
+				continue
+			}
+			causes = append(causes, errorCause{
+				message: w.Error(),
+				errType: fmt.Sprintf("%T", w),
+			})
+			walk(w)
+		}
+	}
+	walk(err)
+
+	return causes
+}
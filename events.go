@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// EventCodeKey is the field holding an event's machine-readable code
+const EventCodeKey = "event_code"
+
+// EventDefinition describes a structured event
+type EventDefinition struct {
+	// Code is the stable machine-readable event code, e.g. "USER_LOGIN"
+	Code string
+	// Message is the human-readable message logged for the event
+	Message string
+	// Level is the default level of the event
+	Level LogLevel
+	// Required lists fields that must be provided with the event
+	Required []string
+	// Schema optionally describes the expected types of the event's fields
+	Schema Schema
+}
+
+// eventRegistry holds all registered event definitions
+var eventRegistry = struct {
+	events map[string]EventDefinition
+	mu     sync.RWMutex
+}{events: map[string]EventDefinition{}}
+
+// RegisterEvent registers an event definition, replacing any existing
+// definition with the same code
+func RegisterEvent(def EventDefinition) error {
+	if def.Code == "" {
+		return fmt.Errorf("event code must not be empty")
+	}
+	if def.Message == "" {
+		def.Message = def.Code
+	}
+
+	eventRegistry.mu.Lock()
+	defer eventRegistry.mu.Unlock()
+
+	eventRegistry.events[def.Code] = def
+	return nil
+}
+
+// lookupEvent returns the definition registered for code
+func lookupEvent(code string) (EventDefinition, bool) {
+	eventRegistry.mu.RLock()
+	defer eventRegistry.mu.RUnlock()
+
+	def, ok := eventRegistry.events[code]
+	return def, ok
+}
+
+// Event logs a registered event using its message, translated to the
+// logger's locale, and default level.
+// Unknown codes are logged at Warn and missing required fields are
+// reported through the internal error handler.
+func (l *Logger) Event(code string, fields map[string]interface{}) {
+	def, ok := lookupEvent(code)
+	if !ok {
+		l.internalErrors.report(fmt.Errorf("unknown event code %q", code))
+		def = EventDefinition{Code: code, Message: code, Level: zapcore.WarnLevel}
+	}
+
+	for _, key := range def.Required {
+		if _, ok := fields[key]; !ok {
+			l.internalErrors.report(fmt.Errorf("event %q missing required field %q", code, key))
+		}
+	}
+
+	l.validateSchema(def.Schema, "event "+code, fields)
+
+	eventFields := make(map[string]interface{}, len(fields)+1)
+	for k, v := range fields {
+		eventFields[k] = v
+	}
+	eventFields[EventCodeKey] = code
+
+	l.log(def.Level, l.translate(code, def.Message), eventFields)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// exampleOption documents one Config option for WriteExampleConfig
+type exampleOption struct {
+	key     string
+	comment string
+	// value is a string, bool, []string, [][2]string for ordered maps
+	// or [][][2]string for lists of them
+	value interface{}
+}
+
+// exampleOptions covers every Config field, in declaration order
+var exampleOptions = []exampleOption{
+	{"name", "Root logger name, added to every entry as the logger field", "app"},
+	{"env", "Deployment environment, added with build info", "production"},
+	{"profile", "Preset defaults for unset options: development, staging or production", "production"},
+	{"twelve_factor", "Write a single JSON stream to stdout without file handlers or buffering", false},
+	{"level", "Global minimum level: debug, info, warn, error, dpanic, panic or fatal", "info"},
+	{"development", "Human-readable console output instead of JSON", false},
+	{"color_mode", "Console colors: auto colors terminals unless NO_COLOR is set, or always with FORCE_COLOR or CLICOLOR_FORCE; always; never", "auto"},
+	{"console_level", "Minimum level of the console handler; omit to disable it", "debug"},
+	{"file_config", "Log files and the minimum level written to each", [][2]string{{"/var/log/app/app.log", "info"}, {"/var/log/app/error.log", "error"}}},
+	{"redact_regex", "Regular expressions redacted from messages and values, with their replacement", [][2]string{{`\b(?:\d{4}[-\s]?){3}\d{4}\b`, "XXXX-XXXX-XXXX-XXXX"}}},
+	{"redact_fields", "Field keys whose values are always replaced", []string{"password", "authorization"}},
+	{"key_mapping", "Renames of standard and custom keys, e.g. for ECS or Datadog", [][2]string{{"msg", "message"}, {"time", "@timestamp"}}},
+	{"time_format", "Timestamp layout: a Go layout, or rfc3339, rfc3339nano, epoch, epoch_millis or epoch_nanos", "rfc3339nano"},
+	{"time_zone", "IANA time zone for timestamps; empty keeps local time", "UTC"},
+	{"utc", "Write timestamps in UTC when no time zone is set", true},
+	{"field_classes", "Sensitivity of field keys: public, internal, pii or secret", [][2]string{{"email", "pii"}, {"api_key", "secret"}}},
+	{"pseudonymize_fields", "Identifier fields logged as keyed hashes, joinable across services sharing the key", []string{"user_id", "email", "client_ip"}},
+	{"pseudonym_key_env", "Environment variable holding the pseudonymization key; never put the key itself in config", "LOG_PSEUDONYM_KEY"},
+	{"anonymize_ip_fields", "Fields whose IP addresses are truncated to the network: last IPv4 octet and last 80 IPv6 bits zeroed", []string{"client_ip"}},
+	{"sanitize", "Strip terminal escape sequences and control characters from untrusted input", true},
+	{"escape_newlines", "Escape newlines and carriage returns so untrusted input can't forge log lines", true},
+	{"locale", "Locale of event messages, using catalogs registered with RegisterCatalog", "de"},
+	{"handlers", "Console and file handlers, each with its own level, name and encoder: json, console, logfmt or ecs; console handlers take a theme: dark, light or monochrome", [][][2]string{
+		{{"kind", "console"}, {"level", "debug"}, {"encoder", "console"}, {"theme", "dark"}},
+		{{"kind", "file"}, {"name", "ecs-file"}, {"sink", "/var/log/app/ecs.json"}, {"level", "info"}, {"encoder", "ecs"}},
+	}},
+}
+
+// WriteExampleConfig writes a configuration covering every option with
+// example values and comments, in "yaml" or "json" format. JSON has no
+// comments, so they are listed under a "_comments" key.
+func WriteExampleConfig(w io.Writer, format string) error {
+	var buf bytes.Buffer
+	switch strings.ToLower(format) {
+	case "yaml", "yml":
+		writeExampleYAML(&buf)
+	case "json":
+		if err := writeExampleJSON(&buf); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown config format %q, use yaml or json", format)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeExampleYAML renders the example options as commented YAML
+func writeExampleYAML(buf *bytes.Buffer) {
+	buf.WriteString("# zap_logger configuration\n")
+	for _, opt := range exampleOptions {
+		fmt.Fprintf(buf, "\n# %s\n%s:", opt.comment, opt.key)
+		switch v := opt.value.(type) {
+		case string:
+			fmt.Fprintf(buf, " %s\n", strconv.Quote(v))
+		case bool:
+			fmt.Fprintf(buf, " %t\n", v)
+		case []string:
+			buf.WriteByte('\n')
+			for _, item := range v {
+				fmt.Fprintf(buf, "  - %s\n", strconv.Quote(item))
+			}
+		case [][2]string:
+			buf.WriteByte('\n')
+			for _, kv := range v {
+				fmt.Fprintf(buf, "  %s: %s\n", yamlQuote(kv[0]), yamlQuote(kv[1]))
+			}
+		case [][][2]string:
+			buf.WriteByte('\n')
+			for _, item := range v {
+				for i, kv := range item {
+					prefix := "    "
+					if i == 0 {
+						prefix = "  - "
+					}
+					fmt.Fprintf(buf, "%s%s: %s\n", prefix, kv[0], yamlQuote(kv[1]))
+				}
+			}
+		}
+	}
+}
+
+// yamlQuote single-quotes a string, which YAML reads without escapes
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// writeExampleJSON renders the example options as indented JSON,
+// preserving their order
+func writeExampleJSON(buf *bytes.Buffer) error {
+	buf.WriteString("{\n")
+
+	comments := make([][2]string, 0, len(exampleOptions))
+	for _, opt := range exampleOptions {
+		comments = append(comments, [2]string{opt.key, opt.comment})
+	}
+	options := append([]exampleOption{{key: "_comments", value: comments}}, exampleOptions...)
+
+	for i, opt := range options {
+		key, _ := json.Marshal(opt.key)
+		fmt.Fprintf(buf, "  %s: ", key)
+
+		switch v := opt.value.(type) {
+		case [][2]string:
+			writeJSONObject(buf, v, "  ")
+		case [][][2]string:
+			buf.WriteString("[")
+			for j, item := range v {
+				if j > 0 {
+					buf.WriteByte(',')
+				}
+				buf.WriteString("\n    ")
+				writeJSONObject(buf, item, "    ")
+			}
+			buf.WriteString("\n  ]")
+		default:
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			buf.Write(value)
+		}
+
+		if i < len(options)-1 {
+			buf.WriteByte(',')
+		}
+		buf.WriteByte('\n')
+	}
+
+	buf.WriteString("}\n")
+	return nil
+}
+
+// writeJSONObject writes ordered pairs as a JSON object closed at indent
+func writeJSONObject(buf *bytes.Buffer, pairs [][2]string, indent string) {
+	buf.WriteString("{")
+	for i, kv := range pairs {
+		k, _ := json.Marshal(kv[0])
+		value, _ := json.Marshal(kv[1])
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "\n%s  %s: %s", indent, k, value)
+	}
+	buf.WriteString("\n" + indent + "}")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// contextExtractors holds the registered context extractors. The slice
+// is copied on write so entries read it without locking.
+var contextExtractors = struct {
+	extractors atomic.Pointer[[]func(context.Context) []zap.Field]
+	mu         sync.Mutex
+}{}
+
+// RegisterContextExtractor registers a function pulling app-specific
+// values such as a session ID, user ID or shard out of contexts. Its
+// fields are added to entries of every context-aware log call, such as
+// InfoContext, of all loggers, so they needn't be repeated with
+// WithContext. Extracted fields are redacted like others. Register
+// extractors at startup; they run on every entry and must be fast.
+func RegisterContextExtractor(extract func(ctx context.Context) []zap.Field) {
+	if extract == nil {
+		return
+	}
+
+	contextExtractors.mu.Lock()
+	defer contextExtractors.mu.Unlock()
+
+	var extractors []func(context.Context) []zap.Field
+	if current := contextExtractors.extractors.Load(); current != nil {
+		extractors = append(extractors, *current...)
+	}
+	extractors = append(extractors, extract)
+	contextExtractors.extractors.Store(&extractors)
+}
+
+// appendContextFields appends the fields extracted from ctx
+func appendContextFields(dst []zap.Field, ctx context.Context) []zap.Field {
+	extractors := contextExtractors.extractors.Load()
+	if extractors == nil || ctx == nil {
+		return dst
+	}
+	for _, extract := range *extractors {
+		dst = append(dst, extract(ctx)...)
+	}
+	return dst
+}
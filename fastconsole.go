@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// WithFastConsoleEncoder makes a development console handler use an
+// encoder writing fields as key=value pairs straight into pooled buffers,
+// avoiding reflection for common field types. It suits debug-heavy test
+// runs where the default console encoder dominates CPU.
+func WithFastConsoleEncoder() HandlerOption {
+	return func(o *handlerOptions) {
+		o.fastConsole = true
+	}
+}
+
+var (
+	fastConsoleBufferPool = buffer.NewPool()
+	fastConsolePool       = sync.Pool{New: func() interface{} { return &fastConsoleEncoder{} }}
+)
+
+// fastConsoleEncoder is a zapcore.Encoder producing lines such as
+// "2024-01-02T03:04:05Z	INFO	message	key=value other="with space""
+type fastConsoleEncoder struct {
+	cfg       *zapcore.EncoderConfig
+	buf       *buffer.Buffer
+	namespace string
+}
+
+// newFastConsoleEncoder creates a fast console encoder
+func newFastConsoleEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	if cfg.ConsoleSeparator == "" {
+		cfg.ConsoleSeparator = "\t"
+	}
+	return &fastConsoleEncoder{cfg: &cfg, buf: fastConsoleBufferPool.Get()}
+}
+
+// Clone implements zapcore.Encoder
+func (e *fastConsoleEncoder) Clone() zapcore.Encoder {
+	clone := &fastConsoleEncoder{cfg: e.cfg, buf: fastConsoleBufferPool.Get(), namespace: e.namespace}
+	clone.buf.Write(e.buf.Bytes())
+	return clone
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (e *fastConsoleEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	line := fastConsoleBufferPool.Get()
+	sep := e.cfg.ConsoleSeparator
+	prims := primitiveAppender{line}
+
+	if e.cfg.TimeKey != "" && e.cfg.EncodeTime != nil {
+		e.cfg.EncodeTime(ent.Time, prims)
+		line.AppendString(sep)
+	}
+	if e.cfg.LevelKey != "" && e.cfg.EncodeLevel != nil {
+		e.cfg.EncodeLevel(ent.Level, prims)
+		line.AppendString(sep)
+	}
+	if ent.LoggerName != "" && e.cfg.NameKey != "" {
+		line.AppendString(ent.LoggerName)
+		line.AppendString(sep)
+	}
+	if ent.Caller.Defined && e.cfg.CallerKey != "" && e.cfg.EncodeCaller != nil {
+		e.cfg.EncodeCaller(ent.Caller, prims)
+		line.AppendString(sep)
+	}
+	line.AppendString(ent.Message)
+
+	// Encode fields straight into the line with a pooled encoder
+	enc := fastConsolePool.Get().(*fastConsoleEncoder)
+	enc.cfg, enc.buf, enc.namespace = e.cfg, line, e.namespace
+	if e.buf.Len() > 0 || len(fields) > 0 {
+		line.AppendString(sep)
+		line.Write(e.buf.Bytes())
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+	enc.cfg, enc.buf = nil, nil
+	fastConsolePool.Put(enc)
+
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" {
+		line.AppendByte('\n')
+		line.AppendString(ent.Stack)
+	}
+	line.AppendString(e.cfg.LineEnding)
+	return line, nil
+}
+
+// addKey starts a key=value pair
+func (e *fastConsoleEncoder) addKey(key string) {
+	if e.buf.Len() > 0 {
+		last := e.buf.Bytes()[e.buf.Len()-1]
+		if last != '\t' && last != ' ' && string(last) != e.cfg.ConsoleSeparator {
+			e.buf.AppendByte(' ')
+		}
+	}
+	if e.namespace != "" {
+		e.buf.AppendString(e.namespace)
+		e.buf.AppendByte('.')
+	}
+	e.appendText(key)
+	e.buf.AppendByte('=')
+}
+
+// appendText writes s, quoting it if it would be ambiguous
+func (e *fastConsoleEncoder) appendText(s string) {
+	if needsQuoting(s) {
+		e.buf.AppendString(strconv.Quote(s))
+		return
+	}
+	e.buf.AppendString(s)
+}
+
+// needsQuoting reports whether s is empty or contains spaces, quotes,
+// equal signs or non-printable characters
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c == '"' || c == '=' || c == 0x7f || c >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// addJSON encodes a complex value as JSON
+func (e *fastConsoleEncoder) addJSON(key string, add func(zapcore.ObjectEncoder) error) error {
+	enc := zapcore.NewMapObjectEncoder()
+	if err := add(enc); err != nil {
+		return err
+	}
+	b, err := json.Marshal(enc.Fields[key])
+	if err != nil {
+		return err
+	}
+	e.addKey(key)
+	e.buf.Write(b)
+	return nil
+}
+
+// AddArray implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddArray(key string, arr zapcore.ArrayMarshaler) error {
+	return e.addJSON(key, func(enc zapcore.ObjectEncoder) error { return enc.AddArray(key, arr) })
+}
+
+// AddObject implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	return e.addJSON(key, func(enc zapcore.ObjectEncoder) error { return enc.AddObject(key, obj) })
+}
+
+// AddReflected implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddReflected(key string, value interface{}) error {
+	return e.addJSON(key, func(enc zapcore.ObjectEncoder) error { return enc.AddReflected(key, value) })
+}
+
+// OpenNamespace implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) OpenNamespace(key string) {
+	if e.namespace != "" {
+		e.namespace += "."
+	}
+	e.namespace += key
+}
+
+// AddBinary implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddBinary(key string, value []byte) {
+	e.AddString(key, base64.StdEncoding.EncodeToString(value))
+}
+
+// AddByteString implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+// AddBool implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddBool(key string, value bool) {
+	e.addKey(key)
+	e.buf.AppendBool(value)
+}
+
+// AddComplex128 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddComplex128(key string, value complex128) {
+	e.addKey(key)
+	e.buf.AppendString(strconv.FormatComplex(value, 'g', -1, 128))
+}
+
+// AddComplex64 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddComplex64(key string, value complex64) {
+	e.addKey(key)
+	e.buf.AppendString(strconv.FormatComplex(complex128(value), 'g', -1, 64))
+}
+
+// AddDuration implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddDuration(key string, value time.Duration) {
+	e.addKey(key)
+	e.buf.AppendString(value.String())
+}
+
+// AddFloat64 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddFloat64(key string, value float64) {
+	e.addKey(key)
+	e.buf.AppendFloat(value, 64)
+}
+
+// AddFloat32 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddFloat32(key string, value float32) {
+	e.addKey(key)
+	e.buf.AppendFloat(float64(value), 32)
+}
+
+// AddInt implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddInt(key string, value int) { e.AddInt64(key, int64(value)) }
+
+// AddInt64 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddInt64(key string, value int64) {
+	e.addKey(key)
+	e.buf.AppendInt(value)
+}
+
+// AddInt32 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddInt32(key string, value int32) { e.AddInt64(key, int64(value)) }
+
+// AddInt16 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddInt16(key string, value int16) { e.AddInt64(key, int64(value)) }
+
+// AddInt8 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddInt8(key string, value int8) { e.AddInt64(key, int64(value)) }
+
+// AddString implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddString(key, value string) {
+	e.addKey(key)
+	e.appendText(value)
+}
+
+// AddTime implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddTime(key string, value time.Time) {
+	e.addKey(key)
+	e.buf.AppendTime(value, time.RFC3339Nano)
+}
+
+// AddUint implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddUint(key string, value uint) { e.AddUint64(key, uint64(value)) }
+
+// AddUint64 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddUint64(key string, value uint64) {
+	e.addKey(key)
+	e.buf.AppendUint(value)
+}
+
+// AddUint32 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddUint32(key string, value uint32) { e.AddUint64(key, uint64(value)) }
+
+// AddUint16 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddUint16(key string, value uint16) { e.AddUint64(key, uint64(value)) }
+
+// AddUint8 implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddUint8(key string, value uint8) { e.AddUint64(key, uint64(value)) }
+
+// AddUintptr implements zapcore.ObjectEncoder
+func (e *fastConsoleEncoder) AddUintptr(key string, value uintptr) { e.AddUint64(key, uint64(value)) }
+
+// primitiveAppender is a zapcore.ArrayEncoder writing the output of
+// time, level and caller encoders to a buffer
+type primitiveAppender struct {
+	buf *buffer.Buffer
+}
+
+func (p primitiveAppender) AppendBool(v bool)         { p.buf.AppendBool(v) }
+func (p primitiveAppender) AppendByteString(v []byte) { p.buf.Write(v) }
+func (p primitiveAppender) AppendComplex128(v complex128) {
+	p.buf.AppendString(strconv.FormatComplex(v, 'g', -1, 128))
+}
+func (p primitiveAppender) AppendComplex64(v complex64)    { p.AppendComplex128(complex128(v)) }
+func (p primitiveAppender) AppendFloat64(v float64)        { p.buf.AppendFloat(v, 64) }
+func (p primitiveAppender) AppendFloat32(v float32)        { p.buf.AppendFloat(float64(v), 32) }
+func (p primitiveAppender) AppendInt(v int)                { p.buf.AppendInt(int64(v)) }
+func (p primitiveAppender) AppendInt64(v int64)            { p.buf.AppendInt(v) }
+func (p primitiveAppender) AppendInt32(v int32)            { p.buf.AppendInt(int64(v)) }
+func (p primitiveAppender) AppendInt16(v int16)            { p.buf.AppendInt(int64(v)) }
+func (p primitiveAppender) AppendInt8(v int8)              { p.buf.AppendInt(int64(v)) }
+func (p primitiveAppender) AppendString(v string)          { p.buf.AppendString(v) }
+func (p primitiveAppender) AppendUint(v uint)              { p.buf.AppendUint(uint64(v)) }
+func (p primitiveAppender) AppendUint64(v uint64)          { p.buf.AppendUint(v) }
+func (p primitiveAppender) AppendUint32(v uint32)          { p.buf.AppendUint(uint64(v)) }
+func (p primitiveAppender) AppendUint16(v uint16)          { p.buf.AppendUint(uint64(v)) }
+func (p primitiveAppender) AppendUint8(v uint8)            { p.buf.AppendUint(uint64(v)) }
+func (p primitiveAppender) AppendUintptr(v uintptr)        { p.buf.AppendUint(uint64(v)) }
+func (p primitiveAppender) AppendDuration(v time.Duration) { p.buf.AppendString(v.String()) }
+func (p primitiveAppender) AppendTime(v time.Time)         { p.buf.AppendTime(v, time.RFC3339Nano) }
+func (p primitiveAppender) AppendArray(v zapcore.ArrayMarshaler) error {
+	return v.MarshalLogArray(p)
+}
+func (p primitiveAppender) AppendObject(v zapcore.ObjectMarshaler) error {
+	enc := zapcore.NewMapObjectEncoder()
+	if err := v.MarshalLogObject(enc); err != nil {
+		return err
+	}
+	return p.AppendReflected(enc.Fields)
+}
+func (p primitiveAppender) AppendReflected(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	p.buf.Write(b)
+	return nil
+}
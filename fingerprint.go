@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// FingerprintKey is the field holding an error's grouping fingerprint
+const FingerprintKey = "error.fingerprint"
+
+// loggerMethodPrefix identifies Logger methods when walking the stack
+var loggerMethodPrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	return name[:strings.LastIndexByte(name, '.')+1] + "(*Logger)."
+}()
+
+// packageDir identifies this package's frames when walking the stack
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// firstError returns the error value among fields, preferring the
+// "error" key and otherwise the lowest key for a stable choice
+func firstError(fields map[string]interface{}) error {
+	if err, ok := fields["error"].(error); ok && err != nil {
+		return err
+	}
+
+	var keys []string
+	for k, v := range fields {
+		if err, ok := v.(error); ok && err != nil {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fields[keys[0]].(error)
+}
+
+// errorFingerprint computes a stable hash of the message template, the
+// error type and the function that logged it, so identical failures
+// group together across instances
+func errorFingerprint(template string, err error) string {
+	h := sha256.New()
+	h.Write([]byte(template))
+	h.Write([]byte{0})
+	h.Write([]byte(fmt.Sprintf("%T", err)))
+	h.Write([]byte{0})
+	h.Write([]byte(callerFunction()))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// callerFunction returns the name of the first function on the stack
+// outside this package, so entries logged through helpers such as
+// Summary or Timer are attributed to their caller
+func callerFunction() string {
+	pcs := make([]uintptr, 32)
+	// Skip runtime.Callers, callerFunction and errorFingerprint
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return frame.Function
+		}
+		if !more {
+			return ""
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestFingerprintAttributesHelpersToCaller(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+
+	syncOrders(l)
+	syncUsers(l)
+
+	entries := logs.All()
+	first, second := entries[0].ContextMap()[FingerprintKey], entries[1].ContextMap()[FingerprintKey]
+	if first == nil || first == second {
+		t.Errorf("callers of Timer share fingerprint %v", first)
+	}
+}
+
+func syncOrders(l *Logger) {
+	l.StartTimer("sync").Done(map[string]interface{}{"error": errors.New("timeout")})
+}
+
+func syncUsers(l *Logger) {
+	l.StartTimer("sync").Done(map[string]interface{}{"error": errors.New("timeout")})
+}
+
+func TestFingerprintUsesTemplate(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+
+	for _, user := range []string{"alice", "bob"} {
+		l.ErrorT("login failed for {user}", map[string]interface{}{"user": user, "error": errors.New("denied")})
+	}
+
+	entries := logs.All()
+	first, second := entries[0].ContextMap()[FingerprintKey], entries[1].ContextMap()[FingerprintKey]
+	if first == nil || first != second {
+		t.Errorf("fingerprints %v and %v differ for one template", first, second)
+	}
+}
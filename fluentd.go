@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FluentdConfig configures a fluentd forward-protocol handler
+type FluentdConfig struct {
+	// Address of the fluentd or fluent-bit forward input, e.g. "localhost:24224"
+	Address string
+	// TagPrefix is prepended to the logger name to form the tag, e.g. "app"
+	TagPrefix string
+	// RequireAck has the server acknowledge every entry. Acknowledged
+	// entries are sent in the background, and entries whose ack times out
+	// are reported rather than resent, so they are never delivered twice.
+	RequireAck bool
+	// Timeout bounds connecting, writing and waiting for acks, defaults to 5s
+	Timeout time.Duration
+}
+
+// AddFluentdHandler adds a handler shipping entries to fluentd using
+// the forward protocol, tagged by logger name
+func (l *Logger) AddFluentdHandler(cfg FluentdConfig, level LogLevel, opts ...HandlerOption) error {
+	if cfg.Address == "" {
+		return fmt.Errorf("fluentd address must not be empty")
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("fluentd", false); err != nil {
+		return err
+	}
+	if handlerOpts.writeTimeout > 0 {
+		cfg.Timeout = handlerOpts.writeTimeout
+	}
+	spec := handlerSpec{kind: "fluentd", sink: cfg.Address, encoder: "msgpack", level: level}
+
+	// Dry-run handlers only need the encoded size
+	if handlerOpts.dryRun != nil {
+		return l.addDryRunCore(spec, handlerOpts)
+	}
+
+	core := &fluentdCore{
+		LevelEnabler: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return lvl >= level
+		}),
+		client:     newFluentdClient(cfg, l.internalErrors.report),
+		messageKey: l.mapKey("msg"),
+		levelKey:   l.mapKey("level"),
+		loggerKey:  l.mapKey("logger"),
+	}
+
+	state := newHandlerState(spec, handlerOpts)
+	state.reconnects = core.client.reconnectCount
+	if cfg.RequireAck {
+		state.queue = core.client.queueDepth
+		state.dropped = core.client.droppedCount
+	}
+	l.registerHandler(state, core)
+	return nil
+}
+
+// fluentdCore converts entries to fluentd records
+type fluentdCore struct {
+	zapcore.LevelEnabler
+	client     *fluentdClient
+	messageKey string
+	levelKey   string
+	loggerKey  string
+	fields     []zapcore.Field
+}
+
+// With implements zapcore.Core
+func (f *fluentdCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *f
+	clone.fields = append(append([]zapcore.Field{}, f.fields...), fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (f *fluentdCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if f.Enabled(ent.Level) {
+		return ce.AddCore(ent, f)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (f *fluentdCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range f.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	record := enc.Fields
+	record[f.messageKey] = ent.Message
+	record[f.levelKey] = ent.Level.String()
+	if ent.Stack != "" {
+		record["stacktrace"] = ent.Stack
+	}
+
+	// Tag entries by logger name
+	tag := f.client.cfg.TagPrefix
+	if name, ok := record[f.loggerKey].(string); ok && name != "" {
+		if tag != "" {
+			tag += "."
+		}
+		tag += name
+	}
+	if tag == "" {
+		tag = "log"
+	}
+
+	return f.client.send(tag, ent.Time, record)
+}
+
+// Sync implements zapcore.Core, waiting for queued entries to be
+// acknowledged
+func (f *fluentdCore) Sync() error {
+	return f.client.flush()
+}
+
+// fluentdQueueCapacity bounds the entries waiting to be acknowledged;
+// newer entries are dropped beyond it
+const fluentdQueueCapacity = 1024
+
+// errFluentdClosed is returned for entries written after Close
+var errFluentdClosed = errors.New("fluentd: handler closed")
+
+// fluentdChunk is an encoded entry waiting for acknowledged delivery,
+// or a marker closing synced once the entries before it are delivered
+type fluentdChunk struct {
+	data   []byte
+	chunk  string
+	synced chan struct{}
+}
+
+// fluentdClient maintains the connection to a fluentd server. With
+// RequireAck, entries are queued and delivered by a background writer,
+// so waiting for acks doesn't stall logging calls.
+type fluentdClient struct {
+	cfg        FluentdConfig
+	conn       net.Conn
+	reader     *bufio.Reader
+	dialed     bool
+	reconnects atomic.Int64
+	onError    func(error)
+	queue      chan fluentdChunk
+	dropped    atomic.Int64
+	done       chan struct{}
+	stopped    chan struct{}
+	closed     sync.Once
+	mu         sync.Mutex
+}
+
+// newFluentdClient creates a client, starting its writer if entries are
+// acknowledged
+func newFluentdClient(cfg FluentdConfig, onError func(error)) *fluentdClient {
+	c := &fluentdClient{cfg: cfg, onError: onError}
+	if cfg.RequireAck {
+		c.queue = make(chan fluentdChunk, fluentdQueueCapacity)
+		c.done = make(chan struct{})
+		c.stopped = make(chan struct{})
+		go c.run()
+	}
+	return c
+}
+
+// run delivers queued entries until Close, then delivers the rest
+func (c *fluentdClient) run() {
+	defer close(c.stopped)
+
+	for {
+		select {
+		case chunk := <-c.queue:
+			c.process(chunk)
+		case <-c.done:
+			for {
+				select {
+				case chunk := <-c.queue:
+					c.process(chunk)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// process delivers a queued entry, reporting failures
+func (c *fluentdClient) process(chunk fluentdChunk) {
+	if chunk.synced != nil {
+		close(chunk.synced)
+		return
+	}
+
+	c.mu.Lock()
+	err := c.deliver(chunk.data, chunk.chunk)
+	c.mu.Unlock()
+	if err != nil && c.onError != nil {
+		c.onError(err)
+	}
+}
+
+// flush waits until the entries queued so far are delivered
+func (c *fluentdClient) flush() error {
+	if c.queue == nil {
+		return nil
+	}
+
+	synced := make(chan struct{})
+	select {
+	case c.queue <- fluentdChunk{synced: synced}:
+	case <-c.done:
+		return errFluentdClosed
+	}
+	select {
+	case <-synced:
+		return nil
+	case <-c.stopped:
+		return errFluentdClosed
+	}
+}
+
+// queueDepth returns the number of entries waiting to be acknowledged
+func (c *fluentdClient) queueDepth() int {
+	return len(c.queue)
+}
+
+// droppedCount returns the number of entries dropped with a full queue
+func (c *fluentdClient) droppedCount() int64 {
+	return c.dropped.Load()
+}
+
+// reconnectCount returns the number of connection attempts after the first
+func (c *fluentdClient) reconnectCount() int64 {
+	return c.reconnects.Load()
+}
+
+// send writes a single entry in message mode, queueing it if it must be
+// acknowledged
+func (c *fluentdClient) send(tag string, t time.Time, record map[string]interface{}) error {
+	var chunk string
+	var buf bytes.Buffer
+	if c.cfg.RequireAck {
+		chunk = newChunkID()
+		appendMsgpackArrayHeader(&buf, 4)
+	} else {
+		appendMsgpackArrayHeader(&buf, 3)
+	}
+	appendMsgpackString(&buf, tag)
+	appendFluentdEventTime(&buf, t)
+	appendMsgpack(&buf, record)
+	if c.cfg.RequireAck {
+		appendMsgpack(&buf, map[string]interface{}{"chunk": chunk})
+	}
+
+	if c.cfg.RequireAck {
+		select {
+		case <-c.done:
+			return errFluentdClosed
+		default:
+		}
+		select {
+		case c.queue <- fluentdChunk{data: buf.Bytes(), chunk: chunk}:
+			return nil
+		default:
+			c.dropped.Add(1)
+			return fmt.Errorf("fluentd: queue full, entry dropped")
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.deliver(buf.Bytes(), "")
+}
+
+// deliver writes an entry, retrying once on a fresh connection if the
+// write fails, and waits for the ack of chunk, if any. Entries that
+// were written are not resent when their ack fails, as the server may
+// have received them.
+func (c *fluentdClient) deliver(data []byte, chunk string) error {
+	err := c.write(data)
+	if err != nil {
+		c.close()
+		err = c.write(data)
+	}
+	if err == nil && chunk != "" {
+		if err = c.awaitAck(chunk); err != nil {
+			err = fmt.Errorf("fluentd: chunk %s not acknowledged, not resent: %w", chunk, err)
+		}
+	}
+	if err != nil {
+		c.close()
+	}
+	return err
+}
+
+// write sends data, connecting first if needed
+func (c *fluentdClient) write(data []byte) error {
+	if c.conn == nil {
+		if c.dialed {
+			c.reconnects.Add(1)
+		}
+		c.dialed = true
+		conn, err := net.DialTimeout("tcp", c.cfg.Address, c.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+		c.reader = bufio.NewReader(conn)
+	}
+
+	if err := c.conn.SetDeadline(time.Now().Add(c.cfg.Timeout)); err != nil {
+		return err
+	}
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// awaitAck reads the server's ack of chunk
+func (c *fluentdClient) awaitAck(chunk string) error {
+	resp, err := readMsgpackStringMap(c.reader)
+	if err != nil {
+		return err
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("fluentd: unexpected ack %q for chunk %q", resp["ack"], chunk)
+	}
+	return nil
+}
+
+// Close implements io.Closer, delivering queued entries and closing the
+// connection when the handler is removed
+func (f *fluentdCore) Close() error {
+	if f.client.done != nil {
+		f.client.closed.Do(func() { close(f.client.done) })
+		<-f.client.stopped
+	}
+
+	f.client.mu.Lock()
+	defer f.client.mu.Unlock()
+
+	f.client.close()
+	return nil
+}
+
+// close drops the current connection
+func (c *fluentdClient) close() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+		c.reader = nil
+	}
+}
+
+// newChunkID returns a random chunk ID for acknowledgements
+func newChunkID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return base64.StdEncoding.EncodeToString(b[:])
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFluentdEventTime(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp unavailable: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 256)
+		n, _ := bufio.NewReader(conn).Read(buf)
+		received <- buf[:n]
+	}()
+
+	client := &fluentdClient{cfg: FluentdConfig{Address: ln.Addr().String(), Timeout: time.Second}}
+	defer client.close()
+	at := time.Unix(1700000000, 123456789)
+	if err := client.send("app", at, map[string]interface{}{"msg": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// [tag, time, record]: fixarray, fixstr "app", then the EventTime
+	data := <-received
+	prefix := []byte{0x93, 0xa3, 'a', 'p', 'p', 0xd7, 0x00}
+	if !bytes.HasPrefix(data, prefix) || len(data) < len(prefix)+8 {
+		t.Fatalf("entry starts with % x, want EventTime after the tag", data)
+	}
+	ts := data[len(prefix):]
+	if sec := binary.BigEndian.Uint32(ts[:4]); sec != 1700000000 {
+		t.Errorf("seconds = %d, want 1700000000", sec)
+	}
+	if nsec := binary.BigEndian.Uint32(ts[4:8]); nsec != 123456789 {
+		t.Errorf("nanoseconds = %d, want 123456789", nsec)
+	}
+}
+
+// fakeFluentd is a forward input recording the entries it receives,
+// acknowledging them if ack is set
+type fakeFluentd struct {
+	ln       net.Listener
+	ack      bool
+	conns    []net.Conn
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	received [][]byte
+}
+
+func newFakeFluentd(t *testing.T, ack bool) *fakeFluentd {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("tcp unavailable: %v", err)
+	}
+	f := &fakeFluentd{ln: ln, ack: ack}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			f.mu.Lock()
+			f.conns = append(f.conns, conn)
+			f.mu.Unlock()
+			f.wg.Add(1)
+			go f.serve(conn)
+		}
+	}()
+
+	// Release the server's sockets before the next test counts files
+	t.Cleanup(func() {
+		ln.Close()
+		f.mu.Lock()
+		for _, conn := range f.conns {
+			conn.Close()
+		}
+		f.mu.Unlock()
+		f.wg.Wait()
+	})
+	return f
+}
+
+// serve reads entries, each ending with the 24 byte chunk ID
+func (f *fakeFluentd) serve(conn net.Conn) {
+	defer f.wg.Done()
+	defer conn.Close()
+	buf := make([]byte, 4096)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.received = append(f.received, append([]byte{}, buf[:n]...))
+		f.mu.Unlock()
+
+		if f.ack {
+			var resp bytes.Buffer
+			appendMsgpack(&resp, map[string]interface{}{"ack": string(buf[n-24 : n])})
+			conn.Write(resp.Bytes())
+		}
+	}
+}
+
+func (f *fakeFluentd) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return len(f.received)
+}
+
+// fluentdErrors collects errors reported by a client
+type fluentdErrors struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+func (e *fluentdErrors) report(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.errs = append(e.errs, err)
+}
+
+func (e *fluentdErrors) count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return len(e.errs)
+}
+
+func TestFluentdAckTimeoutIsNotResent(t *testing.T) {
+	server := newFakeFluentd(t, false)
+	errs := &fluentdErrors{}
+	client := newFluentdClient(FluentdConfig{Address: server.ln.Addr().String(), RequireAck: true, Timeout: 100 * time.Millisecond}, errs.report)
+	core := &fluentdCore{client: client}
+	defer core.Close()
+
+	start := time.Now()
+	if err := client.send("app", time.Now(), map[string]interface{}{"msg": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= 100*time.Millisecond {
+		t.Errorf("send waited %v for the ack", elapsed)
+	}
+	if err := client.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs.count() != 1 {
+		t.Errorf("got %d errors, want the ack timeout", errs.count())
+	}
+	if n := server.count(); n != 1 {
+		t.Errorf("server received %d writes, want 1", n)
+	}
+}
+
+func TestFluentdRetriesFailedWrite(t *testing.T) {
+	server := newFakeFluentd(t, true)
+	errs := &fluentdErrors{}
+	client := newFluentdClient(FluentdConfig{Address: server.ln.Addr().String(), RequireAck: true, Timeout: time.Second}, errs.report)
+	core := &fluentdCore{client: client}
+	defer core.Close()
+
+	// Start from a broken connection
+	stale, err := net.Dial("tcp", server.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.Close()
+	client.conn, client.reader, client.dialed = stale, bufio.NewReader(stale), true
+
+	if err := client.send("app", time.Now(), map[string]interface{}{"msg": "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs.count() != 0 {
+		t.Errorf("got errors %v, want the entry delivered on retry", errs.errs)
+	}
+	if n := server.count(); n != 1 {
+		t.Errorf("server received %d writes, want 1", n)
+	}
+	if n := client.reconnectCount(); n != 1 {
+		t.Errorf("reconnected %d times, want 1", n)
+	}
+}
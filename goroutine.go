@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// Field keys used to label concurrent output
+const (
+	GoroutineIDKey = "goroutine_id"
+	WorkerKey      = "worker"
+)
+
+// EnableGoroutineID attaches the ID of the logging goroutine to every
+// entry, which helps untangle interleaved output from worker pools
+func (l *Logger) EnableGoroutineID() {
+	l.goroutineIDs.Store(true)
+}
+
+// DisableGoroutineID stops attaching goroutine IDs
+func (l *Logger) DisableGoroutineID() {
+	l.goroutineIDs.Store(false)
+}
+
+// WithWorker creates a new logger labeling entries with a worker name
+func (l *Logger) WithWorker(name string) *Logger {
+	return l.WithContext(map[string]interface{}{
+		WorkerKey: name,
+	})
+}
+
+// goroutineIDField returns the goroutine ID field, if enabled
+func goroutineIDField(enabled *atomic.Bool) []zap.Field {
+	if !enabled.Load() {
+		return nil
+	}
+	return []zap.Field{zap.Uint64(GoroutineIDKey, currentGoroutineID())}
+}
+
+// currentGoroutineID parses the current goroutine's ID from its stack
+// header, "goroutine 123 [running]:"
+func currentGoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
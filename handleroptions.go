@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// handlerOptions holds per-handler settings overriding logger defaults
+type handlerOptions struct {
+	timeFormat     *string
+	timeZone       *string
+	dryRun         *DryRunReport
+	name           string
+	backpressure   *BackpressureConfig
+	writeTimeout   time.Duration
+	fastConsole    bool
+	encoding       string
+	batching       *BatchConfig
+	prealloc       *PreallocConfig
+	diskFull       *diskFullConfig
+	multiline      map[string]struct{}
+	theme          *ConsoleTheme
+	symbols        bool
+	colorMode      *ColorMode
+	tenants        map[string]struct{}
+	maxSensitivity *sensitivityLimit
+}
+
+// HandlerOption configures a single handler
+type HandlerOption func(*handlerOptions)
+
+// WithTimeFormat overrides the logger's timestamp format and time zone
+// for one handler
+func WithTimeFormat(format, zone string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.timeFormat = &format
+		o.timeZone = &zone
+	}
+}
+
+// newHandlerOptions applies opts on top of the defaults
+func newHandlerOptions(opts []HandlerOption) handlerOptions {
+	var o handlerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// validate reports invalid handler options, and options a handler can't
+// honour. sink reports whether the handler writes encoded entries to a
+// sink, which buffering options need.
+func (o handlerOptions) validate(kind string, sink bool) error {
+	if o.backpressure != nil {
+		if !sink {
+			return fmt.Errorf("%s handler: WithBackpressure needs a handler writing to a sink", kind)
+		}
+		if o.backpressure.Policy == OverflowSpill && o.backpressure.SpillPath == "" {
+			return fmt.Errorf("%s handler: OverflowSpill needs a SpillPath", kind)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// handlerSpec describes a handler being registered
+type handlerSpec struct {
+	kind    string
+	sink    string
+	encoder string
+	level   LogLevel
+}
+
+// handlerState identifies a registered handler and tracks its activity
+type handlerState struct {
+	handlerSpec
+	name           string
+	core           zapcore.Core
+	tenants        map[string]struct{}
+	maxSensitivity *sensitivityLimit
+	queue          func() int
+	reconnects     func() int64
+	dropped        func() int64
+	degraded       func() bool
+	worker         *handlerWorker
+	// closers release the handler's files, connections and goroutines
+	// when it is removed, outermost first
+	closers []io.Closer
+	mirror  zapcore.Core
+	// mirrorWorker writes the primary's entries to its shadow, and
+	// mirrorDropped counts the entries a shadow's worker had no room for
+	mirrorWorker  *handlerWorker
+	mirrorDropped atomic.Int64
+	shadow        *handlerState
+	primary       *handlerState
+	muted         atomic.Bool
+	entries       atomic.Int64
+	bytes         atomic.Int64
+	errors        atomic.Int64
+	lastWrite     atomic.Int64
+	lastErrorAt   atomic.Int64
+	lastError     atomic.Pointer[error]
+	// removed rejects writes once the handler is removed, which the
+	// lock-free Sugar path may still attempt; writes hold removing for
+	// reading, so none is in progress when the sink is closed
+	removed  bool
+	removing sync.RWMutex
+}
+
+// errHandlerRemoved is returned for entries written to a removed handler
+var errHandlerRemoved = errors.New("handler removed")
+
+// reject makes the handler refuse writes, waiting for writes in progress
+func (s *handlerState) reject() {
+	s.removing.Lock()
+	defer s.removing.Unlock()
+
+	s.removed = true
+}
+
+// recordWrite updates the handler's counters after a write
+func (s *handlerState) recordWrite(err error) {
+	now := time.Now().UnixNano()
+	if err != nil {
+		s.errors.Add(1)
+		s.lastError.Store(&err)
+		s.lastErrorAt.Store(now)
+		return
+	}
+	s.entries.Add(1)
+	s.lastWrite.Store(now)
+}
+
+// onClose registers c to be closed when the handler is removed
+func (s *handlerState) onClose(c io.Closer) {
+	s.closers = append(s.closers, c)
+}
+
+// close releases the handler's resources, joining their errors
+func (s *handlerState) close() error {
+	var errs []error
+	for _, c := range s.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handlerRegistry holds the state of every registered handler.
+// It is shared by a logger and its children.
+type handlerRegistry struct {
+	handlers []*handlerState
+	mu       sync.RWMutex
+}
+
+// add registers a handler, making its name unique
+func (r *handlerRegistry) add(state *handlerState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := state.name
+	for i := 2; r.lookupLocked(name) != nil; i++ {
+		name = fmt.Sprintf("%s#%d", state.name, i)
+	}
+	state.name = name
+
+	r.handlers = append(r.handlers, state)
+}
+
+// lookup returns the handler with the given name
+func (r *handlerRegistry) lookup(name string) *handlerState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lookupLocked(name)
+}
+
+// lookupLocked returns the handler with the given name.
+// The caller must hold the lock.
+func (r *handlerRegistry) lookupLocked(name string) *handlerState {
+	for _, h := range r.handlers {
+		if h.name == name {
+			return h
+		}
+	}
+	return nil
+}
+
+// remove unregisters the handler with the given name, returning it
+func (r *handlerRegistry) remove(name string) *handlerState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, h := range r.handlers {
+		if h.name == name {
+			r.handlers = append(r.handlers[:i:i], r.handlers[i+1:]...)
+			return h
+		}
+	}
+	return nil
+}
+
+// all returns the registered handlers
+func (r *handlerRegistry) all() []*handlerState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*handlerState{}, r.handlers...)
+}
+
+// HandlerInfo describes a registered handler and its activity, e.g. for
+// admin pages showing where logs are going
+type HandlerInfo struct {
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Sink    string   `json:"sink,omitempty"`
+	Encoder string   `json:"encoder,omitempty"`
+	Level   LogLevel `json:"level"`
+	// Shadow names the handler receiving a copy of this handler's
+	// entries, Primary the handler this one shadows
+	Shadow  string `json:"shadow,omitempty"`
+	Primary string `json:"primary,omitempty"`
+	HandlerStats
+}
+
+// Handlers describes the registered handlers in registration order
+func (l *Logger) Handlers() []HandlerInfo {
+	// Shadowing is changed under the lock
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	handlers := l.handlers.all()
+	infos := make([]HandlerInfo, 0, len(handlers))
+	for _, h := range handlers {
+		info := HandlerInfo{
+			Name:         h.name,
+			Kind:         h.kind,
+			Sink:         h.sink,
+			Encoder:      h.encoder,
+			Level:        h.level,
+			HandlerStats: h.stats(),
+		}
+		if shadow := h.shadow; shadow != nil {
+			info.Shadow = shadow.name
+		}
+		if primary := h.primary; primary != nil {
+			info.Primary = primary.name
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// WithName names a handler, e.g. for statistics. Names default to the
+// handler kind and sink, such as "console" or "file:app.log".
+func WithName(name string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.name = name
+	}
+}
+
+// newHandlerState creates the state of a handler about to be registered
+func newHandlerState(spec handlerSpec, opts handlerOptions) *handlerState {
+	name := opts.name
+	if name == "" {
+		name = spec.kind
+		if spec.sink != "" {
+			name += ":" + spec.sink
+		}
+	}
+	return &handlerState{handlerSpec: spec, name: name, tenants: opts.tenants, maxSensitivity: opts.maxSensitivity}
+}
+
+// registerHandler wraps a handler's core to track its activity and adds
+// it to the wrapper. Cores holding connections implement io.Closer and
+// are closed when the handler is removed. The caller must hold the lock.
+func (l *Logger) registerHandler(state *handlerState, core zapcore.Core) {
+	if closer, ok := core.(io.Closer); ok {
+		state.onClose(closer)
+	}
+	if state.maxSensitivity != nil {
+		core = newSensitivityCore(core, l.classes, l.mappedKeys, *state.maxSensitivity)
+	}
+	core = &handlerCore{Core: core, state: state}
+	if state.kind == "console" {
+		core = &liveFilterCore{Core: core, filters: l.liveFilters, mapKey: l.mapKey}
+	}
+	core = &routingCore{Core: core, key: TenantKey, mapKey: l.mapKey, accept: l.tenants.accepts(state.tenants)}
+	core = &routingCore{Core: core, key: RegionKey, mapKey: l.mapKey, accept: l.regions.accepts(state)}
+	if async := l.async.Load(); async != nil && !l.twelveFactor {
+		core = l.newWorkerCore(state, *async, core)
+	}
+	state.core = l.createRedactingCore(core)
+	l.handlers.add(state)
+
+	l.coreWrapper.AddCore(state.core)
+}
+
+// RemoveHandler stops writing to the named handler after syncing it,
+// reporting whether it existed, and closes its files, connections and
+// background goroutines. Handlers are named by WithName or default to
+// their kind and sink, as listed by Stats. Removing a shadowed handler
+// promotes its shadow.
+func (l *Logger) RemoveHandler(name string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := l.handlers.remove(name)
+	if state == nil {
+		return false, nil
+	}
+	switch {
+	case state.primary != nil:
+		l.detachShadow(state.primary)
+	case state.shadow != nil:
+		shadow := state.shadow
+		l.detachShadow(state)
+		l.coreWrapper.ReplaceCore(state.core, shadow.core)
+	default:
+		l.coreWrapper.RemoveCore(state.core)
+	}
+
+	// Write queued entries before releasing the sink
+	if state.worker != nil {
+		state.worker.stop()
+	}
+	state.reject()
+	return true, errors.Join(state.core.Sync(), state.close())
+}
+
+// Close syncs and removes every handler, closing their files,
+// connections and background goroutines, and stops syncing on exit
+// signals. Defer it from main so buffered entries are written before
+// the process exits.
+func (l *Logger) Close() error {
+	l.exitFlush.stop()
+
+	var errs []error
+	for _, h := range l.handlers.all() {
+		if _, err := l.RemoveHandler(h.name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MuteHandler silences the named handler without removing it, keeping
+// its connections, files and statistics, reporting whether it exists
+func (l *Logger) MuteHandler(name string) bool {
+	return l.setMuted(name, true)
+}
+
+// UnmuteHandler resumes writing to a handler silenced by MuteHandler,
+// reporting whether it exists
+func (l *Logger) UnmuteHandler(name string) bool {
+	return l.setMuted(name, false)
+}
+
+// setMuted mutes or unmutes the named handler
+func (l *Logger) setMuted(name string, muted bool) bool {
+	state := l.handlers.lookup(name)
+	if state == nil {
+		return false
+	}
+	state.muted.Store(muted)
+	return true
+}
+
+// handlerCore records writes to a handler in its state
+type handlerCore struct {
+	zapcore.Core
+	state *handlerState
+}
+
+// With implements zapcore.Core
+func (h *handlerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &handlerCore{Core: h.Core.With(fields), state: h.state}
+}
+
+// Check implements zapcore.Core
+func (h *handlerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if h.Enabled(ent.Level) && !h.state.muted.Load() {
+		return ce.AddCore(ent, h)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (h *handlerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if h.state.muted.Load() {
+		return nil
+	}
+
+	h.state.removing.RLock()
+	defer h.state.removing.RUnlock()
+
+	if h.state.removed {
+		return &HandlerError{Handler: h.state.name, Err: errHandlerRemoved}
+	}
+	err := h.Core.Write(ent, fields)
+	h.state.recordWrite(err)
+	if err != nil {
+		return &HandlerError{Handler: h.state.name, Err: err}
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (h *handlerCore) Sync() error {
+	if err := h.Core.Sync(); err != nil {
+		return &HandlerError{Handler: h.state.name, Err: err}
+	}
+	return nil
+}
+
+// HandlerError identifies the handler that failed to write or sync.
+// Errors from several handlers are combined with errors.Join; use
+// errors.As to inspect them.
+type HandlerError struct {
+	Handler string
+	Err     error
+}
+
+// Error implements error
+func (e *HandlerError) Error() string {
+	return "handler " + e.Handler + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// countingWriter counts the bytes written to a handler's sink
+type countingWriter struct {
+	zapcore.WriteSyncer
+	state *handlerState
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.state.bytes.Add(int64(n))
+	return n, err
+}
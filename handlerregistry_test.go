@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// closingCore records whether the handler released it
+type closingCore struct {
+	zapcore.Core
+	closed bool
+}
+
+func (c *closingCore) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestRemoveHandlerClosesCore(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	observed, _ := observer.New(zapcore.InfoLevel)
+	core := &closingCore{Core: observed}
+
+	l.mu.Lock()
+	l.registerHandler(newHandlerState(handlerSpec{kind: "closing"}, handlerOptions{}), core)
+	l.mu.Unlock()
+
+	if ok, err := l.RemoveHandler("closing"); !ok || err != nil {
+		t.Fatalf("RemoveHandler = %v, %v", ok, err)
+	}
+	if !core.closed {
+		t.Error("core was not closed")
+	}
+}
+
+func TestRemovedHandlerRejectsWrites(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	observed, logs := observer.New(zapcore.InfoLevel)
+	core := &closingCore{Core: observed}
+
+	l.mu.Lock()
+	l.registerHandler(newHandlerState(handlerSpec{kind: "closing"}, handlerOptions{}), core)
+	l.mu.Unlock()
+
+	// The lock-free Sugar path may still hold the handler's core
+	held := l.handlers.all()[0].core
+	if _, err := l.RemoveHandler("closing"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := held.Write(zapcore.Entry{Level: zapcore.InfoLevel, Message: "late"}, nil)
+	if !errors.Is(err, errHandlerRemoved) {
+		t.Errorf("Write = %v, want handler removed", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("closed core got %d entries", logs.Len())
+	}
+}
+
+func TestRemoveHandlerClosesFile(t *testing.T) {
+	fds := func() int {
+		entries, err := os.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip("no /proc/self/fd")
+		}
+		return len(entries)
+	}
+
+	l := NewLogger("app", zapcore.InfoLevel)
+	before := fds()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := l.AddFileHandler(path, zapcore.InfoLevel, WithName("file"), WithBackpressure(BackpressureConfig{})); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("written")
+
+	if _, err := l.RemoveHandler("file"); err != nil {
+		t.Fatal(err)
+	}
+	if after := fds(); after != before {
+		t.Errorf("open files = %d after removal, want %d", after, before)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		t.Errorf("file = %q, %v; want the entry written before removal", data, err)
+	}
+}
+
+func TestClosedAsyncWriterStops(t *testing.T) {
+	sink := &recordingSink{release: make(chan struct{})}
+	close(sink.release)
+	w := newAsyncWriter(sink, BackpressureConfig{Capacity: 4, DrainTimeout: 5 * time.Second}, func(err error) { t.Error(err) })
+
+	if _, err := w.Write([]byte("entry\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.writes) != 1 {
+		t.Errorf("writes = %q, want the buffered entry", sink.writes)
+	}
+	if _, err := w.Write([]byte("late\n")); !errors.Is(err, errBufferClosed) {
+		t.Errorf("Write after Close = %v, want errBufferClosed", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"io"
 	"os"
 
 	"go.uber.org/zap"
@@ -8,88 +9,267 @@ import (
 )
 
 // AddConsoleHandler adds a console output handler
-func (l *Logger) AddConsoleHandler(level LogLevel, development bool) {
+func (l *Logger) AddConsoleHandler(level LogLevel, development bool, opts ...HandlerOption) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	// Report invalid options, adding the handler without them
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("console", true); err != nil {
+		l.internalErrors.report(err)
+		handlerOpts.backpressure = nil
+	}
+
+	// Color output unless disabled by the color mode or environment
+	colorMode := l.colorMode
+	if handlerOpts.colorMode != nil {
+		colorMode = *handlerOpts.colorMode
+	}
+	color := useColor(colorMode, os.Stdout)
+	levelEncoder := zapcore.CapitalLevelEncoder
+	if color {
+		levelEncoder = zapcore.CapitalColorLevelEncoder
+	} else if handlerOpts.theme != nil || handlerOpts.symbols {
+		handlerOpts.theme = &ConsoleTheme{}
+	}
+
 	// Create encoder configuration
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "time",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "msg",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.CapitalColorLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.StringDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
+	encoderConfig, err := l.newEncoderConfig(levelEncoder, handlerOpts)
+	if err != nil {
+		l.internalErrors.report(err)
+	}
+	if handlerOpts.theme != nil {
+		encoderConfig.EncodeLevel = handlerOpts.theme.levelEncoder()
+	}
+	if handlerOpts.symbols && development {
+		encoderConfig.EncodeLevel = symbolLevelEncoder(handlerOpts.theme)
+		encoderConfig.EncodeTime = relativeTimeEncoder
 	}
 
 	// Create a console encoder
+	spec := handlerSpec{kind: "console", level: level}
 	var encoder zapcore.Encoder
-	if development {
+	if development && handlerOpts.fastConsole {
+		encoder = newFastConsoleEncoder(encoderConfig)
+		spec.encoder = "fast-console"
+	} else if development {
 		encoder = zapcore.NewConsoleEncoder(encoderConfig)
+		spec.encoder = "console"
 	} else {
 		encoder = zapcore.NewJSONEncoder(encoderConfig)
+		spec.encoder = "json"
+	}
+	if handlerOpts.encoding != "" {
+		override, err := l.newEncoding(handlerOpts.encoding, color, handlerOpts)
+		if err != nil {
+			l.internalErrors.report(err)
+		}
+		if override != nil {
+			encoder, spec.encoder = override, handlerOpts.encoding
+		}
 	}
 
-	// Create a level enabler
-	levelEnabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl >= level
-	})
-
-	// Create a core
-	core := zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), levelEnabler)
+	// Color messages and render selected fields as blocks, unless the
+	// output is machine-readable
+	humanReadable := spec.encoder == "console" || spec.encoder == "fast-console"
+	if handlerOpts.theme != nil && humanReadable {
+		encoder = &themedEncoder{Encoder: encoder, cfg: encoderConfig, theme: *handlerOpts.theme}
+	}
+	if handlerOpts.multiline != nil && humanReadable {
+		encoder = multilineEncoder{Encoder: encoder, keys: handlerOpts.multiline}
+	}
 
-	// Add the core to the wrapper
-	l.coreWrapper.AddCore(l.createRedactingCore(core))
+	// Create the handler core
+	l.addHandlerCore(spec, encoder, zapcore.AddSync(os.Stdout), handlerOpts)
 }
 
 // AddFileHandler adds a file output handler
-func (l *Logger) AddFileHandler(filePath string, level LogLevel) error {
+func (l *Logger) AddFileHandler(filePath string, level LogLevel, opts ...HandlerOption) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	// Open the log file
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+	if l.twelveFactor {
+		return ErrFileHandlersDisabled
 	}
 
 	// Create encoder configuration
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "time",
-		LevelKey:       "level",
-		NameKey:        "logger",
-		CallerKey:      "caller",
-		MessageKey:     "msg",
-		StacktraceKey:  "stacktrace",
-		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.StringDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("file", true); err != nil {
+		return err
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		return err
 	}
 
-	// Create a JSON encoder
+	// Create a JSON encoder, unless the handler chose another encoding
+	spec := handlerSpec{kind: "file", sink: filePath, encoder: "json", level: level}
 	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	if handlerOpts.encoding != "" {
+		if encoder, err = l.newEncoding(handlerOpts.encoding, false, handlerOpts); err != nil {
+			return err
+		}
+		spec.encoder = handlerOpts.encoding
+	}
+
+	// Open the log file, unless the handler only counts what it would write
+	var sink zapcore.WriteSyncer
+	if handlerOpts.dryRun == nil && handlerOpts.prealloc != nil {
+		file, err := openPreallocatedFile(filePath, *handlerOpts.prealloc, l.internalErrors.report)
+		if err != nil {
+			return err
+		}
+		sink = file
+	} else if handlerOpts.dryRun == nil {
+		file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		sink = zapcore.AddSync(file)
+	}
+	file, _ := sink.(io.Closer)
+
+	// Degrade instead of failing every write while the disk is full
+	if sink != nil {
+		sink = l.newDiskFullWriter(sink, filePath, handlerOpts.diskFull)
+	}
+
+	// Create the handler core, closing the file when it is removed
+	state := l.addHandlerCore(spec, encoder, sink, handlerOpts)
+	if file != nil {
+		state.onClose(file)
+	}
+
+	return nil
+}
+
+// addHandlerCore creates a core writing encoded entries to sink and
+// registers it as a handler, returning its state. Buffering added here is
+// stopped when the handler is removed; callers owning sink register it
+// with onClose. The caller must hold the lock.
+func (l *Logger) addHandlerCore(spec handlerSpec, encoder zapcore.Encoder, sink zapcore.WriteSyncer, opts handlerOptions) *handlerState {
+	state := newHandlerState(spec, opts)
+
+	// Twelve-factor handlers keep nothing buffered
+	if l.twelveFactor {
+		opts.batching, opts.backpressure = nil, nil
+	}
+
+	// Expose reconnects of network sinks
+	if reconnecting, ok := sink.(interface{ reconnectCount() int64 }); ok {
+		state.reconnects = reconnecting.reconnectCount
+	}
+
+	// Bound writes to sinks that may hang
+	if opts.writeTimeout > 0 && sink != nil {
+		sink = &timeoutWriter{WriteSyncer: sink, timeout: opts.writeTimeout}
+	}
+
+	// Group entries into fewer writes
+	// Closers run outermost first, so buffers drain into open sinks
+	var closers []io.Closer
+	if opts.batching != nil && sink != nil && opts.dryRun == nil {
+		batched := newBatchSink(sink, *opts.batching, func(err error) {
+			state.recordWrite(err)
+			l.internalErrors.report(err)
+		})
+		sink = batched
+		closers = append([]io.Closer{batched}, closers...)
+	}
+
+	// Buffer entries for slow sinks
+	if opts.backpressure != nil && opts.dryRun == nil {
+		async := newAsyncWriter(sink, *opts.backpressure, func(err error) {
+			state.recordWrite(err)
+			l.internalErrors.report(err)
+		})
+		sink = async
+		closers = append([]io.Closer{async}, closers...)
+	}
+	for _, c := range closers {
+		state.onClose(c)
+	}
+
+	// Expose the depth of buffering sinks
+	if queued, ok := sink.(interface{ queueDepth() int }); ok {
+		state.queue = queued.queueDepth
+	}
+	if dropping, ok := sink.(interface{ droppedCount() int64 }); ok {
+		state.dropped = dropping.droppedCount
+	}
+	if degrading, ok := sink.(interface{ isDegraded() bool }); ok {
+		state.degraded = degrading.isDegraded
+	}
 
 	// Create a level enabler
 	levelEnabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
-		return lvl >= level
+		return lvl >= spec.level
 	})
 
+	// Dry-run handlers write into their report instead of the sink
+	if opts.dryRun != nil {
+		sink = opts.dryRun
+	}
+
 	// Create a core
-	core := zapcore.NewCore(encoder, zapcore.AddSync(file), levelEnabler)
+	var core zapcore.Core = zapcore.NewCore(encoder, &countingWriter{WriteSyncer: sink, state: state}, levelEnabler)
+	if opts.dryRun != nil {
+		core = &dryRunCore{Core: core, report: opts.dryRun}
+	}
 
 	// Add the core to the wrapper
-	l.coreWrapper.AddCore(l.createRedactingCore(core))
+	l.registerHandler(state, core)
+	return state
+}
 
+// addDryRunCore adds a dry-run core for handlers that don't encode
+// entries themselves, measuring them as JSON. The caller must hold the lock.
+func (l *Logger) addDryRunCore(spec handlerSpec, opts handlerOptions) error {
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, opts)
+	if err != nil {
+		return err
+	}
+	spec.encoder = "json"
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), nil, opts)
 	return nil
 }
 
+// newEncoderConfig returns the encoder configuration shared by handlers,
+// with output keys renamed according to the logger's key mapping.
+// An invalid time setting falls back to ISO8601 and is returned as error.
+// The caller must hold the lock.
+func (l *Logger) newEncoderConfig(levelEncoder zapcore.LevelEncoder, opts handlerOptions) (zapcore.EncoderConfig, error) {
+	// Resolve the time encoder, preferring handler-level settings
+	timeFormat, timeZone := l.timeFormat, l.timeZone
+	if opts.timeFormat != nil {
+		timeFormat = *opts.timeFormat
+	}
+	if opts.timeZone != nil {
+		timeZone = *opts.timeZone
+	}
+	timeEncoder, err := newTimeEncoder(timeFormat, timeZone)
+	if err != nil {
+		timeEncoder = zapcore.ISO8601TimeEncoder
+	}
+	if timeZone == "" {
+		timeEncoder = utcTimeEncoder(l.utc, timeEncoder)
+	}
+
+	return zapcore.EncoderConfig{
+		TimeKey:        l.mapKey("time"),
+		LevelKey:       l.mapKey("level"),
+		NameKey:        l.mapKey("logger"),
+		CallerKey:      l.mapKey("caller"),
+		MessageKey:     l.mapKey("msg"),
+		StacktraceKey:  l.mapKey("stacktrace"),
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    levelEncoder,
+		EncodeTime:     timeEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}, err
+}
+
 // createRedactingCore wraps a core with redaction functionality
 func (l *Logger) createRedactingCore(core zapcore.Core) zapcore.Core {
 	return &redactingCore{
@@ -98,17 +278,36 @@ func (l *Logger) createRedactingCore(core zapcore.Core) zapcore.Core {
 	}
 }
 
-// redactingCore is a zapcore.Core wrapper that redacts log messages
+// redactingCore is a zapcore.Core wrapper that redacts log messages and
+// fields. It adds itself to checked entries so that redaction applies
+// whether entries reach the handler through Check or Write.
 type redactingCore struct {
 	zapcore.Core
 	logger *Logger
 }
 
+// With implements zapcore.Core
+func (rc *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{
+		Core:   rc.Core.With(rc.logger.publishedRedactor().fields(fields)),
+		logger: rc.logger,
+	}
+}
+
 // Check implements zapcore.Core
 func (rc *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
-	// Redact the message
-	redactedEntry := ent
-	redactedEntry.Message = rc.logger.redactMessage(ent.Message)
+	if rc.Enabled(ent.Level) {
+		return ce.AddCore(ent, rc)
+	}
+	return ce
+}
 
-	return rc.Core.Check(redactedEntry, ce)
+// Write implements zapcore.Core
+func (rc *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if skipsRedaction(fields) {
+		return rc.Core.Write(ent, fields)
+	}
+	r := rc.logger.publishedRedactor()
+	ent.Message = r.string(ent.Message)
+	return rc.Core.Write(ent, r.fields(fields))
 }
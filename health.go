@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// HandlerHealth reports the status of a handler
+type HandlerHealth struct {
+	// Healthy is false while the most recent write failed
+	Healthy     bool       `json:"healthy"`
+	LastWriteAt *time.Time `json:"last_write_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// Reconnects counts reconnect attempts of network handlers
+	Reconnects int64 `json:"reconnects"`
+	// DiskFree is the space available to file handlers, in bytes
+	DiskFree *uint64 `json:"disk_free,omitempty"`
+}
+
+// Health reports the status of every handler, keyed by handler name,
+// e.g. for readiness probes
+func (l *Logger) Health() map[string]HandlerHealth {
+	health := map[string]HandlerHealth{}
+	for _, h := range l.handlers.all() {
+		lastWrite, lastErrorAt := h.lastWrite.Load(), h.lastErrorAt.Load()
+		status := HandlerHealth{Healthy: lastErrorAt == 0 || lastWrite > lastErrorAt}
+
+		if lastWrite != 0 {
+			at := time.Unix(0, lastWrite)
+			status.LastWriteAt = &at
+		}
+		if err := h.lastError.Load(); err != nil {
+			at := time.Unix(0, lastErrorAt)
+			status.LastError = (*err).Error()
+			status.LastErrorAt = &at
+		}
+		if h.reconnects != nil {
+			status.Reconnects = h.reconnects()
+		}
+		if h.kind == "file" {
+			if free, err := diskFree(filepath.Dir(h.sink)); err == nil {
+				status.DiskFree = &free
+				if free == 0 {
+					status.Healthy = false
+				}
+			}
+		}
+		health[h.name] = status
+	}
+	return health
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Incident providers supported by the incident handler
+const (
+	IncidentPagerDuty = "pagerduty"
+	IncidentOpsgenie  = "opsgenie"
+)
+
+// Default incident API endpoints
+const (
+	pagerDutyEndpoint = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieEndpoint  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// IncidentConfig configures an incident handler
+type IncidentConfig struct {
+	// Provider is IncidentPagerDuty or IncidentOpsgenie
+	Provider string
+	// Key is the PagerDuty routing key or the Opsgenie API key
+	Key string
+	// Endpoint overrides the provider's API URL, e.g. for EU accounts
+	Endpoint string
+	// Source identifies the service, defaults to the hostname
+	Source string
+	// RepeatedErrors raises an incident when the same error fingerprint
+	// is logged this many times within ErrorWindow; zero ignores Errors
+	RepeatedErrors int
+	// ErrorWindow defaults to 5 minutes
+	ErrorWindow time.Duration
+	// Client is the HTTP client used for alerts, defaults to one with a
+	// 10 second timeout
+	Client *http.Client
+}
+
+// AddIncidentHandler adds a handler converting Fatal entries, and
+// optionally repeated Error entries, into PagerDuty or Opsgenie alerts
+// deduplicated by error fingerprint
+func (l *Logger) AddIncidentHandler(cfg IncidentConfig) error {
+	switch cfg.Provider {
+	case IncidentPagerDuty:
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = pagerDutyEndpoint
+		}
+	case IncidentOpsgenie:
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = opsgenieEndpoint
+		}
+	default:
+		return fmt.Errorf("unsupported incident provider %q", cfg.Provider)
+	}
+	if cfg.Key == "" {
+		return fmt.Errorf("incident provider key is required")
+	}
+	if cfg.Source == "" {
+		cfg.Source, _ = os.Hostname()
+	}
+	if cfg.ErrorWindow <= 0 {
+		cfg.ErrorWindow = 5 * time.Minute
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	minLevel := zapcore.FatalLevel
+	if cfg.RepeatedErrors > 0 {
+		minLevel = zapcore.ErrorLevel
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	core := &incidentCore{
+		LevelEnabler: minLevel,
+		cfg:          cfg,
+		loggerKey:    l.mapKey("logger"),
+		errors:       &errorCounts{seen: map[string][]time.Time{}},
+	}
+
+	spec := handlerSpec{kind: "incident", sink: cfg.Provider, encoder: "json", level: minLevel}
+	l.registerHandler(newHandlerState(spec, handlerOptions{}), core)
+	return nil
+}
+
+// errorCounts tracks recent occurrences of Error fingerprints
+type errorCounts struct {
+	seen map[string][]time.Time
+	mu   sync.Mutex
+}
+
+// record adds an occurrence and reports whether the threshold is reached
+func (c *errorCounts) record(key string, now time.Time, window time.Duration, threshold int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Drop occurrences outside the window
+	recent := c.seen[key][:0]
+	for _, t := range c.seen[key] {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, now)
+
+	if len(recent) >= threshold {
+		delete(c.seen, key)
+		return true
+	}
+	c.seen[key] = recent
+	return false
+}
+
+// incidentCore sends alerts for qualifying entries
+type incidentCore struct {
+	zapcore.LevelEnabler
+	cfg       IncidentConfig
+	loggerKey string
+	errors    *errorCounts
+	fields    []zapcore.Field
+}
+
+// With implements zapcore.Core
+func (i *incidentCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *i
+	clone.fields = append(append([]zapcore.Field{}, i.fields...), fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (i *incidentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if i.Enabled(ent.Level) {
+		return ce.AddCore(ent, i)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (i *incidentCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range i.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	logger, _ := enc.Fields[i.loggerKey].(string)
+	dedupKey := incidentDedupKey(ent.Message, logger, enc.Fields)
+
+	// Errors only page when they repeat
+	if ent.Level < zapcore.FatalLevel {
+		if !i.errors.record(dedupKey, ent.Time, i.cfg.ErrorWindow, i.cfg.RepeatedErrors) {
+			return nil
+		}
+	}
+
+	var body interface{}
+	var header, headerValue string
+	switch i.cfg.Provider {
+	case IncidentOpsgenie:
+		header, headerValue = "Authorization", "GenieKey "+i.cfg.Key
+		body = map[string]interface{}{
+			"message":     truncate(ent.Message, 130),
+			"alias":       dedupKey,
+			"description": ent.Message,
+			"source":      i.cfg.Source,
+			"priority":    opsgeniePriority(ent.Level),
+			"details":     stringDetails(enc.Fields),
+		}
+	default:
+		body = map[string]interface{}{
+			"routing_key":  i.cfg.Key,
+			"event_action": "trigger",
+			"dedup_key":    dedupKey,
+			"payload": map[string]interface{}{
+				"summary":        truncate(ent.Message, 1024),
+				"source":         i.cfg.Source,
+				"severity":       pagerDutySeverity(ent.Level),
+				"timestamp":      ent.Time.Format(time.RFC3339Nano),
+				"component":      logger,
+				"custom_details": enc.Fields,
+			},
+		}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, i.cfg.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if header != "" {
+		req.Header.Set(header, headerValue)
+	}
+
+	resp, err := i.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: unexpected status %s", i.cfg.Provider, resp.Status)
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core
+func (i *incidentCore) Sync() error {
+	return nil
+}
+
+// incidentDedupKey prefers the error fingerprint and otherwise hashes
+// the logger name and message
+func incidentDedupKey(msg, logger string, fields map[string]interface{}) string {
+	if fingerprint, ok := fields[FingerprintKey].(string); ok && fingerprint != "" {
+		return fingerprint
+	}
+	sum := sha256.Sum256([]byte(logger + "\x00" + msg))
+	return hex.EncodeToString(sum[:8])
+}
+
+// pagerDutySeverity maps a level to a PagerDuty severity
+func pagerDutySeverity(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return "critical"
+	case level >= zapcore.ErrorLevel:
+		return "error"
+	case level >= zapcore.WarnLevel:
+		return "warning"
+	default:
+		return "info"
+	}
+}
+
+// opsgeniePriority maps a level to an Opsgenie priority
+func opsgeniePriority(level zapcore.Level) string {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return "P1"
+	case level >= zapcore.ErrorLevel:
+		return "P2"
+	default:
+		return "P3"
+	}
+}
+
+// stringDetails converts fields to the string map Opsgenie expects
+func stringDetails(fields map[string]interface{}) map[string]string {
+	details := make(map[string]string, len(fields))
+	for k, v := range fields {
+		details[k] = fmt.Sprint(v)
+	}
+	return details
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// errorReporter delivers internal logger errors such as schema
+// violations or failed writes. It is shared by a logger and its children.
+type errorReporter struct {
+	handler     func(error)
+	lastError   error
+	lastErrorAt time.Time
+	mu          sync.RWMutex
+}
+
+// report delivers err to the registered handler, or stderr by default
+func (r *errorReporter) report(err error) {
+	r.mu.Lock()
+	handler := r.handler
+	r.lastError, r.lastErrorAt = err, time.Now()
+	r.mu.Unlock()
+
+	if handler != nil {
+		handler(err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%v logger error: %v\n", time.Now().UTC(), err)
+}
+
+// last returns the most recent error reported and when it occurred
+func (r *errorReporter) last() (error, time.Time) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastError, r.lastErrorAt
+}
+
+// OnInternalError registers a handler for errors raised by the logger
+// itself. By default they are written to stderr.
+func (l *Logger) OnInternalError(handler func(error)) {
+	l.internalErrors.mu.Lock()
+	defer l.internalErrors.mu.Unlock()
+
+	l.internalErrors.handler = handler
+}
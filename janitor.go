@@ -0,0 +1,225 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JanitorConfig configures log directory housekeeping
+type JanitorConfig struct {
+	// Dir is the log directory
+	Dir string
+	// Pattern selects the files managed in Dir, defaults to "*.log*"
+	Pattern string
+	// CompressAfter gzips files last modified longer ago; zero never
+	// compresses
+	CompressAfter time.Duration
+	// MaxAge deletes files last modified longer ago; zero keeps them
+	MaxAge time.Duration
+	// MaxBytes deletes the oldest files until the managed files take at
+	// most this much space; zero doesn't limit it
+	MaxBytes int64
+	// Interval between runs, defaults to 1 hour
+	Interval time.Duration
+}
+
+// JanitorResult reports what a janitor run changed
+type JanitorResult struct {
+	Deleted    []string
+	Compressed []string
+	FreedBytes int64
+}
+
+// janitorFile is a managed file in the log directory
+type janitorFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// RunJanitor cleans the log directory according to cfg every interval
+// until ctx is done. It covers any matching file, including ones written
+// by earlier versions or other processes, but never the files of the
+// logger's own file handlers.
+func (l *Logger) RunJanitor(ctx context.Context, cfg JanitorConfig) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Hour
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		result, err := l.CleanLogDir(cfg)
+		if err != nil {
+			l.Warn("Log directory cleanup failed", map[string]interface{}{
+				"dir":   cfg.Dir,
+				"error": err.Error(),
+			})
+		}
+		if len(result.Deleted) > 0 || len(result.Compressed) > 0 {
+			l.Info("Log directory cleaned", map[string]interface{}{
+				"dir":         cfg.Dir,
+				"deleted":     len(result.Deleted),
+				"compressed":  len(result.Compressed),
+				"freed_bytes": result.FreedBytes,
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CleanLogDir runs the janitor once: it compresses files past
+// CompressAfter, then deletes files past MaxAge and the oldest files
+// beyond MaxBytes. Failures on single files don't stop the run and are
+// returned joined.
+func (l *Logger) CleanLogDir(cfg JanitorConfig) (JanitorResult, error) {
+	var result JanitorResult
+	if cfg.Dir == "" {
+		return result, fmt.Errorf("janitor directory must not be empty")
+	}
+	if cfg.Pattern == "" {
+		cfg.Pattern = "*.log*"
+	}
+
+	files, err := l.janitorFiles(cfg)
+	if err != nil {
+		return result, err
+	}
+
+	var errs []error
+	now := time.Now()
+
+	// Compress old files, keeping their modification time for later runs
+	if cfg.CompressAfter > 0 {
+		for i, f := range files {
+			if strings.HasSuffix(f.path, ".gz") || now.Sub(f.modTime) <= cfg.CompressAfter {
+				continue
+			}
+			compressed, err := gzipFile(f)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			result.Compressed = append(result.Compressed, f.path)
+			result.FreedBytes += f.size - compressed.size
+			files[i] = compressed
+		}
+	}
+
+	// Delete files past the age limit and the oldest beyond the size limit
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	for _, f := range files {
+		expired := cfg.MaxAge > 0 && now.Sub(f.modTime) > cfg.MaxAge
+		oversized := cfg.MaxBytes > 0 && total > cfg.MaxBytes
+		if !expired && !oversized {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		total -= f.size
+		result.Deleted = append(result.Deleted, f.path)
+		result.FreedBytes += f.size
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// janitorFiles lists the managed files in the log directory, oldest
+// first, excluding the logger's open handler files
+func (l *Logger) janitorFiles(cfg JanitorConfig) ([]janitorFile, error) {
+	matches, err := filepath.Glob(filepath.Join(cfg.Dir, cfg.Pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	open := map[string]struct{}{}
+	for _, h := range l.handlers.all() {
+		if h.kind == "file" {
+			if path, err := filepath.Abs(h.sink); err == nil {
+				open[path] = struct{}{}
+			}
+		}
+	}
+
+	files := make([]janitorFile, 0, len(matches))
+	for _, path := range matches {
+		if abs, err := filepath.Abs(path); err == nil {
+			if _, ok := open[abs]; ok {
+				continue
+			}
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, janitorFile{path: path, size: info.Size(), modTime: info.ModTime()})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	return files, nil
+}
+
+// gzipFile replaces a file with a gzip-compressed copy named path.gz
+func gzipFile(f janitorFile) (janitorFile, error) {
+	in, err := os.Open(f.path)
+	if err != nil {
+		return f, err
+	}
+	defer in.Close()
+
+	target := f.path + ".gz"
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return f, err
+	}
+
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(f.path)
+	zw.ModTime = f.modTime
+	_, err = io.Copy(zw, in)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chtimes(target, f.modTime, f.modTime)
+	}
+	if err != nil {
+		os.Remove(target)
+		return f, err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return f, err
+	}
+	in.Close()
+	if err := os.Remove(f.path); err != nil {
+		os.Remove(target)
+		return f, err
+	}
+	return janitorFile{path: target, size: info.Size(), modTime: f.modTime}, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// JournalSocket is the systemd journal's native protocol socket
+const JournalSocket = "/run/systemd/journal/socket"
+
+// AddJournaldHandler adds a handler sending entries to the systemd
+// journal using its native protocol, mapping fields to journal fields
+// and levels to syslog priorities
+func (l *Logger) AddJournaldHandler(level LogLevel, opts ...HandlerOption) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("journald", false); err != nil {
+		return err
+	}
+	spec := handlerSpec{kind: "journald", sink: JournalSocket, encoder: "journal", level: level}
+
+	// Dry-run handlers only need the encoded size
+	if handlerOpts.dryRun != nil {
+		return l.addDryRunCore(spec, handlerOpts)
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: JournalSocket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+
+	core := &journaldCore{
+		LevelEnabler: zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return lvl >= level
+		}),
+		conn:       conn,
+		timeout:    handlerOpts.writeTimeout,
+		identifier: filepath.Base(os.Args[0]),
+		messageKey: l.mapKey("msg"),
+	}
+
+	l.registerHandler(newHandlerState(spec, handlerOpts), core)
+	return nil
+}
+
+// journaldCore writes entries to the journal socket
+type journaldCore struct {
+	zapcore.LevelEnabler
+	conn       *net.UnixConn
+	timeout    time.Duration
+	identifier string
+	messageKey string
+	fields     []zapcore.Field
+}
+
+// With implements zapcore.Core
+func (j *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *j
+	clone.fields = append(append([]zapcore.Field{}, j.fields...), fields...)
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (j *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if j.Enabled(ent.Level) {
+		return ce.AddCore(ent, j)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (j *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, field := range j.fields {
+		field.AddTo(enc)
+	}
+	for _, field := range fields {
+		field.AddTo(enc)
+	}
+
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", ent.Message)
+	writeJournalField(&buf, "PRIORITY", fmt.Sprint(journalPriority(ent.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", j.identifier)
+	writeJournalField(&buf, "SYSLOG_TIMESTAMP", ent.Time.Format(time.RFC3339Nano))
+	if ent.LoggerName != "" {
+		writeJournalField(&buf, "LOGGER", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		writeJournalField(&buf, "CODE_FILE", ent.Caller.File)
+		writeJournalField(&buf, "CODE_LINE", fmt.Sprint(ent.Caller.Line))
+		writeJournalField(&buf, "CODE_FUNC", ent.Caller.Function)
+	}
+	if ent.Stack != "" {
+		writeJournalField(&buf, "STACKTRACE", ent.Stack)
+	}
+
+	for key, value := range enc.Fields {
+		name := journalFieldName(key)
+		if name == "" || name == "STACKTRACE" && ent.Stack != "" {
+			// The entry's own stack trace is already written
+			continue
+		}
+		writeJournalField(&buf, name, journalValue(value))
+	}
+
+	if j.timeout > 0 {
+		if err := j.conn.SetWriteDeadline(time.Now().Add(j.timeout)); err != nil {
+			return err
+		}
+	}
+	_, err := j.conn.Write(buf.Bytes())
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		// Entries too large for a datagram are passed as a file
+		return sendJournalFile(j.conn, buf.Bytes())
+	}
+	return err
+}
+
+// Sync implements zapcore.Core
+func (j *journaldCore) Sync() error {
+	return nil
+}
+
+// Close implements io.Closer, closing the socket when the handler is
+// removed
+func (j *journaldCore) Close() error {
+	return j.conn.Close()
+}
+
+// journalPriority maps a zap level to a syslog priority
+func journalPriority(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	default:
+		// DPanic, Panic and Fatal are critical
+		return 2
+	}
+}
+
+// journalReservedFields are journal fields set by the handler or
+// interpreted by journald, which entry fields must not override. The
+// logger and stacktrace fields keep their LOGGER and STACKTRACE names.
+var journalReservedFields = map[string]struct{}{
+	"MESSAGE":           {},
+	"MESSAGE_ID":        {},
+	"PRIORITY":          {},
+	"SYSLOG_IDENTIFIER": {},
+	"SYSLOG_FACILITY":   {},
+	"SYSLOG_PID":        {},
+	"SYSLOG_TIMESTAMP":  {},
+	"CODE_FILE":         {},
+	"CODE_LINE":         {},
+	"CODE_FUNC":         {},
+	"ERRNO":             {},
+	"DOCUMENTATION":     {},
+	"TID":               {},
+}
+
+// journalFieldPrefix is prepended to entry fields named like reserved
+// journal fields, e.g. a "priority" field becomes FIELD_PRIORITY
+const journalFieldPrefix = "FIELD_"
+
+// journalFieldName converts a field key to a valid journal field name:
+// uppercase letters, digits and underscores, not starting with an
+// underscore or digit. Reserved names are prefixed with FIELD_.
+func journalFieldName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(key) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	name := strings.TrimLeft(b.String(), "_0123456789")
+	if _, reserved := journalReservedFields[name]; reserved {
+		name = journalFieldPrefix + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journalValue renders a field value as a journal string
+func journalValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	}
+	if b, err := json.Marshal(value); err == nil {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// writeJournalField appends a field in the journal's native format,
+// using the binary length-prefixed form for values containing newlines
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
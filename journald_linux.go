@@ -0,0 +1,41 @@
+//go:build linux
+
+package main
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// sendJournalFile passes an entry too large for a datagram to the
+// journal as a file descriptor, written to an unlinked file on /dev/shm
+// as journald expects
+func sendJournalFile(conn *net.UnixConn, data []byte) error {
+	file, err := os.CreateTemp("/dev/shm", "journal-")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	// WriteMsgUnix refuses connected datagram sockets, so send directly
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var sendErr error
+	err = raw.Write(func(fd uintptr) bool {
+		sendErr = syscall.Sendmsg(int(fd), nil, syscall.UnixRights(int(file.Fd())), nil, 0)
+		return sendErr != syscall.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	return sendErr
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestJournaldPassesOversizedEntriesAsFiles(t *testing.T) {
+	journal, core := journalSocket(t)
+	message := strings.Repeat("x", 4<<20)
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: message}
+	if err := core.Write(ent, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	oob := make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, err := journal.ReadMsgUnix(nil, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("got control messages %v, %v, want a file descriptor", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "journal")
+	defer file.Close()
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "MESSAGE="+message+"\n") {
+		t.Error("file does not hold the entry")
+	}
+}
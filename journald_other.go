@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"fmt"
+	"net"
+)
+
+// sendJournalFile fails: the journal only runs on Linux
+func sendJournalFile(conn *net.UnixConn, data []byte) error {
+	return fmt.Errorf("journal entry of %d bytes exceeds the datagram size", len(data))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestJournaldReservedFields(t *testing.T) {
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "journal.sock"), Net: "unixgram"}
+	journal, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer journal.Close()
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	core := &journaldCore{LevelEnabler: zapcore.DebugLevel, conn: conn, identifier: "app"}
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "disk failed"}
+	if err := core.Write(ent, []zapcore.Field{
+		zap.String("message", "forged"),
+		zap.Int("priority", 7),
+		zap.String("syslog_identifier", "other"),
+		zap.String("device", "sda"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	n, err := journal.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(buf[:n]), "\n"), "\n")
+
+	want := map[string]string{
+		"MESSAGE":                 "disk failed",
+		"PRIORITY":                "3",
+		"SYSLOG_IDENTIFIER":       "app",
+		"FIELD_MESSAGE":           "forged",
+		"FIELD_PRIORITY":          "7",
+		"FIELD_SYSLOG_IDENTIFIER": "other",
+		"DEVICE":                  "sda",
+	}
+	seen := map[string]int{}
+	for _, line := range lines {
+		name, value, _ := strings.Cut(line, "=")
+		seen[name]++
+		if expected, ok := want[name]; ok && value != expected {
+			t.Errorf("%s = %q, want %q", name, value, expected)
+		}
+	}
+	for name := range want {
+		if seen[name] != 1 {
+			t.Errorf("%s written %d times, want once", name, seen[name])
+		}
+	}
+}
+
+// journalSocket returns a core writing to a fake journal socket
+func journalSocket(t *testing.T) (*net.UnixConn, *journaldCore) {
+	addr := &net.UnixAddr{Name: filepath.Join(t.TempDir(), "journal.sock"), Net: "unixgram"}
+	journal, err := net.ListenUnixgram("unixgram", addr)
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { journal.Close() })
+
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return journal, &journaldCore{LevelEnabler: zapcore.DebugLevel, conn: conn, identifier: "app"}
+}
+
+func TestJournaldWritesStackTraceOnce(t *testing.T) {
+	journal, core := journalSocket(t)
+	ent := zapcore.Entry{Level: zapcore.ErrorLevel, Time: time.Now(), Message: "failed", Stack: "main.run"}
+	if err := core.Write(ent, []zapcore.Field{zap.String("stacktrace", "main.run")}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4096)
+	n, err := journal.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(buf[:n]), "STACKTRACE="); got != 1 {
+		t.Errorf("STACKTRACE written %d times, want once", got)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// SetKeyMapping renames field keys before encoding, e.g. "user_id" to
+// "usr.id" or "msg" to "message". Built-in keys (msg, level, time, logger,
+// caller, stacktrace) are renamed for handlers added after the call.
+func (l *Logger) SetKeyMapping(mapping map[string]string) {
+	copied := make(map[string]string, len(mapping))
+	for from, to := range mapping {
+		copied[from] = to
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.keyMapping = copied
+	l.mappedKeys.Store(&copied)
+	rulesGeneration.Add(1)
+}
+
+// mappedKeysOf returns an atomic snapshot of a key mapping, read by
+// handlers and baked context without locking
+func mappedKeysOf(mapping map[string]string) *atomic.Pointer[map[string]string] {
+	snapshot := &atomic.Pointer[map[string]string]{}
+	snapshot.Store(&mapping)
+	return snapshot
+}
+
+// mapKey returns the output name for key
+func (l *Logger) mapKey(key string) string {
+	if mapped, ok := (*l.mappedKeys.Load())[key]; ok {
+		return mapped
+	}
+	return key
+}
+
+// mapFieldKeys renames field keys in place according to the key mapping
+func (l *Logger) mapFieldKeys(fields []zap.Field) {
+	mapping := *l.mappedKeys.Load()
+	if len(mapping) == 0 {
+		return
+	}
+	for i := range fields {
+		if mapped, ok := mapping[fields[i].Key]; ok {
+			fields[i].Key = mapped
+		}
+	}
+}
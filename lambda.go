@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Field keys attached to Lambda invocation loggers
+const (
+	LambdaRequestIDKey       = "aws_request_id"
+	LambdaFunctionNameKey    = "function_name"
+	LambdaFunctionVersionKey = "function_version"
+)
+
+// LambdaConfig configures WrapLambda
+type LambdaConfig struct {
+	// RequestID extracts the invocation's request ID from the handler
+	// context, e.g. using lambdacontext.FromContext(ctx).AwsRequestID
+	RequestID func(ctx context.Context) string
+}
+
+// IsLambda reports whether the process runs inside AWS Lambda
+func IsLambda() bool {
+	return os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != ""
+}
+
+// AddCloudWatchHandler adds a stdout handler formatted for CloudWatch
+// Logs: single-line JSON without colors and RFC3339 nanosecond timestamps
+func (l *Logger) AddCloudWatchHandler(level LogLevel, opts ...HandlerOption) {
+	opts = append([]HandlerOption{WithTimeFormat(TimeFormatRFC3339Nano, "UTC")}, opts...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("cloudwatch", true); err != nil {
+		l.internalErrors.report(err)
+		handlerOpts.backpressure = nil
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		l.internalErrors.report(err)
+	}
+
+	spec := handlerSpec{kind: "cloudwatch", encoder: "json", level: level}
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(os.Stdout), handlerOpts)
+}
+
+// WrapLambda wraps a Lambda handler so each invocation gets a logger
+// carrying its request ID in the context, and all handlers are flushed
+// before the invocation returns, since the runtime may freeze the
+// process afterwards
+func WrapLambda[In, Out any](l *Logger, cfg LambdaConfig, handler func(context.Context, In) (Out, error)) func(context.Context, In) (Out, error) {
+	return func(ctx context.Context, in In) (Out, error) {
+		fields := map[string]interface{}{
+			LambdaFunctionNameKey:    os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+			LambdaFunctionVersionKey: os.Getenv("AWS_LAMBDA_FUNCTION_VERSION"),
+		}
+
+		var requestID string
+		if cfg.RequestID != nil {
+			requestID = cfg.RequestID(ctx)
+			fields[LambdaRequestIDKey] = requestID
+		}
+
+		invocationLogger := l.WithRequestID(requestID).WithContext(fields)
+		// Sync errors from stdout pipes are expected and not actionable
+		defer func() { _ = l.Sync() }()
+
+		return handler(ContextWithLogger(ctx, invocationLogger), in)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestStartHeader carries the time a proxy received the request,
+// as set by Heroku, NGINX and others ("t=<microseconds>" or seconds)
+const RequestStartHeader = "X-Request-Start"
+
+// DefaultLatencyBuckets are the default histogram bucket upper bounds
+var DefaultLatencyBuckets = []time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond,
+	5 * time.Second, 10 * time.Second,
+}
+
+// LatencyHistogram counts request latencies in cumulative buckets.
+// HTTPMiddleware records into it with WithLatencyHistogram. This module
+// doesn't depend on gRPC, so gRPC servers call Observe from their own
+// interceptors:
+//
+//	start := time.Now()
+//	resp, err := handler(ctx, req)
+//	histogram.Observe(time.Since(start))
+type LatencyHistogram struct {
+	buckets []time.Duration
+	counts  []int64
+	count   int64
+	sum     time.Duration
+	mu      sync.Mutex
+}
+
+// LatencySnapshot is a point-in-time view of a LatencyHistogram.
+// Buckets maps upper bounds to the cumulative number of requests.
+type LatencySnapshot struct {
+	Buckets map[time.Duration]int64
+	Count   int64
+	Sum     time.Duration
+}
+
+// NewLatencyHistogram creates a histogram with the given bucket upper
+// bounds, or DefaultLatencyBuckets when none are given
+func NewLatencyHistogram(buckets ...time.Duration) *LatencyHistogram {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]time.Duration{}, buckets...)
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i] < buckets[j] })
+
+	return &LatencyHistogram{
+		buckets: buckets,
+		counts:  make([]int64, len(buckets)),
+	}
+}
+
+// Observe records a latency
+func (h *LatencyHistogram) Observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.count++
+	h.sum += d
+	for i, bound := range h.buckets {
+		if d <= bound {
+			h.counts[i]++
+		}
+	}
+}
+
+// Snapshot returns the current bucket counts
+func (h *LatencyHistogram) Snapshot() LatencySnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := LatencySnapshot{
+		Buckets: make(map[time.Duration]int64, len(h.buckets)),
+		Count:   h.count,
+		Sum:     h.sum,
+	}
+	for i, bound := range h.buckets {
+		snapshot.Buckets[bound] = h.counts[i]
+	}
+	return snapshot
+}
+
+// WithLatencyHistogram records every request's latency in h
+func WithLatencyHistogram(h *LatencyHistogram) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.histogram = h
+	}
+}
+
+// WithSlowRequestThreshold logs HTTP requests slower than threshold at
+// Warn with diagnostics such as queue time and handler name
+func WithSlowRequestThreshold(threshold time.Duration) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.slowThreshold = threshold
+	}
+}
+
+// WithHandlerName names the wrapped handler in slow-request diagnostics
+func WithHandlerName(name string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.handlerName = name
+	}
+}
+
+// WithServerTiming adds a Server-Timing response header with the
+// handler's duration
+func WithServerTiming() MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.serverTiming = true
+	}
+}
+
+// queueTime returns how long the request waited between the proxy and
+// this server, based on the X-Request-Start header
+func queueTime(r *http.Request, now time.Time) (time.Duration, bool) {
+	header := strings.TrimPrefix(r.Header.Get(RequestStartHeader), "t=")
+	if header == "" {
+		return 0, false
+	}
+
+	value, err := strconv.ParseFloat(header, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	// Accept seconds, milliseconds or microseconds since the epoch
+	var start time.Time
+	switch {
+	case value > 1e15:
+		start = time.UnixMicro(int64(value))
+	case value > 1e12:
+		start = time.UnixMilli(int64(value))
+	default:
+		start = time.Unix(0, int64(value*float64(time.Second)))
+	}
+
+	queued := now.Sub(start)
+	if queued < 0 {
+		return 0, false
+	}
+	return queued, true
+}
+
+// serverTimingValue formats a Server-Timing header entry
+func serverTimingValue(d time.Duration) string {
+	return fmt.Sprintf("app;dur=%.3f", float64(d.Microseconds())/1000)
+}
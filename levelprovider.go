@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelSnapshot is a set of levels reported by a LevelProvider
+type LevelSnapshot struct {
+	// Root is the global level; nil leaves it unchanged
+	Root *LogLevel
+	// Loggers maps logger names to levels and replaces all per-logger levels
+	Loggers map[string]LogLevel
+}
+
+// LevelProvider fetches log levels from a remote source such as
+// etcd, Consul, a feature-flag service or a config service
+type LevelProvider interface {
+	FetchLevels(ctx context.Context) (LevelSnapshot, error)
+}
+
+// LevelProviderFunc adapts a function to the LevelProvider interface
+type LevelProviderFunc func(ctx context.Context) (LevelSnapshot, error)
+
+// FetchLevels implements LevelProvider
+func (f LevelProviderFunc) FetchLevels(ctx context.Context) (LevelSnapshot, error) {
+	return f(ctx)
+}
+
+// LevelWatchConfig configures WatchLevels
+type LevelWatchConfig struct {
+	// Interval between polls, defaults to 30 seconds
+	Interval time.Duration
+	// MaxFailures is the number of consecutive provider errors after which
+	// levels are rolled back to those in effect when watching started.
+	// Defaults to 3.
+	MaxFailures int
+}
+
+// WatchLevels polls provider and applies the levels it returns until ctx
+// is done. Provider errors keep the last applied levels; after
+// MaxFailures consecutive errors the original levels are restored.
+func (l *Logger) WatchLevels(ctx context.Context, provider LevelProvider, cfg LevelWatchConfig) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = 3
+	}
+
+	// Capture the baseline to roll back to
+	baseRoot := l.Level()
+	baseLoggers := l.LoggerLevels()
+
+	failures := 0
+	rolledBack := false
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		snapshot, err := provider.FetchLevels(ctx)
+		if err == nil {
+			err = validateLevelSnapshot(snapshot)
+		}
+
+		if err != nil {
+			failures++
+			if failures == 1 {
+				l.Warn("Level provider failed, keeping current levels", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			if failures >= cfg.MaxFailures && !rolledBack {
+				l.SetLevel(baseRoot)
+				l.levels.replace(baseLoggers)
+				rolledBack = true
+				l.Warn("Level provider unavailable, rolled back to initial levels", map[string]interface{}{
+					"failures": failures,
+				})
+			}
+		} else {
+			failures = 0
+			rolledBack = false
+			if snapshot.Root != nil {
+				l.SetLevel(*snapshot.Root)
+			}
+			if snapshot.Loggers != nil {
+				l.levels.replace(snapshot.Loggers)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// validateLevelSnapshot rejects levels outside the supported range
+func validateLevelSnapshot(snapshot LevelSnapshot) error {
+	if snapshot.Root != nil && !validLevel(*snapshot.Root) {
+		return fmt.Errorf("invalid root level %d", *snapshot.Root)
+	}
+	for name, level := range snapshot.Loggers {
+		if !validLevel(level) {
+			return fmt.Errorf("invalid level %d for logger %q", level, name)
+		}
+	}
+	return nil
+}
+
+// validLevel reports whether level is a known zap level
+func validLevel(level LogLevel) bool {
+	return level >= zapcore.DebugLevel && level <= zapcore.FatalLevel
+}
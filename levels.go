@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// levelRules holds per-logger level overrides keyed by hierarchical
+// logger name (e.g. "app.auth"). Names are split into segments at sep,
+// or at '.' if unset. It is shared by a logger and its children.
+type levelRules struct {
+	levels map[string]LogLevel
+	sep    byte
+	mu     sync.RWMutex
+}
+
+// lookup returns the level of the closest configured ancestor of name
+func (r *levelRules) lookup(name string) (LogLevel, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.levels) == 0 {
+		return 0, false
+	}
+
+	for {
+		if level, ok := r.levels[name]; ok {
+			return level, true
+		}
+		sep := r.sep
+		if sep == 0 {
+			sep = '.'
+		}
+		i := strings.LastIndexByte(name, sep)
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// set sets the level for a logger name
+func (r *levelRules) set(name string, level LogLevel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.levels[name] = level
+}
+
+// clear removes the level for a logger name
+func (r *levelRules) clear(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.levels, name)
+}
+
+// snapshot returns a copy of all configured levels
+func (r *levelRules) snapshot() map[string]LogLevel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	levels := make(map[string]LogLevel, len(r.levels))
+	for name, level := range r.levels {
+		levels[name] = level
+	}
+	return levels
+}
+
+// replace swaps all configured levels at once
+func (r *levelRules) replace(levels map[string]LogLevel) {
+	copied := make(map[string]LogLevel, len(levels))
+	for name, level := range levels {
+		copied[name] = level
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.levels = copied
+}
+
+// levelListeners holds the callbacks notified of global level changes.
+// It is shared by a logger and its children.
+type levelListeners struct {
+	callbacks []func(old, new LogLevel)
+	mu        sync.Mutex
+}
+
+// change sets level to to, notifying callbacks if it changed. Changes
+// are serialized so each callback sees the true previous level.
+func (w *levelListeners) change(level zap.AtomicLevel, to LogLevel) {
+	w.mu.Lock()
+	old := level.Level()
+	level.SetLevel(to)
+	callbacks := w.callbacks
+	w.mu.Unlock()
+
+	if old == to {
+		return
+	}
+	for _, callback := range callbacks {
+		callback(old, to)
+	}
+}
+
+// OnLevelChange registers a callback run after the global level changes,
+// e.g. to adjust a tracer's verbosity. Callbacks run in registration
+// order on the goroutine changing the level.
+func (l *Logger) OnLevelChange(callback func(old, new LogLevel)) {
+	l.levelListeners.mu.Lock()
+	defer l.levelListeners.mu.Unlock()
+
+	l.levelListeners.callbacks = append(l.levelListeners.callbacks, callback)
+}
+
+// SetLoggerLevel sets the level for the named logger and its descendants,
+// taking precedence over the global level
+func (l *Logger) SetLoggerLevel(name string, level LogLevel) {
+	l.levels.set(name, level)
+}
+
+// ClearLoggerLevel removes a level set by SetLoggerLevel
+func (l *Logger) ClearLoggerLevel(name string) {
+	l.levels.clear(name)
+}
+
+// LoggerLevels returns the per-logger levels currently in effect
+func (l *Logger) LoggerLevels() map[string]LogLevel {
+	return l.levels.snapshot()
+}
+
+// Level returns the global minimum log level
+func (l *Logger) Level() LogLevel {
+	return l.atomicLevel.Level()
+}
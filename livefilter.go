@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FilterHelp describes the commands accepted by the live filter REPL
+const FilterHelp = `filters apply to console output and combine with AND:
+  level>=warn      level=error, level<=info, level>debug ...
+  logger=app.auth  entries from app.auth and its children (logger!= to exclude)
+  grep payment     message or field values contain text (grep -v to exclude)
+  show             list active filters
+  clear            remove all filters
+  help             show this help`
+
+// liveFilters holds the filters applied to console handlers during local
+// debugging. The list is copied on write. It is shared by a logger and
+// its children.
+type liveFilters struct {
+	conds atomic.Pointer[[]liveFilter]
+	mu    sync.Mutex
+}
+
+// liveFilter is a single parsed filter command
+type liveFilter struct {
+	expr  string
+	level bool
+	match func(ent zapcore.Entry, logger string, fields func() string) bool
+}
+
+// load returns the active filters
+func (f *liveFilters) load() []liveFilter {
+	if conds := f.conds.Load(); conds != nil {
+		return *conds
+	}
+	return nil
+}
+
+// add appends a filter. A level filter replaces the previous one.
+func (f *liveFilters) add(cond liveFilter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	current := f.load()
+	conds := make([]liveFilter, 0, len(current)+1)
+	for _, c := range current {
+		if !cond.level || !c.level {
+			conds = append(conds, c)
+		}
+	}
+	conds = append(conds, cond)
+	f.conds.Store(&conds)
+}
+
+// clear removes all filters
+func (f *liveFilters) clear() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.conds.Store(nil)
+}
+
+// AddConsoleFilter applies a filter such as "level>=warn",
+// "logger=app.auth" or "grep payment" to console output
+func (l *Logger) AddConsoleFilter(expr string) error {
+	cond, err := parseLiveFilter(expr)
+	if err != nil {
+		return err
+	}
+	l.liveFilters.add(cond)
+	return nil
+}
+
+// ClearConsoleFilters removes all console filters
+func (l *Logger) ClearConsoleFilters() {
+	l.liveFilters.clear()
+}
+
+// RunFilterREPL reads filter commands from in, one per line, until ctx
+// is done or in is exhausted, writing replies to out. See FilterHelp.
+func (l *Logger) RunFilterREPL(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	fmt.Fprint(out, "filter> ")
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case line := <-lines:
+			l.filterCommand(strings.TrimSpace(line), out)
+			fmt.Fprint(out, "filter> ")
+		}
+	}
+}
+
+// ServeFilterREPL accepts filter REPL sessions on a local socket, e.g.
+// ("unix", "/tmp/app.sock") or ("tcp", "127.0.0.1:7070"), until ctx is
+// done
+func (l *Logger) ServeFilterREPL(ctx context.Context, network, address string) error {
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			l.RunFilterREPL(ctx, conn, conn)
+		}()
+	}
+}
+
+// filterCommand runs a single REPL command
+func (l *Logger) filterCommand(line string, out io.Writer) {
+	switch line {
+	case "":
+	case "help":
+		fmt.Fprintln(out, FilterHelp)
+	case "show":
+		conds := l.liveFilters.load()
+		if len(conds) == 0 {
+			fmt.Fprintln(out, "no filters")
+		}
+		for _, c := range conds {
+			fmt.Fprintln(out, c.expr)
+		}
+	case "clear":
+		l.ClearConsoleFilters()
+	default:
+		if err := l.AddConsoleFilter(line); err != nil {
+			fmt.Fprintln(out, err)
+		}
+	}
+}
+
+// parseLiveFilter parses a filter command
+func parseLiveFilter(expr string) (liveFilter, error) {
+	expr = strings.TrimSpace(expr)
+
+	if rest, ok := strings.CutPrefix(expr, "grep "); ok {
+		text := strings.TrimSpace(rest)
+		invert := false
+		if t, ok := strings.CutPrefix(text, "-v "); ok {
+			text, invert = strings.TrimSpace(t), true
+		}
+		if text == "" {
+			return liveFilter{}, fmt.Errorf("grep needs text")
+		}
+		needle := strings.ToLower(text)
+		return liveFilter{expr: expr, match: func(ent zapcore.Entry, _ string, fields func() string) bool {
+			found := strings.Contains(strings.ToLower(ent.Message), needle) ||
+				strings.Contains(strings.ToLower(fields()), needle)
+			return found != invert
+		}}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(expr, "level"); ok {
+		for _, op := range []string{">=", "<=", "!=", "=", ">", "<"} {
+			value, ok := strings.CutPrefix(rest, op)
+			if !ok {
+				continue
+			}
+			var level zapcore.Level
+			if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+				return liveFilter{}, err
+			}
+			return liveFilter{expr: expr, level: true, match: func(ent zapcore.Entry, _ string, _ func() string) bool {
+				return compareLevel(ent.Level, op, level)
+			}}, nil
+		}
+	}
+
+	if rest, ok := strings.CutPrefix(expr, "logger"); ok {
+		invert := strings.HasPrefix(rest, "!=")
+		name, ok := strings.CutPrefix(strings.TrimPrefix(rest, "!"), "=")
+		if ok && strings.TrimSpace(name) != "" {
+			name = strings.TrimSpace(name)
+			return liveFilter{expr: expr, match: func(_ zapcore.Entry, logger string, _ func() string) bool {
+				within := logger == name || strings.HasPrefix(logger, name+".")
+				return within != invert
+			}}, nil
+		}
+	}
+
+	return liveFilter{}, fmt.Errorf("unknown filter %q, type help for commands", expr)
+}
+
+// compareLevel applies a comparison operator to two levels
+func compareLevel(lvl zapcore.Level, op string, target zapcore.Level) bool {
+	switch op {
+	case ">=":
+		return lvl >= target
+	case "<=":
+		return lvl <= target
+	case ">":
+		return lvl > target
+	case "<":
+		return lvl < target
+	case "!=":
+		return lvl != target
+	}
+	return lvl == target
+}
+
+// liveFilterCore drops console entries not matching the live filters
+type liveFilterCore struct {
+	zapcore.Core
+	filters *liveFilters
+	mapKey  func(key string) string
+	logger  string
+	context []zapcore.Field
+}
+
+// With implements zapcore.Core
+func (f *liveFilterCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *f
+	clone.Core = f.Core.With(fields)
+	clone.context = append(append([]zapcore.Field{}, f.context...), fields...)
+	if name, ok := mappedStringField(fields, "logger", f.mapKey); ok {
+		clone.logger = name
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (f *liveFilterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if f.Enabled(ent.Level) {
+		return ce.AddCore(ent, f)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (f *liveFilterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	conds := f.filters.load()
+	if len(conds) == 0 {
+		return f.Core.Write(ent, fields)
+	}
+
+	logger := f.logger
+	if name, ok := mappedStringField(fields, "logger", f.mapKey); ok {
+		logger = name
+	}
+
+	// Render field values only if a grep filter asks for them
+	var rendered *string
+	values := func() string {
+		if rendered == nil {
+			enc := zapcore.NewMapObjectEncoder()
+			for _, field := range f.context {
+				field.AddTo(enc)
+			}
+			for _, field := range fields {
+				field.AddTo(enc)
+			}
+			s := fmt.Sprint(enc.Fields)
+			rendered = &s
+		}
+		return *rendered
+	}
+
+	for _, c := range conds {
+		if !c.match(ent, logger, values) {
+			return nil
+		}
+	}
+	return f.Core.Write(ent, fields)
+}
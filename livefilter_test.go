@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestConcurrentConsoleFiltersAreKept(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := l.AddConsoleFilter(fmt.Sprintf("grep term%d", i)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(l.liveFilters.load()); got != 50 {
+		t.Errorf("filters = %d, want 50", got)
+	}
+}
+
+func TestLevelFilterReplacesPrevious(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	for _, expr := range []string{"level>=warn", "grep payment", "level=error"} {
+		if err := l.AddConsoleFilter(expr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var exprs []string
+	for _, c := range l.liveFilters.load() {
+		exprs = append(exprs, c.expr)
+	}
+	if !equalStrings(exprs, []string{"grep payment", "level=error"}) {
+		t.Errorf("filters = %q", exprs)
+	}
+}
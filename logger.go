@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -10,12 +13,61 @@ import (
 // Logger wraps zap.Logger with additional functionality
 type Logger struct {
 	*zap.Logger
-	name        string
-	context     []zap.Field
-	redactions  []redaction
-	atomicLevel zap.AtomicLevel
-	coreWrapper *multiCoreSyncWrapper
-	mu          sync.RWMutex
+	name           string
+	requestID      string
+	tenant         string
+	context        []zap.Field
+	debugContext   []zap.Field
+	redactions     []redaction
+	redactKeys     map[string]struct{}
+	rules          *atomic.Pointer[redactor]
+	mergedRules    *atomic.Pointer[mergedRules]
+	secrets        *secretSet
+	keyMapping     map[string]string
+	mappedKeys     *atomic.Pointer[map[string]string]
+	timeFormat     string
+	colorMode      ColorMode
+	async          *atomic.Pointer[AsyncConfig]
+	twelveFactor   bool
+	exitFlush      *exitFlush
+	order          *entryOrder
+	timeZone       string
+	atomicLevel    zap.AtomicLevel
+	levelOverride  *LogLevel
+	debugToken     bool
+	levels         *levelRules
+	packageLevels  *levelRules
+	levelListeners *levelListeners
+	throttle       *throttle
+	quotas         *quotaRules
+	tenants        *tenantRules
+	regions        *regionRules
+	classes        *fieldClasses
+	liveFilters    *liveFilters
+	quiet          *quietRules
+	internalErrors *errorReporter
+	schemas        *schemaRules
+	sequence       *sequencer
+	goroutineIDs   *atomic.Bool
+	runtimeTrace   *atomic.Bool
+	callerLevels   *atomic.Bool
+	callerFilters  *callerFilters
+	stacks         *stackTraces
+	crash          *crashReporter
+	utc            *atomic.Bool
+	clock          *clockSkew
+	sanitizer      *sanitizer
+	locale         *atomic.Pointer[string]
+	sampledDebug   *atomic.Pointer[func(context.Context) bool]
+	policy         *atomic.Pointer[appliedPolicy]
+	transforms     *fieldTransforms
+	noLock         bool
+	noRedact       bool
+	spans          *spanMirror
+	handlers       *handlerRegistry
+	stats          *loggerStats
+	coreWrapper    *multiCoreSyncWrapper
+	mu             sync.RWMutex
 }
 
 // NewLogger creates a new Logger with the specified name and initial log level
@@ -24,23 +76,100 @@ func NewLogger(name string, level LogLevel) *Logger {
 	atomicLevel := zap.NewAtomicLevelAt(level)
 
 	// Initialize the multi-core wrapper
-	coreWrapper := &multiCoreSyncWrapper{cores: []zapcore.Core{}}
+	coreWrapper := &multiCoreSyncWrapper{}
 
 	// Create the logger
 	zapLogger := zap.New(coreWrapper)
-
-	return &Logger{
-		Logger:      zapLogger,
-		name:        name,
-		context:     []zap.Field{zap.String("logger", name)},
-		redactions:  []redaction{},
-		atomicLevel: atomicLevel,
-		coreWrapper: coreWrapper,
+	policy := &atomic.Pointer[appliedPolicy]{}
+
+	logger := &Logger{
+		Logger:         zapLogger,
+		name:           name,
+		context:        []zap.Field{zap.String("logger", name)},
+		redactions:     []redaction{},
+		redactKeys:     map[string]struct{}{},
+		secrets:        &secretSet{},
+		rules:          &atomic.Pointer[redactor]{},
+		mergedRules:    &atomic.Pointer[mergedRules]{},
+		mappedKeys:     mappedKeysOf(nil),
+		async:          &atomic.Pointer[AsyncConfig]{},
+		atomicLevel:    atomicLevel,
+		levels:         &levelRules{levels: map[string]LogLevel{}},
+		packageLevels:  &levelRules{levels: map[string]LogLevel{}, sep: '/'},
+		levelListeners: &levelListeners{},
+		throttle:       &throttle{},
+		quotas:         &quotaRules{},
+		tenants:        &tenantRules{},
+		regions:        &regionRules{strict: true},
+		classes:        &fieldClasses{policy: policy},
+		liveFilters:    &liveFilters{},
+		quiet:          &quietRules{windows: map[int]*quietWindow{}},
+		internalErrors: &errorReporter{},
+		schemas:        &schemaRules{schemas: map[string]Schema{}},
+		sequence:       &sequencer{},
+		goroutineIDs:   &atomic.Bool{},
+		runtimeTrace:   &atomic.Bool{},
+		callerLevels:   &atomic.Bool{},
+		callerFilters:  &callerFilters{},
+		stacks:         &stackTraces{},
+		crash:          &crashReporter{},
+		utc:            &atomic.Bool{},
+		clock:          &clockSkew{},
+		sanitizer:      &sanitizer{},
+		locale:         &atomic.Pointer[string]{},
+		sampledDebug:   &atomic.Pointer[func(context.Context) bool]{},
+		policy:         policy,
+		exitFlush:      &exitFlush{},
+		transforms:     &fieldTransforms{},
+		order:          &entryOrder{},
+		spans:          &spanMirror{},
+		handlers:       &handlerRegistry{},
+		stats:          &loggerStats{},
+		coreWrapper:    coreWrapper,
 	}
+	logger.publishRedactor()
+	return logger
 }
 
+// NewLoggerWithConfig creates a logger from cfg, returning the problems
+// found by cfg.Validate
 func NewLoggerWithConfig(cfg Config) (*Logger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withProfile().withTwelveFactor()
+
 	logger := NewLogger(cfg.Name, cfg.Level)
+	if cfg.TwelveFactor {
+		logger.EnableTwelveFactor()
+	}
+	logger.applyProfile(cfg.Profile)
+	logger.AddBuildInfo(cfg.Env)
+	if err := logger.AddResource(cfg.Resource); err != nil {
+		return nil, err
+	}
+
+	for key, class := range cfg.FieldClasses {
+		logger.ClassifyFields(class, key)
+	}
+	if err := logger.pseudonymizeFromEnv(cfg.PseudonymKeyEnv, cfg.PseudonymizeFields); err != nil {
+		return nil, err
+	}
+	logger.AnonymizeIPs(cfg.AnonymizeIPFields...)
+
+	// Key mapping and time format must be in place before handlers
+	// build their encoders
+	if len(cfg.KeyMapping) > 0 {
+		logger.SetKeyMapping(cfg.KeyMapping)
+	}
+	if err := logger.SetTimeFormat(cfg.TimeFormat, cfg.TimeZone); err != nil {
+		return nil, err
+	}
+	logger.UseUTC(cfg.UTC)
+	logger.SetColorMode(cfg.ColorMode)
+	logger.EnableSanitization(cfg.Sanitize)
+	logger.EscapeNewlines(cfg.EscapeNewlines)
+	logger.SetLocale(cfg.Locale)
 
 	if cfg.ConsoleLevel != nil {
 		logger.AddConsoleHandler(*cfg.ConsoleLevel, cfg.Development)
@@ -52,126 +181,269 @@ func NewLoggerWithConfig(cfg Config) (*Logger, error) {
 		}
 	}
 
+	for _, h := range cfg.Handlers {
+		if err := logger.addConfiguredHandler(h, cfg.Development); err != nil {
+			return nil, err
+		}
+	}
+
 	for regex, replacement := range cfg.RedactRegex {
 		logger.AddRedaction(regex, replacement)
 	}
 
 	// Apply redact field keys
-	if len(cfg.RedactFields) > 0 {
-		fieldRedactor := createFieldRedactorCore(logger, cfg.RedactFields)
-		logger.coreWrapper.AddCore(fieldRedactor)
-	}
+	logger.AddRedactFields(cfg.RedactFields...)
 	return logger, nil
 }
 
 // Debug logs a message at Debug level with context fields
 func (l *Logger) Debug(msg string, fields ...map[string]interface{}) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	l.log(zapcore.DebugLevel, msg, fields...)
+}
 
-	// Redact the message
-	redactedMsg := l.redactMessage(msg)
+// Info logs a message at Info level with context fields
+func (l *Logger) Info(msg string, fields ...map[string]interface{}) {
+	l.log(zapcore.InfoLevel, msg, fields...)
+}
 
-	// Combine all context fields
-	allFields := append([]zap.Field{}, l.context...)
+// Warn logs a message at Warn level with context fields
+func (l *Logger) Warn(msg string, fields ...map[string]interface{}) {
+	l.log(zapcore.WarnLevel, msg, fields...)
+}
 
-	// Add any additional fields
-	if len(fields) > 0 && fields[0] != nil {
-		for k, v := range fields[0] {
-			allFields = append(allFields, zap.Any(k, v))
-		}
-	}
+// Error logs a message at Error level with context fields
+func (l *Logger) Error(msg string, fields ...map[string]interface{}) {
+	l.log(zapcore.ErrorLevel, msg, fields...)
+}
 
-	l.Logger.Debug(redactedMsg, allFields...)
+// Fatal logs a message at Fatal level with context fields
+func (l *Logger) Fatal(msg string, fields ...map[string]interface{}) {
+	l.log(zapcore.FatalLevel, msg, fields...)
 }
 
-// Info logs a message at Info level with context fields
-func (l *Logger) Info(msg string, fields ...map[string]interface{}) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+// log writes a message at the given level with context fields
+func (l *Logger) log(level LogLevel, msg string, fields ...map[string]interface{}) {
+	var entryFields map[string]interface{}
+	if len(fields) > 0 {
+		entryFields = fields[0]
+	}
+	l.write(context.Background(), level, msg, entryFields)
+}
 
-	// Redact the message
-	redactedMsg := l.redactMessage(msg)
+// write runs an entry through the logging pipeline in the scope of ctx
+func (l *Logger) write(ctx context.Context, level LogLevel, msg string, entryFields map[string]interface{}) {
+	if !l.noLock {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+	}
 
-	// Combine all context fields
-	allFields := append([]zap.Field{}, l.context...)
+	enabled, raised := l.enabledIn(ctx, level)
+	if !enabled {
+		return
+	}
+	if l.quiet.suppress(l.name, level) || l.callerFilters.drop(level) {
+		l.stats.dropped.Add(1)
+		return
+	}
 
-	// Add any additional fields
-	if len(fields) > 0 && fields[0] != nil {
-		for k, v := range fields[0] {
-			allFields = append(allFields, zap.Any(k, v))
-		}
+	// Apply adaptive throttling
+	allowed, notice := l.throttle.allow(level, time.Now())
+	if notice != nil {
+		l.Logger.Warn(notice.msg, append(append([]zap.Field{}, l.context...), notice.fields...)...)
+	}
+	if !allowed {
+		l.stats.dropped.Add(1)
+		return
 	}
 
-	l.Logger.Info(redactedMsg, allFields...)
-}
+	// Enforce per-logger quotas
+	allowed, notice = l.quotas.allow(l.name, level, msg, entryFields, time.Now())
+	if notice != nil {
+		l.Logger.Warn(notice.msg, append(append([]zap.Field{}, l.context...), notice.fields...)...)
+	}
+	if !allowed {
+		l.stats.dropped.Add(1)
+		return
+	}
 
-// Warn logs a message at Warn level with context fields
-func (l *Logger) Warn(msg string, fields ...map[string]interface{}) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	// Validate fields against the logger's schema
+	l.validateSchema(l.loggerSchema(), "logger "+l.name, entryFields)
 
 	// Redact the message
-	redactedMsg := l.redactMessage(msg)
+	r := l.entryRedactor()
+	redactedMsg := r.string(msg)
 
-	// Combine all context fields
+	// Combine all context fields, including those extracted from ctx
 	allFields := append([]zap.Field{}, l.context...)
+	allFields = appendContextFields(allFields, ctx)
+
+	// Expensive debug fields only appear at Debug verbosity
+	if len(l.debugContext) > 0 && l.effectiveLevel() <= zapcore.DebugLevel {
+		allFields = append(allFields, l.debugContext...)
+	}
 
 	// Add any additional fields
-	if len(fields) > 0 && fields[0] != nil {
-		for k, v := range fields[0] {
-			allFields = append(allFields, zap.Any(k, v))
+	if entryFields != nil {
+		for k, v := range entryFields {
+			allFields = r.appendValueFields(allFields, k, v)
+		}
+
+		// Fingerprint errors for downstream grouping by the message
+		// template, which varies less than the rendered message
+		if err := firstError(entryFields); err != nil {
+			template, ok := entryFields[MessageTemplateKey].(string)
+			if !ok {
+				template = msg
+			}
+			allFields = append(allFields, zap.String(FingerprintKey, errorFingerprint(template, err)))
 		}
 	}
 
-	l.Logger.Warn(redactedMsg, allFields...)
-}
+	allFields = append(allFields, goroutineIDField(l.goroutineIDs)...)
+	allFields = append(allFields, l.clock.fields()...)
 
-// Error logs a message at Error level with context fields
-func (l *Logger) Error(msg string, fields ...map[string]interface{}) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+	// Attach deduplicated stack traces, unless the entry carries its own
+	if _, ok := entryFields["stacktrace"]; !ok && l.stacks.wants(level) {
+		trace, ref := l.stacks.capture()
+		if trace != "" {
+			allFields = append(allFields, zap.String("stacktrace", trace))
+		}
+		allFields = append(allFields, zap.String(StackRefKey, ref))
+	}
 
-	// Redact the message
-	redactedMsg := l.redactMessage(msg)
+	// Redact context and entry fields. Fields baked into handlers by
+	// WithContext are redacted by their handlers.
+	allFields = r.fields(allFields)
 
-	// Combine all context fields
-	allFields := append([]zap.Field{}, l.context...)
+	// Link metrics to this entry before keys are renamed
+	var exemplarLabels string
+	if l.stats.exemplarsOn.Load() {
+		exemplarLabels = entryExemplar(ctx, allFields)
+	}
 
-	// Add any additional fields
-	if len(fields) > 0 && fields[0] != nil {
-		for k, v := range fields[0] {
-			allFields = append(allFields, zap.Any(k, v))
+	// Rename keys to the configured naming convention
+	l.mapFieldKeys(allFields)
+
+	// Surface failures in runtime/trace output
+	if level >= zapcore.ErrorLevel {
+		l.traceEvent(ctx, level, redactedMsg)
+	}
+
+	if l.noRedact {
+		allFields = append(allFields, skipRedactionField)
+	}
+
+	ce := l.Logger.Check(level, redactedMsg)
+	if ce == nil && raised {
+		ce = l.checkRaised(redactedMsg)
+	}
+	if ce != nil {
+		// Number entries so ordering survives colliding timestamps, once
+		// a handler takes them so rejected entries leave no gaps
+		allFields = append(allFields, l.sequence.fields(l.mapKey)...)
+		l.stats.recordEntry(level)
+		l.stats.recordExemplar(level, exemplarLabels)
+		async := l.async.Load()
+		ordered := async != nil && async.Ordered
+		switch {
+		case level >= zapcore.PanicLevel:
+			// Panic and Fatal entries end the goroutine or process on
+			// write, and are written synchronously outside the order
+			if ordered {
+				seq := l.order.next(l.name)
+				l.order.mu.Unlock()
+				allFields = append(allFields, zap.Uint64(l.mapKey(LoggerSequenceKey), seq))
+			}
+			l.reportCrash(level, redactedMsg)
+			ce.Write(allFields...)
+		case ordered:
+			l.writeOrdered(ce, allFields)
+		default:
+			ce.Write(allFields...)
 		}
 	}
+}
 
-	l.Logger.Error(redactedMsg, allFields...)
+// withBakedFields returns the zap logger with the fields returned by bake
+// baked into its handlers, redacted and key-mapped. bake may run again
+// on any entry, so it must not take the logger's lock.
+func (l *Logger) withBakedFields(bake func() []zap.Field) *zap.Logger {
+	return l.Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return withBakedFields(core, func() []zap.Field {
+			// Keys are mapped in place, so leave the layer's fields as
+			// they were given for later rebuilds
+			baked := append([]zap.Field{}, bake()...)
+			baked = l.publishedEntryRedactor().fields(baked)
+			l.mapFieldKeys(baked)
+			return baked
+		})
+	}))
 }
 
-// Fatal logs a message at Fatal level with context fields
-func (l *Logger) Fatal(msg string, fields ...map[string]interface{}) {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+// publishedEntryRedactor is entryRedactor without locking, using the
+// published redaction rules
+func (l *Logger) publishedEntryRedactor() redactor {
+	if l.noRedact {
+		return l.unredacted()
+	}
+	return l.withTenantRules(l.publishedRedactor())
+}
 
-	// Redact the message
-	redactedMsg := l.redactMessage(msg)
+// withTenantRules returns r merged with the rules of the logger's tenant
+func (l *Logger) withTenantRules(r redactor) redactor {
+	if l.tenant != "" {
+		if tr, ok := l.tenants.redactor(l.tenant); ok {
+			r = r.merge(tr)
+		}
+	}
+	return r
+}
 
-	// Combine all context fields
-	allFields := append([]zap.Field{}, l.context...)
+// entryRedactor returns the redactor for this logger's entries,
+// including the redaction policy and its tenant's rules
+func (l *Logger) entryRedactor() redactor {
+	return l.redactorFor(l.noRedact)
+}
 
-	// Add any additional fields
-	if len(fields) > 0 && fields[0] != nil {
-		for k, v := range fields[0] {
-			allFields = append(allFields, zap.Any(k, v))
-		}
+// redactorFor returns the entry redactor, or only the sanitizer and
+// transforms if noRedact is set
+func (l *Logger) redactorFor(noRedact bool) redactor {
+	if noRedact {
+		return l.unredacted()
 	}
+	return l.withTenantRules(l.publishedRedactor())
+}
 
-	l.Logger.Fatal(redactedMsg, allFields...)
+// unredacted returns the redactor of entries skipping redaction, which
+// still sanitizes and transforms values
+func (l *Logger) unredacted() redactor {
+	return redactor{sanitizer: l.sanitizer, transforms: l.transforms}
+}
+
+// enabled reports whether the logger's level allows the given level
+func (l *Logger) enabled(level LogLevel) bool {
+	return level >= l.effectiveLevel()
+}
+
+// effectiveLevel returns the minimum level of this logger.
+// A request-scoped level override takes precedence over the calling
+// package's level, then per-logger levels, then the global level.
+func (l *Logger) effectiveLevel() LogLevel {
+	if l.levelOverride != nil {
+		return *l.levelOverride
+	}
+	if pkgLevel, ok := l.callerLevel(); ok {
+		return pkgLevel
+	}
+	if loggerLevel, ok := l.levels.lookup(l.name); ok {
+		return loggerLevel
+	}
+	return l.atomicLevel.Level()
 }
 
 // SetLevel sets the global minimum log level
 func (l *Logger) SetLevel(level LogLevel) {
-	l.atomicLevel.SetLevel(level)
+	l.levelListeners.change(l.atomicLevel, level)
 }
 
 // Child creates a child logger with the given name
@@ -188,14 +460,8 @@ func (l *Logger) Child(name string) *Logger {
 	}
 
 	// Create a new logger with the same settings
-	child := &Logger{
-		Logger:      l.Logger,
-		name:        childName,
-		context:     append([]zap.Field{}, l.context...),
-		redactions:  append([]redaction{}, l.redactions...),
-		atomicLevel: l.atomicLevel,
-		coreWrapper: l.coreWrapper,
-	}
+	child := l.clone()
+	child.name = childName
 
 	// Replace the logger name field
 	for i, field := range child.context {
@@ -214,19 +480,126 @@ func (l *Logger) WithContext(fields map[string]interface{}) *Logger {
 	defer l.mu.RUnlock()
 
 	// Create a new logger with the same settings
-	contextLogger := &Logger{
-		Logger:      l.Logger,
-		name:        l.name,
-		context:     append([]zap.Field{}, l.context...),
-		redactions:  append([]redaction{}, l.redactions...),
-		atomicLevel: l.atomicLevel,
-		coreWrapper: l.coreWrapper,
-	}
+	contextLogger := l.clone()
 
-	// Add the new context fields
+	// Bake the redacted context fields into the handlers once, so they
+	// aren't encoded again for every entry. They are baked again with the
+	// current rules whenever redaction rules change.
+	values := make(map[string]interface{}, len(fields))
 	for key, value := range fields {
-		contextLogger.context = append(contextLogger.context, zap.Any(key, value))
+		values[key] = value
 	}
+	contextLogger.Logger = contextLogger.withBakedFields(func() []zap.Field {
+		r := contextLogger.publishedEntryRedactor()
+		baked := make([]zap.Field, 0, len(values))
+		for key, value := range values {
+			baked = r.appendValueFields(baked, key, value)
+		}
+		return baked
+	})
 
 	return contextLogger
 }
+
+// appendValueFields converts a field value to zap fields appended to dst
+func (r redactor) appendValueFields(dst []zap.Field, key string, value interface{}) []zap.Field {
+	// Encode marshalers directly, routing their fields through redaction
+	if field, ok := r.marshalerField(key, value); ok {
+		return append(dst, field)
+	}
+
+	// Expand errors into their message, type and cause chain
+	if err, ok := value.(error); ok && err != nil {
+		return append(dst, expandError(key, err)...)
+	}
+	return append(dst, zap.Any(key, value))
+}
+
+// clone returns a copy of the logger sharing its cores and level.
+// The caller must hold at least a read lock.
+func (l *Logger) clone() *Logger {
+	c := &Logger{
+		Logger:         l.Logger,
+		name:           l.name,
+		requestID:      l.requestID,
+		tenant:         l.tenant,
+		context:        append([]zap.Field{}, l.context...),
+		debugContext:   append([]zap.Field{}, l.debugContext...),
+		redactions:     append([]redaction{}, l.redactions...),
+		redactKeys:     l.redactKeys,
+		secrets:        l.secrets,
+		rules:          &atomic.Pointer[redactor]{},
+		mergedRules:    &atomic.Pointer[mergedRules]{},
+		keyMapping:     l.keyMapping,
+		mappedKeys:     mappedKeysOf(l.keyMapping),
+		timeFormat:     l.timeFormat,
+		colorMode:      l.colorMode,
+		async:          l.async,
+		twelveFactor:   l.twelveFactor,
+		exitFlush:      l.exitFlush,
+		order:          l.order,
+		timeZone:       l.timeZone,
+		atomicLevel:    l.atomicLevel,
+		levelOverride:  l.levelOverride,
+		debugToken:     l.debugToken,
+		levels:         l.levels,
+		packageLevels:  l.packageLevels,
+		levelListeners: l.levelListeners,
+		throttle:       l.throttle,
+		quotas:         l.quotas,
+		tenants:        l.tenants,
+		regions:        l.regions,
+		classes:        l.classes,
+		liveFilters:    l.liveFilters,
+		quiet:          l.quiet,
+		internalErrors: l.internalErrors,
+		schemas:        l.schemas,
+		sequence:       l.sequence,
+		goroutineIDs:   l.goroutineIDs,
+		runtimeTrace:   l.runtimeTrace,
+		callerLevels:   l.callerLevels,
+		callerFilters:  l.callerFilters,
+		stacks:         l.stacks,
+		crash:          l.crash,
+		utc:            l.utc,
+		clock:          l.clock,
+		sanitizer:      l.sanitizer,
+		locale:         l.locale,
+		sampledDebug:   l.sampledDebug,
+		policy:         l.policy,
+		transforms:     l.transforms,
+		noLock:         l.noLock,
+		noRedact:       l.noRedact,
+		spans:          l.spans,
+		handlers:       l.handlers,
+		stats:          l.stats,
+		coreWrapper:    l.coreWrapper,
+	}
+	c.publishRedactor()
+	return c
+}
+
+// WithLevel creates a new logger that uses the given level instead of the
+// global level. Handler levels still apply.
+func (l *Logger) WithLevel(level LogLevel) *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	levelLogger := l.clone()
+	levelLogger.levelOverride = &level
+	return levelLogger
+}
+
+// WithDebugContext creates a new logger with context fields that are only
+// attached while the effective level is Debug or lower, so large payloads
+// don't bloat production logs
+func (l *Logger) WithDebugContext(fields map[string]interface{}) *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	debugLogger := l.clone()
+	for key, value := range fields {
+		debugLogger.debugContext = append(debugLogger.debugContext, zap.Any(key, value))
+	}
+	return debugLogger
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observe registers a handler at level recording the entries it
+// receives, as the handler constructors register theirs
+func observe(l *Logger, level LogLevel, opts ...HandlerOption) *observer.ObservedLogs {
+	core, logs := observer.New(level)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	spec := handlerSpec{kind: "observer", level: level}
+	l.registerHandler(newHandlerState(spec, newHandlerOptions(opts)), core)
+	return logs
+}
+
+func TestWithContextFieldsFollowLaterRules(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+	ctxLogger := l.WithContext(map[string]interface{}{
+		"password": "hunter2",
+		"note":     "key sk-live-123456",
+		"card":     "4111111111111111",
+	})
+
+	ctxLogger.Info("before")
+	l.AddRedactFields("password")
+	l.AddSecrets("sk-live-123456")
+	if err := l.ApplyRedactionPolicy(RedactionPolicy{Rules: []PolicyRule{{Name: "card", Pattern: `\d{16}`, Replacement: "[CARD]"}}}); err != nil {
+		t.Fatal(err)
+	}
+	ctxLogger.Info("after")
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["password"]; got != "hunter2" {
+		t.Fatalf("password before rule = %v", got)
+	}
+	after := entries[1].ContextMap()
+	if after["password"] != RedactedValue {
+		t.Errorf("password = %v, want redacted", after["password"])
+	}
+	if after["note"] != "key "+RedactedValue {
+		t.Errorf("note = %v, want secret redacted", after["note"])
+	}
+	if after["card"] != "[CARD]" {
+		t.Errorf("card = %v, want policy redaction", after["card"])
+	}
+}
+
+func TestWithFieldsFollowLaterRules(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+	withLogger := l.With(zap.String("token", "abc"))
+
+	l.AddRedactFields("token")
+	withLogger.Info("after")
+
+	if got := logs.All()[0].ContextMap()["token"]; got != RedactedValue {
+		t.Errorf("token = %v, want redacted", got)
+	}
+}
+
+func TestWithFieldsFollowChangedKeyMapping(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+	withLogger := l.With(zap.Int("user_id", 7))
+
+	withLogger.SetKeyMapping(map[string]string{"user_id": "usr.id"})
+	withLogger.Info("first")
+	withLogger.SetKeyMapping(map[string]string{"user_id": "user.identifier"})
+	withLogger.Info("second")
+
+	entries := logs.All()
+	if _, ok := entries[0].ContextMap()["usr.id"]; !ok {
+		t.Errorf("first fields = %v, want usr.id", entries[0].ContextMap())
+	}
+	if _, ok := entries[1].ContextMap()["user.identifier"]; !ok {
+		t.Errorf("second fields = %v, want user.identifier", entries[1].ContextMap())
+	}
+}
+
+func TestWithContextFieldsFollowKeyMapping(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+	ctxLogger := l.WithContext(map[string]interface{}{"user_id": 7})
+
+	ctxLogger.SetKeyMapping(map[string]string{"user_id": "usr.id"})
+	ctxLogger.Info("mapped")
+
+	fields := logs.All()[0].ContextMap()
+	if _, ok := fields["usr.id"]; !ok {
+		t.Errorf("fields = %v, want usr.id", fields)
+	}
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactedValue replaces values of redacted field keys
+const RedactedValue = "***REDACTED***"
+
+// redactor applies a snapshot of a logger's regex and key redactions
+type redactor struct {
+	redactions []redaction
+	keys       map[string]struct{}
+	secrets    *secretSet
+	sanitizer  *sanitizer
+	transforms *fieldTransforms
+}
+
+// redactor returns a snapshot of the logger's redaction rules.
+// The caller must hold at least a read lock.
+func (l *Logger) redactor() redactor {
+	return redactor{
+		redactions: l.redactions,
+		keys:       l.redactKeys,
+		secrets:    l.secrets,
+		sanitizer:  l.sanitizer,
+		transforms: l.transforms,
+	}
+}
+
+// merge returns a redactor applying the rules of both redactors
+func (r redactor) merge(other redactor) redactor {
+	merged := redactor{
+		redactions: append(append([]redaction{}, r.redactions...), other.redactions...),
+		keys:       r.keys,
+		secrets:    r.secrets,
+		sanitizer:  r.sanitizer,
+		transforms: r.transforms,
+	}
+	if len(other.keys) > 0 {
+		merged.keys = make(map[string]struct{}, len(r.keys)+len(other.keys))
+		for k := range r.keys {
+			merged.keys[k] = struct{}{}
+		}
+		for k := range other.keys {
+			merged.keys[k] = struct{}{}
+		}
+	}
+	return merged
+}
+
+// string applies all regex and secret redactions to s, then sanitizes it
+func (r redactor) string(s string) string {
+	s = r.secrets.replace(s)
+	for _, rd := range r.redactions {
+		if rd.replace != nil {
+			s = rd.regex.ReplaceAllStringFunc(s, rd.replace)
+			continue
+		}
+		if rd.perMatch {
+			s = rd.expand(s)
+			continue
+		}
+		s = rd.regex.ReplaceAllString(s, rd.replacement)
+	}
+	return r.sanitizer.apply(s)
+}
+
+// redactsKey reports whether values under key must be fully redacted
+func (r redactor) redactsKey(key string) bool {
+	_, ok := r.keys[key]
+	return ok
+}
+
+// marshalerField returns a field for values implementing
+// zapcore.ObjectMarshaler or zapcore.ArrayMarshaler whose encoded
+// contents pass through redaction
+func (r redactor) marshalerField(key string, value interface{}) (zap.Field, bool) {
+	switch m := value.(type) {
+	case zapcore.ObjectMarshaler:
+		if r.redactsKey(key) {
+			return zap.String(key, RedactedValue), true
+		}
+		return zap.Object(key, redactingObjectMarshaler{m, r}), true
+	case zapcore.ArrayMarshaler:
+		if r.redactsKey(key) {
+			return zap.String(key, RedactedValue), true
+		}
+		return zap.Array(key, redactingArrayMarshaler{m, r}), true
+	}
+	return zap.Field{}, false
+}
+
+// empty reports whether the redactor has no rules
+func (r redactor) empty() bool {
+	return len(r.redactions) == 0 && len(r.keys) == 0 && r.secrets.empty() && !r.sanitizer.active() && !r.transforms.active()
+}
+
+// field applies key and regex redaction to a field, reporting whether
+// it changed
+func (r redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.NamespaceType || f.Type == zapcore.SkipType {
+		return f, false
+	}
+	if r.redactsKey(f.Key) {
+		return zap.String(f.Key, RedactedValue), true
+	}
+	if transformed, ok := r.transforms.transform(f); ok {
+		return transformed, true
+	}
+
+	switch f.Type {
+	case zapcore.StringType:
+		if redacted := r.string(f.String); redacted != f.String {
+			return zap.String(f.Key, redacted), true
+		}
+	case zapcore.ByteStringType:
+		return zap.String(f.Key, r.string(string(f.Interface.([]byte)))), true
+	case zapcore.StringerType:
+		return zap.String(f.Key, r.string(f.Interface.(fmt.Stringer).String())), true
+	case zapcore.ObjectMarshalerType:
+		if !redactsObject(f.Interface) {
+			return zap.Object(f.Key, redactingObjectMarshaler{f.Interface.(zapcore.ObjectMarshaler), r}), true
+		}
+	case zapcore.ArrayMarshalerType:
+		if _, ok := f.Interface.(redactingArrayMarshaler); !ok {
+			return zap.Array(f.Key, redactingArrayMarshaler{f.Interface.(zapcore.ArrayMarshaler), r}), true
+		}
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			if _, redacted := err.(redactedError); !redacted {
+				return zap.NamedError(f.Key, redactedError{err, r}), true
+			}
+		}
+	case zapcore.ReflectType:
+		return r.reflected(f.Key, f.Interface), true
+	}
+	return f, false
+}
+
+// reflected returns a field for a value encoded by reflection. Maps such
+// as request metadata are redacted key by key, other values in their
+// JSON form; values without one are withheld.
+func (r redactor) reflected(key string, value interface{}) zapcore.Field {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return zap.Object(key, redactingMap{v, r})
+	case map[string]string:
+		return zap.Object(key, redactingStringMap{v, r})
+	case []interface{}:
+		return zap.Array(key, redactingSlice{v, r})
+	case string:
+		return zap.String(key, r.string(v))
+	case nil, bool, json.Number:
+		return zap.Reflect(key, v)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return zap.String(key, RedactedValue)
+	}
+	var decoded interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&decoded); err != nil {
+		return zap.String(key, RedactedValue)
+	}
+	return r.reflected(key, decoded)
+}
+
+// redactedError redacts the message and verbose form of an error
+type redactedError struct {
+	err      error
+	redactor redactor
+}
+
+// Error implements error
+func (e redactedError) Error() string {
+	return e.redactor.string(e.err.Error())
+}
+
+// Format implements fmt.Formatter, redacting the %+v stack traces of
+// pkg/errors-style errors
+func (e redactedError) Format(s fmt.State, verb rune) {
+	if verb == 'v' && s.Flag('+') {
+		fmt.Fprint(s, e.redactor.string(fmt.Sprintf("%+v", e.err)))
+		return
+	}
+	fmt.Fprint(s, e.Error())
+}
+
+// redactsObject reports whether an object marshaler already redacts
+func redactsObject(m interface{}) bool {
+	switch m.(type) {
+	case redactingObjectMarshaler, redactingMap, redactingStringMap:
+		return true
+	}
+	return false
+}
+
+// redactingMap encodes a map, redacting its keys and string values
+type redactingMap struct {
+	m        map[string]interface{}
+	redactor redactor
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (m redactingMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range m.m {
+		field, _ := m.redactor.field(zap.Any(k, v))
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+// redactingStringMap encodes a string map, redacting its keys and values
+type redactingStringMap struct {
+	m        map[string]string
+	redactor redactor
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (m redactingStringMap) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for k, v := range m.m {
+		field, _ := m.redactor.field(zap.String(k, v))
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+// redactingSlice encodes a decoded JSON array, redacting its elements
+type redactingSlice struct {
+	s        []interface{}
+	redactor redactor
+}
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (s redactingSlice) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range s.s {
+		if err := appendField(enc, s.redactor.reflected("", v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendField appends the value of a field built by reflected
+func appendField(enc zapcore.ArrayEncoder, f zapcore.Field) error {
+	switch f.Type {
+	case zapcore.StringType:
+		enc.AppendString(f.String)
+		return nil
+	case zapcore.ObjectMarshalerType:
+		return enc.AppendObject(f.Interface.(zapcore.ObjectMarshaler))
+	case zapcore.ArrayMarshalerType:
+		return enc.AppendArray(f.Interface.(zapcore.ArrayMarshaler))
+	}
+	return enc.AppendReflected(f.Interface)
+}
+
+// fields applies key and regex redaction to fields, copying the slice
+// only if a field changed
+func (r redactor) fields(fields []zapcore.Field) []zapcore.Field {
+	if r.empty() {
+		return fields
+	}
+
+	var redacted []zapcore.Field
+	for i, f := range fields {
+		rf, changed := r.field(f)
+		if !changed {
+			continue
+		}
+		if redacted == nil {
+			redacted = append([]zapcore.Field{}, fields...)
+		}
+		redacted[i] = rf
+	}
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+// redactingObjectMarshaler redacts the fields written by an ObjectMarshaler
+type redactingObjectMarshaler struct {
+	zapcore.ObjectMarshaler
+	redactor redactor
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (m redactingObjectMarshaler) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return m.ObjectMarshaler.MarshalLogObject(&redactingObjectEncoder{enc, m.redactor})
+}
+
+// redactingArrayMarshaler redacts the elements written by an ArrayMarshaler
+type redactingArrayMarshaler struct {
+	zapcore.ArrayMarshaler
+	redactor redactor
+}
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (m redactingArrayMarshaler) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	return m.ArrayMarshaler.MarshalLogArray(&redactingArrayEncoder{enc, m.redactor})
+}
+
+// redactingObjectEncoder applies key and regex redaction to string values
+type redactingObjectEncoder struct {
+	zapcore.ObjectEncoder
+	redactor redactor
+}
+
+// AddString implements zapcore.ObjectEncoder
+func (e *redactingObjectEncoder) AddString(key, value string) {
+	if e.redactor.redactsKey(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return
+	}
+	if transform := e.redactor.transforms.lookup(key); transform != nil {
+		e.ObjectEncoder.AddString(key, transform(value))
+		return
+	}
+	e.ObjectEncoder.AddString(key, e.redactor.string(value))
+}
+
+// AddByteString implements zapcore.ObjectEncoder
+func (e *redactingObjectEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+// AddObject implements zapcore.ObjectEncoder
+func (e *redactingObjectEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	if e.redactor.redactsKey(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactingObjectMarshaler{marshaler, e.redactor})
+}
+
+// AddArray implements zapcore.ObjectEncoder
+func (e *redactingObjectEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	if e.redactor.redactsKey(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return nil
+	}
+	return e.ObjectEncoder.AddArray(key, redactingArrayMarshaler{marshaler, e.redactor})
+}
+
+// AddReflected implements zapcore.ObjectEncoder
+func (e *redactingObjectEncoder) AddReflected(key string, value interface{}) error {
+	if e.redactor.redactsKey(key) {
+		e.ObjectEncoder.AddString(key, RedactedValue)
+		return nil
+	}
+	e.redactor.reflected(key, value).AddTo(e.ObjectEncoder)
+	return nil
+}
+
+// redactingArrayEncoder applies regex redaction to string elements
+type redactingArrayEncoder struct {
+	zapcore.ArrayEncoder
+	redactor redactor
+}
+
+// AppendString implements zapcore.ArrayEncoder
+func (e *redactingArrayEncoder) AppendString(value string) {
+	e.ArrayEncoder.AppendString(e.redactor.string(value))
+}
+
+// AppendByteString implements zapcore.ArrayEncoder
+func (e *redactingArrayEncoder) AppendByteString(value []byte) {
+	e.AppendString(string(value))
+}
+
+// AppendReflected implements zapcore.ArrayEncoder
+func (e *redactingArrayEncoder) AppendReflected(value interface{}) error {
+	return appendField(e.ArrayEncoder, e.redactor.reflected("", value))
+}
+
+// AppendObject implements zapcore.ArrayEncoder
+func (e *redactingArrayEncoder) AppendObject(marshaler zapcore.ObjectMarshaler) error {
+	return e.ArrayEncoder.AppendObject(redactingObjectMarshaler{marshaler, e.redactor})
+}
+
+// AppendArray implements zapcore.ArrayEncoder
+func (e *redactingArrayEncoder) AppendArray(marshaler zapcore.ArrayMarshaler) error {
+	return e.ArrayEncoder.AppendArray(redactingArrayMarshaler{marshaler, e.redactor})
+}
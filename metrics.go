@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// OpenMetricsContentType is the content type served by MetricsHandler
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// exemplar links a counter to a representative entry
+type exemplar struct {
+	labels string
+	at     time.Time
+}
+
+// entryExemplar returns the exemplar labels of an entry from the trace
+// context of ctx or its trace_id or entry_id fields
+func entryExemplar(ctx context.Context, fields []zap.Field) string {
+	if ctx != nil {
+		if tc, ok := TraceContextFromContext(ctx); ok {
+			return fmt.Sprintf("trace_id=%q,span_id=%q", tc.TraceID, tc.SpanID)
+		}
+	}
+	var entryID string
+	for _, f := range fields {
+		if f.Type != zapcore.StringType {
+			continue
+		}
+		switch f.Key {
+		case "trace_id":
+			return fmt.Sprintf("trace_id=%q", f.String)
+		case EntryIDKey:
+			entryID = f.String
+		}
+	}
+	if entryID != "" {
+		return fmt.Sprintf("%s=%q", EntryIDKey, entryID)
+	}
+	return ""
+}
+
+// recordExemplar keeps the latest exemplar of an entry at level
+func (s *loggerStats) recordExemplar(level LogLevel, labels string) {
+	if labels == "" || level < zapcore.DebugLevel || level > zapcore.FatalLevel {
+		return
+	}
+	s.exemplars[level-zapcore.DebugLevel].Store(&exemplar{labels: labels, at: time.Now()})
+}
+
+// MetricsHandler returns an HTTP handler serving the logger's entry
+// counters in the OpenMetrics text format for Prometheus to scrape:
+// log_entries_total by level and log_errors_total for Error and above.
+// Once it is created, counters carry the trace ID, or else the entry ID,
+// of the latest entry as an exemplar, so dashboards can jump from a
+// spike to representative log lines. Prometheus stores exemplars when
+// started with --enable-feature=exemplar-storage.
+func (l *Logger) MetricsHandler() http.Handler {
+	l.stats.exemplarsOn.Store(true)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", OpenMetricsContentType)
+		if _, err := w.Write([]byte(l.openMetrics())); err != nil {
+			l.internalErrors.report(err)
+		}
+	})
+}
+
+// openMetrics renders the entry counters in the OpenMetrics text format
+func (l *Logger) openMetrics() string {
+	var b strings.Builder
+	b.WriteString("# TYPE log_entries counter\n")
+	b.WriteString("# HELP log_entries Entries written by level.\n")
+
+	var errors int64
+	var errorExemplar *exemplar
+	for i := range l.stats.entries {
+		level := zapcore.DebugLevel + LogLevel(i)
+		count := l.stats.entries[i].Load()
+		ex := l.stats.exemplars[i].Load()
+		fmt.Fprintf(&b, "log_entries_total{level=%q} %d%s\n", level.String(), count, formatExemplar(ex))
+
+		if level >= zapcore.ErrorLevel {
+			errors += count
+			if ex != nil && (errorExemplar == nil || ex.at.After(errorExemplar.at)) {
+				errorExemplar = ex
+			}
+		}
+	}
+
+	b.WriteString("# TYPE log_errors counter\n")
+	b.WriteString("# HELP log_errors Entries written at Error level and above.\n")
+	fmt.Fprintf(&b, "log_errors_total %d%s\n", errors, formatExemplar(errorExemplar))
+
+	b.WriteString("# TYPE log_dropped counter\n")
+	b.WriteString("# HELP log_dropped Entries dropped before reaching handlers.\n")
+	fmt.Fprintf(&b, "log_dropped_total %d\n", l.stats.dropped.Load())
+	b.WriteString("# EOF\n")
+	return b.String()
+}
+
+// formatExemplar renders an exemplar suffix, if any
+func formatExemplar(ex *exemplar) string {
+	if ex == nil {
+		return ""
+	}
+	at := float64(ex.at.UnixMilli()) / 1000
+	return fmt.Sprintf(" # {%s} 1 %.3f", ex.labels, at)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"runtime/pprof"
+	"time"
+)
+
+// RequestIDHeader is the HTTP header used to propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds incoming request IDs taken from headers
+const maxRequestIDLength = 128
+
+// middlewareConfig holds the options of HTTPMiddleware
+type middlewareConfig struct {
+	debugSecret   []byte
+	accessLog     *accessLogger
+	histogram     *LatencyHistogram
+	slowThreshold time.Duration
+	handlerName   string
+	serverTiming  bool
+	pprofLabels   bool
+	deadlineRatio float64
+}
+
+// recordsResponses reports whether requests need a response recorder
+func (c *middlewareConfig) recordsResponses() bool {
+	return c.accessLog != nil || c.histogram != nil || c.slowThreshold > 0 || c.serverTiming
+}
+
+// MiddlewareOption configures HTTPMiddleware
+type MiddlewareOption func(*middlewareConfig)
+
+// WithDebugTokenSecret enables per-request Debug logging for requests
+// carrying a valid X-Debug-Token signed with secret. Their Debug entries
+// are written by handlers accepting Info as well as Debug handlers.
+func WithDebugTokenSecret(secret []byte) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.debugSecret = secret
+	}
+}
+
+// HTTPMiddleware returns middleware that attaches a request-scoped logger
+// to each request's context. Incoming X-Request-ID headers are honored,
+// otherwise a new ID is generated and echoed in the response. W3C
+// traceparent, tracestate and baggage headers are added as context fields,
+// and the parsed traceparent is stored in the request context.
+func HTTPMiddleware(l *Logger, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	cfg := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if len(requestID) > maxRequestIDLength {
+				requestID = ""
+			}
+			requestLogger := l.WithRequestID(requestID)
+
+			// Attach cross-service correlation fields when present
+			if traceFields := TraceFields(r.Header.Get); len(traceFields) > 0 {
+				requestLogger = requestLogger.WithContext(traceFields)
+			}
+
+			// Upgrade this request to Debug when it carries a valid token
+			if token := r.Header.Get(DebugTokenHeader); token != "" {
+				if VerifyDebugToken(cfg.debugSecret, token, time.Now()) {
+					requestLogger = requestLogger.withDebugToken()
+				}
+			}
+
+			w.Header().Set(RequestIDHeader, requestLogger.RequestID())
+
+			ctx := ContextWithLogger(r.Context(), requestLogger)
+			if tc, ok := ParseTraceContext(r.Header.Get); ok {
+				ctx = ContextWithTraceContext(ctx, tc)
+			}
+			if cfg.pprofLabels {
+				ctx = pprof.WithLabels(ctx, requestLogger.pprofLabels())
+				pprof.SetGoroutineLabels(ctx)
+				defer pprof.SetGoroutineLabels(r.Context())
+			}
+
+			if cfg.deadlineRatio > 0 {
+				defer requestLogger.CheckDeadlineRatio(ctx, r.Method+" "+r.URL.Path, cfg.deadlineRatio)()
+			}
+
+			if !cfg.recordsResponses() {
+				next.ServeHTTP(w, r.WithContext(ctx))
+				return
+			}
+
+			start := time.Now()
+			rec := &responseRecorder{ResponseWriter: w}
+			if cfg.serverTiming {
+				rec.beforeHeader = func() {
+					w.Header().Add("Server-Timing", serverTimingValue(time.Since(start)))
+				}
+			}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			duration := time.Since(start)
+
+			if cfg.histogram != nil {
+				cfg.histogram.Observe(duration)
+			}
+
+			if cfg.slowThreshold > 0 && duration > cfg.slowThreshold {
+				fields := map[string]interface{}{
+					"method":      r.Method,
+					"path":        r.URL.Path,
+					"status":      rec.statusCode(),
+					"duration":    duration,
+					"threshold":   cfg.slowThreshold,
+					"bytes":       rec.bytes,
+					"handler":     cfg.handlerName,
+					"remote_addr": r.RemoteAddr,
+				}
+				if queued, ok := queueTime(r, start); ok {
+					fields["queue_time"] = queued
+				}
+				requestLogger.Warn("Slow request", fields)
+			}
+
+			if cfg.accessLog != nil {
+				if err := cfg.accessLog.log(r, rec, start, duration, requestLogger.RequestID(), requestLogger.publishedRedactor()); err != nil {
+					l.internalErrors.report(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// MQTTClient publishes a message to an MQTT topic, e.g. an adapter
+// around a paho client's Publish token
+type MQTTClient interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+	IsConnected() bool
+}
+
+// MQTTConfig configures an MQTT handler
+type MQTTConfig struct {
+	Client MQTTClient
+	Topic  string
+	// QoS is the MQTT quality of service level (0, 1 or 2)
+	QoS byte
+	// BufferSize is the number of entries kept while offline; the oldest
+	// are dropped when full. Defaults to 1000.
+	BufferSize int
+}
+
+// AddMQTTHandler adds a handler publishing entries to an MQTT topic,
+// buffering them while the uplink is offline
+func (l *Logger) AddMQTTHandler(cfg MQTTConfig, level LogLevel, opts ...HandlerOption) error {
+	if cfg.Client == nil {
+		return fmt.Errorf("mqtt client must not be nil")
+	}
+	if cfg.Topic == "" {
+		return fmt.Errorf("mqtt topic must not be empty")
+	}
+	if cfg.QoS > 2 {
+		return fmt.Errorf("invalid mqtt QoS %d", cfg.QoS)
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1000
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("mqtt", true); err != nil {
+		return err
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		return err
+	}
+
+	sink := &mqttWriter{cfg: cfg}
+	spec := handlerSpec{kind: "mqtt", sink: cfg.Topic, encoder: "json", level: level}
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), sink, handlerOpts)
+	return nil
+}
+
+// mqttWriter publishes encoded entries, buffering them while offline
+type mqttWriter struct {
+	cfg     MQTTConfig
+	buffer  [][]byte
+	dropped int64
+	mu      sync.Mutex
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *mqttWriter) Write(p []byte) (int, error) {
+	// The encoder reuses its buffer, so keep a copy
+	payload := append([]byte{}, p...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buffer = append(w.buffer, payload)
+	if len(w.buffer) > w.cfg.BufferSize {
+		w.buffer = w.buffer[len(w.buffer)-w.cfg.BufferSize:]
+		w.dropped++
+	}
+
+	if err := w.drainLocked(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync publishes buffered entries if the client is connected
+func (w *mqttWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.drainLocked()
+}
+
+// queueDepth returns the number of buffered entries
+func (w *mqttWriter) queueDepth() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.buffer)
+}
+
+// droppedCount returns the number of entries dropped while offline
+func (w *mqttWriter) droppedCount() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.dropped
+}
+
+// drainLocked publishes buffered entries in order while connected.
+// Being offline is not an error; entries stay buffered, and those
+// dropped from a full buffer are counted in the handler's statistics.
+// The caller must hold the lock.
+func (w *mqttWriter) drainLocked() error {
+	for len(w.buffer) > 0 && w.cfg.Client.IsConnected() {
+		if err := w.cfg.Client.Publish(w.cfg.Topic, w.cfg.QoS, false, w.buffer[0]); err != nil {
+			return err
+		}
+		w.buffer[0] = nil
+		w.buffer = w.buffer[1:]
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeMQTTClient records published payloads
+type fakeMQTTClient struct {
+	connected bool
+	fail      error
+	published []string
+}
+
+func (c *fakeMQTTClient) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	if c.fail != nil {
+		return c.fail
+	}
+	c.published = append(c.published, string(payload))
+	return nil
+}
+
+func (c *fakeMQTTClient) IsConnected() bool {
+	return c.connected
+}
+
+func TestMQTTWriterReportsOnlyPublishFailures(t *testing.T) {
+	client := &fakeMQTTClient{}
+	w := &mqttWriter{cfg: MQTTConfig{Client: client, Topic: "logs", BufferSize: 1}}
+
+	// Offline writes are buffered, dropping the oldest
+	for _, entry := range []string{"a", "b"} {
+		if _, err := w.Write([]byte(entry)); err != nil {
+			t.Fatalf("offline Write returned %v", err)
+		}
+	}
+
+	client.connected = true
+	if _, err := w.Write([]byte("c")); err != nil {
+		t.Fatalf("successful publish returned %v", err)
+	}
+	if len(client.published) != 1 || client.published[0] != "c" {
+		t.Errorf("published %q, want only the newest entry", client.published)
+	}
+	if n := w.droppedCount(); n != 2 {
+		t.Errorf("dropped %d entries, want 2", n)
+	}
+
+	client.fail = errors.New("broker refused")
+	if _, err := w.Write([]byte("d")); err == nil {
+		t.Error("failed publish returned no error")
+	}
+}
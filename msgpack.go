@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// appendMsgpack encodes v in MessagePack format. It supports the value
+// types produced by zapcore.MapObjectEncoder; other values are encoded
+// as their string representation.
+func appendMsgpack(buf *bytes.Buffer, v interface{}) {
+	switch val := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if val {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case string:
+		appendMsgpackString(buf, val)
+	case []byte:
+		appendMsgpackString(buf, string(val))
+	case float32:
+		buf.WriteByte(0xca)
+		binary.Write(buf, binary.BigEndian, math.Float32bits(val))
+	case float64:
+		buf.WriteByte(0xcb)
+		binary.Write(buf, binary.BigEndian, math.Float64bits(val))
+	case time.Time:
+		appendMsgpackString(buf, val.Format(time.RFC3339Nano))
+	case time.Duration:
+		appendMsgpackString(buf, val.String())
+	case []interface{}:
+		appendMsgpackArrayHeader(buf, len(val))
+		for _, item := range val {
+			appendMsgpack(buf, item)
+		}
+	case map[string]interface{}:
+		appendMsgpackMapHeader(buf, len(val))
+		for key, item := range val {
+			appendMsgpackString(buf, key)
+			appendMsgpack(buf, item)
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			buf.WriteByte(0xd3)
+			binary.Write(buf, binary.BigEndian, rv.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			buf.WriteByte(0xcf)
+			binary.Write(buf, binary.BigEndian, rv.Uint())
+		default:
+			appendMsgpackString(buf, fmt.Sprint(v))
+		}
+	}
+}
+
+// appendMsgpackString encodes a string
+func appendMsgpackString(buf *bytes.Buffer, s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf.WriteByte(0xa0 | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(0xd9)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xda)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdb)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+	buf.WriteString(s)
+}
+
+// appendFluentdEventTime encodes t as a fluentd EventTime: ext type 0
+// holding big-endian seconds and nanoseconds, so entries keep sub-second
+// precision and order
+func appendFluentdEventTime(buf *bytes.Buffer, t time.Time) {
+	buf.WriteByte(0xd7)
+	buf.WriteByte(0x00)
+	binary.Write(buf, binary.BigEndian, uint32(t.Unix()))
+	binary.Write(buf, binary.BigEndian, uint32(t.Nanosecond()))
+}
+
+// appendMsgpackArrayHeader encodes the header of an n-element array
+func appendMsgpackArrayHeader(buf *bytes.Buffer, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(0x90 | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xdc)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdd)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// appendMsgpackMapHeader encodes the header of an n-entry map
+func appendMsgpackMapHeader(buf *bytes.Buffer, n int) {
+	switch {
+	case n < 16:
+		buf.WriteByte(0x80 | byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(0xde)
+		binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(0xdf)
+		binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+// readMsgpackStringMap decodes a map with string keys and values, such as
+// a fluentd ack response
+func readMsgpackStringMap(r *bufio.Reader) (map[string]string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	var n int
+	switch {
+	case b&0xf0 == 0x80:
+		n = int(b & 0x0f)
+	case b == 0xde:
+		var n16 uint16
+		if err := binary.Read(r, binary.BigEndian, &n16); err != nil {
+			return nil, err
+		}
+		n = int(n16)
+	case b == 0xdf:
+		var n32 uint32
+		if err := binary.Read(r, binary.BigEndian, &n32); err != nil {
+			return nil, err
+		}
+		n = int(n32)
+	default:
+		return nil, fmt.Errorf("msgpack: expected map, got 0x%x", b)
+	}
+
+	m := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		key, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readMsgpackString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// readMsgpackString decodes a string
+func readMsgpackString(r *bufio.Reader) (string, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+
+	var n int
+	switch {
+	case b&0xe0 == 0xa0:
+		n = int(b & 0x1f)
+	case b == 0xd9:
+		n8, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		n = int(n8)
+	case b == 0xda:
+		var n16 uint16
+		if err := binary.Read(r, binary.BigEndian, &n16); err != nil {
+			return "", err
+		}
+		n = int(n16)
+	case b == 0xdb:
+		var n32 uint32
+		if err := binary.Read(r, binary.BigEndian, &n32); err != nil {
+			return "", err
+		}
+		n = int(n32)
+	default:
+		return "", fmt.Errorf("msgpack: expected string, got 0x%x", b)
+	}
+
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultMultilineKeys are the fields rendered as blocks by WithMultiline
+// when no keys are given
+var DefaultMultilineKeys = []string{"stacktrace", "sql"}
+
+// WithMultiline makes a development console handler render the string
+// fields with the given keys as indented blocks below the entry, keeping
+// line breaks, e.g.
+//
+//	2024-01-02T03:04:05Z  INFO  query ran  {"rows": 3}
+//	  ┌ sql
+//	  │ SELECT id
+//	  │ FROM users
+//	  └
+//
+// JSON and other machine-readable encodings stay single-line. Fields
+// attached with With are always rendered inline.
+func WithMultiline(keys ...string) HandlerOption {
+	if len(keys) == 0 {
+		keys = DefaultMultilineKeys
+	}
+	set := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		set[key] = struct{}{}
+	}
+	return func(o *handlerOptions) {
+		o.multiline = set
+	}
+}
+
+// multilineEncoder moves selected string fields out of the entry line
+// into indented blocks
+type multilineEncoder struct {
+	zapcore.Encoder
+	keys map[string]struct{}
+}
+
+// Clone implements zapcore.Encoder
+func (e multilineEncoder) Clone() zapcore.Encoder {
+	return multilineEncoder{Encoder: e.Encoder.Clone(), keys: e.keys}
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (e multilineEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	var blocks [][2]string
+	inline := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if value, ok := e.blockValue(f); ok {
+			blocks = append(blocks, [2]string{f.Key, value})
+			continue
+		}
+		inline = append(inline, f)
+	}
+
+	// zap's own stack traces render as a block too
+	if _, ok := e.keys["stacktrace"]; ok && ent.Stack != "" {
+		blocks = append(blocks, [2]string{"stacktrace", ent.Stack})
+		ent.Stack = ""
+	}
+
+	buf, err := e.Encoder.EncodeEntry(ent, inline)
+	if err != nil || len(blocks) == 0 {
+		return buf, err
+	}
+	for _, block := range blocks {
+		writeMultilineBlock(buf, block[0], block[1])
+	}
+	return buf, nil
+}
+
+// blockValue returns the string value of a field rendered as a block
+func (e multilineEncoder) blockValue(f zapcore.Field) (string, bool) {
+	if _, ok := e.keys[f.Key]; !ok {
+		return "", false
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String, true
+	case zapcore.ByteStringType:
+		return string(f.Interface.([]byte)), true
+	case zapcore.StringerType:
+		return f.Interface.(fmt.Stringer).String(), true
+	}
+	return "", false
+}
+
+// writeMultilineBlock appends a delimited, indented block to buf
+func writeMultilineBlock(buf *buffer.Buffer, key, value string) {
+	buf.AppendString("  ┌ ")
+	buf.AppendString(key)
+	buf.AppendByte('\n')
+	for _, line := range strings.Split(strings.TrimRight(value, "\n"), "\n") {
+		buf.AppendString("  │ ")
+		buf.AppendString(strings.TrimRight(line, "\r"))
+		buf.AppendByte('\n')
+	}
+	buf.AppendString("  └\n")
+}
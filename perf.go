@@ -0,0 +1,55 @@
+package main
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// skipRedactionKey names the skip field marking entries logged without
+// redaction
+const skipRedactionKey = "_skip_redaction"
+
+// skipRedactionField is appended to entries logged without redaction, so
+// handlers don't redact them either. Encoders ignore skip fields.
+var skipRedactionField = zap.Field{Key: skipRedactionKey, Type: zapcore.SkipType}
+
+// SkipRedaction logs an entry written through Zap or Sugar without
+// regex, key and secret redaction, for hot paths logging values known to
+// be safe. Use WithoutRedaction for the map API.
+func SkipRedaction() zap.Field {
+	return skipRedactionField
+}
+
+// Unlocked returns a logger that doesn't take its read lock for every
+// entry, removing lock contention in hot paths. The returned logger and
+// its children must not be reconfigured, e.g. with AddRedaction or
+// SetKeyMapping; adding handlers and changing levels remain safe.
+func (l *Logger) Unlocked() *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	unlocked := l.clone()
+	unlocked.noLock = true
+	return unlocked
+}
+
+// WithoutRedaction returns a logger whose entries skip regex, key and
+// secret redaction, for hot paths logging values known to be safe
+func (l *Logger) WithoutRedaction() *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	unredacted := l.clone()
+	unredacted.noRedact = true
+	return unredacted
+}
+
+// skipsRedaction reports whether fields carry the skip redaction marker
+func skipsRedaction(fields []zapcore.Field) bool {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Type == zapcore.SkipType && fields[i].Key == skipRedactionKey {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSkipRedactionKeyIsAnOrdinaryField(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedaction(regexp.MustCompile(`secret-\w+`), "[SECRET]")
+	l.AddRedactFields("password")
+	logs := observe(l, zapcore.InfoLevel)
+
+	l.Info("id secret-a", map[string]interface{}{"password": "hunter2", skipRedactionKey: true})
+
+	entry := logs.All()[0]
+	if entry.Message != "id [SECRET]" {
+		t.Errorf("message = %q, want redacted", entry.Message)
+	}
+	if got := entry.ContextMap()["password"]; got != RedactedValue {
+		t.Errorf("password = %v, want redacted", got)
+	}
+}
+
+func TestWithoutRedaction(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("password")
+	logs := observe(l, zapcore.InfoLevel)
+
+	l.WithoutRedaction().Info("login", map[string]interface{}{"password": "hunter2"})
+	l.Info("login", map[string]interface{}{"password": "hunter2"})
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["password"]; got != "hunter2" {
+		t.Errorf("password = %v, want unredacted", got)
+	}
+	if got := entries[1].ContextMap()["password"]; got != RedactedValue {
+		t.Errorf("password = %v, want redacted", got)
+	}
+}
+
+func TestSkipRedactionFieldThroughSugar(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("password")
+	logs := observe(l, zapcore.InfoLevel)
+
+	l.Sugar().Infow("login", "password", "hunter2", SkipRedaction())
+	l.Sugar().Infow("login", "password", "hunter2")
+
+	entries := logs.All()
+	if got := entries[0].ContextMap()["password"]; got != "hunter2" {
+		t.Errorf("skipped password = %v, want unredacted", got)
+	}
+	if got := entries[1].ContextMap()["password"]; got != RedactedValue {
+		t.Errorf("password = %v, want redacted", got)
+	}
+}
+
+func TestZapSkipRedactionField(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("password")
+	logs := observe(l, zapcore.InfoLevel)
+
+	l.Sugar().Desugar().Info("login", zap.String("password", "hunter2"), SkipRedaction())
+
+	if got := logs.All()[0].ContextMap()["password"]; got != "hunter2" {
+		t.Errorf("password = %v, want unredacted", got)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// RedactionPolicy is a set of redaction rules distributed by a central
+// policy service. Applying a policy replaces the previous one as a whole;
+// rules added in code are kept.
+type RedactionPolicy struct {
+	// Version identifies the policy in logs
+	Version string `json:"version,omitempty"`
+	// Rules are redaction patterns applied to messages and string values
+	Rules []PolicyRule `json:"rules,omitempty"`
+	// Keys are field keys whose values are always redacted
+	Keys []string `json:"keys,omitempty"`
+	// Classes declares the sensitivity of field keys. A key classified
+	// both in code and by the policy gets the higher class.
+	Classes map[string]Sensitivity `json:"classes,omitempty"`
+}
+
+// PolicyRule is a redaction pattern of a RedactionPolicy, see
+// RedactionRule for the replacement template
+type PolicyRule struct {
+	Name        string `json:"name,omitempty"`
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// PolicyProvider fetches redaction policies from a policy service
+type PolicyProvider interface {
+	FetchPolicy(ctx context.Context) (RedactionPolicy, error)
+}
+
+// PolicyProviderFunc adapts a function to the PolicyProvider interface
+type PolicyProviderFunc func(ctx context.Context) (RedactionPolicy, error)
+
+// FetchPolicy implements PolicyProvider
+func (f PolicyProviderFunc) FetchPolicy(ctx context.Context) (RedactionPolicy, error) {
+	return f(ctx)
+}
+
+// HTTPPolicyProvider fetches a RedactionPolicy as JSON from url, e.g.
+//
+//	{"version": "42", "rules": [{"name": "iban", "pattern": "[A-Z]{2}\\d{2}[A-Z0-9]{11,30}", "replacement": "[IBAN]"}],
+//	 "keys": ["password"], "classes": {"email": "pii"}}
+//
+// A nil client uses http.DefaultClient.
+func HTTPPolicyProvider(url string, client *http.Client) PolicyProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return PolicyProviderFunc(func(ctx context.Context) (RedactionPolicy, error) {
+		var policy RedactionPolicy
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return policy, err
+		}
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return policy, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return policy, fmt.Errorf("policy endpoint returned %s", resp.Status)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&policy); err != nil {
+			return policy, fmt.Errorf("decoding policy: %w", err)
+		}
+		return policy, nil
+	})
+}
+
+// PolicyWatchConfig configures WatchRedactionPolicy
+type PolicyWatchConfig struct {
+	// Interval between polls, defaults to 1 minute
+	Interval time.Duration
+}
+
+// WatchRedactionPolicy polls provider and applies the policies it returns
+// until ctx is done, so masking can be updated without redeploying.
+// Provider errors and invalid policies keep the last applied policy.
+func (l *Logger) WatchRedactionPolicy(ctx context.Context, provider PolicyProvider, cfg PolicyWatchConfig) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	failing := false
+	version := ""
+	for {
+		policy, err := provider.FetchPolicy(ctx)
+		if err == nil {
+			err = l.ApplyRedactionPolicy(policy)
+		}
+
+		if err != nil {
+			if !failing {
+				l.Warn("Redaction policy update failed, keeping current policy", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+			failing = true
+		} else {
+			failing = false
+			if policy.Version != version {
+				version = policy.Version
+				l.Info("Redaction policy applied", map[string]interface{}{"version": version})
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ApplyRedactionPolicy replaces the redaction policy of the logger and
+// its relatives. The policy is validated first and takes effect for all
+// entries at once; an invalid policy leaves the current one in place.
+func (l *Logger) ApplyRedactionPolicy(policy RedactionPolicy) error {
+	applied := &appliedPolicy{
+		rules:   redactor{keys: make(map[string]struct{}, len(policy.Keys))},
+		classes: make(map[string]Sensitivity, len(policy.Classes)),
+	}
+	for _, rule := range policy.Rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return fmt.Errorf("redaction policy rule %q: %w", rule.Name, err)
+		}
+		applied.rules.redactions = append(applied.rules.redactions, newRedaction(rule.Name, pattern, rule.Replacement))
+	}
+	for _, key := range policy.Keys {
+		applied.rules.keys[key] = struct{}{}
+	}
+	for key, class := range policy.Classes {
+		if class < SensitivityPublic || class > SensitivitySecret {
+			return fmt.Errorf("redaction policy: invalid class %d for key %q", class, key)
+		}
+		applied.classes[key] = class
+	}
+
+	l.policy.Store(applied)
+	rulesGeneration.Add(1)
+	return nil
+}
+
+// appliedPolicy is the applied redaction policy, replaced as a whole. It
+// is shared by a logger and its relatives.
+type appliedPolicy struct {
+	rules   redactor
+	classes map[string]Sensitivity
+}
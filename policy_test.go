@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRedactionPolicyIsReplacedWhole(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	child := l.Child("db")
+	policy := RedactionPolicy{
+		Rules:   []PolicyRule{{Name: "card", Pattern: `\d{16}`, Replacement: "[${pattern_name}]"}},
+		Classes: map[string]Sensitivity{"email": SensitivityPII},
+	}
+	if err := l.ApplyRedactionPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := child.redactMessage("card 4111111111111111"); got != "card [card]" {
+		t.Errorf("redacted = %q", got)
+	}
+	if got := l.classes.lookup("email"); got != SensitivityPII {
+		t.Errorf("email class = %v", got)
+	}
+
+	if err := l.ApplyRedactionPolicy(RedactionPolicy{Keys: []string{"token"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := child.redactMessage("card 4111111111111111"); got != "card 4111111111111111" {
+		t.Errorf("redacted by replaced policy = %q", got)
+	}
+	if got := l.classes.lookup("email"); got != SensitivityPublic {
+		t.Errorf("email class of replaced policy = %v", got)
+	}
+}
+
+func TestRedactionPolicyIsMergedOnce(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	if err := l.ApplyRedactionPolicy(RedactionPolicy{Keys: []string{"token"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	l.publishedRedactor()
+	merged := l.mergedRules.Load()
+	l.publishedRedactor()
+	if l.mergedRules.Load() != merged {
+		t.Error("rules merged again without a change")
+	}
+
+	l.AddRedactFields("password")
+	r := l.publishedRedactor()
+	if l.mergedRules.Load() == merged {
+		t.Error("rules not merged again after a change")
+	}
+	for _, key := range []string{"token", "password"} {
+		if _, ok := r.keys[key]; !ok {
+			t.Errorf("%s not redacted", key)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE, reserving space past the end of
+// the file without changing its size
+const fallocKeepSize = 0x1
+
+// preallocate reserves length bytes of disk space at offset, beyond the
+// end of the file. Truncating the file releases the unused space.
+func preallocate(file *os.File, offset, length int64) error {
+	err := syscall.Fallocate(int(file.Fd()), fallocKeepSize, offset, length)
+	if err == syscall.EOPNOTSUPP {
+		// Some file systems don't support fallocate; write unreserved
+		return nil
+	}
+	return err
+}
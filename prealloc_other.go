@@ -0,0 +1,11 @@
+//go:build !linux
+
+package main
+
+import "os"
+
+// preallocate is a no-op: space is not reserved on this platform, and
+// the file grows as entries are written
+func preallocate(file *os.File, offset, length int64) error {
+	return nil
+}
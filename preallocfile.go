@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// PreallocConfig configures WithPreallocation
+type PreallocConfig struct {
+	// ChunkSize is the space reserved each time the file fills up,
+	// defaults to 64MB
+	ChunkSize int64
+	// BufferSize is the size of the write buffer, rounded up to a
+	// multiple of 4KB. Defaults to 1MB.
+	BufferSize int
+	// FlushInterval is how often buffered entries are written and synced
+	// to disk, bounding what a crash can lose. Defaults to 1s.
+	FlushInterval time.Duration
+}
+
+// WithPreallocation makes a file handler reserve disk space in large
+// chunks and write through a large aligned buffer, for sustained high
+// volume logging. Space is reserved past the end of the file, so readers
+// never see padding, and unused space is released when the handler is
+// removed. Zero padding left by a crash is trimmed when the file is
+// reopened.
+func WithPreallocation(cfg PreallocConfig) HandlerOption {
+	if cfg.ChunkSize <= 0 {
+		cfg.ChunkSize = 64 << 20
+	}
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1 << 20
+	}
+	cfg.BufferSize = (cfg.BufferSize + 4095) &^ 4095
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	return func(o *handlerOptions) {
+		o.prealloc = &cfg
+	}
+}
+
+// preallocatedFile writes to a file with preallocated space at a tracked
+// offset. It implements zapcore.WriteSyncer.
+type preallocatedFile struct {
+	cfg       PreallocConfig
+	file      *os.File
+	offset    int64
+	allocated int64
+	buf       []byte
+	onError   func(error)
+	done      chan struct{}
+	stopped   chan struct{}
+	closed    sync.Once
+	mu        sync.Mutex
+}
+
+// openPreallocatedFile opens path, resuming after the last entry written
+func openPreallocatedFile(path string, cfg PreallocConfig, onError func(error)) (*preallocatedFile, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	// Trim zero padding left by a previous run
+	offset, err := dataEnd(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(offset); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	f := &preallocatedFile{
+		cfg:       cfg,
+		file:      file,
+		offset:    offset,
+		allocated: offset,
+		buf:       make([]byte, 0, cfg.BufferSize),
+		onError:   onError,
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+	go f.run()
+	return f, nil
+}
+
+// dataEnd returns the offset following the last non-zero byte of file
+func dataEnd(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	chunk := make([]byte, 64*1024)
+	end := info.Size()
+	for end > 0 {
+		start := max(end-int64(len(chunk)), 0)
+		n, err := file.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexFunc(chunk[:n], func(r rune) bool { return r != 0 }); i >= 0 {
+			// Include the rest of a multi-byte character
+			for i < n && chunk[i] != 0 {
+				i++
+			}
+			return start + int64(i), nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// run flushes and syncs the buffer periodically until the file is closed
+func (f *preallocatedFile) run() {
+	defer close(f.stopped)
+
+	ticker := time.NewTicker(f.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.done:
+			return
+		case <-ticker.C:
+			if err := f.Sync(); err != nil {
+				f.onError(err)
+			}
+		}
+	}
+}
+
+// Write implements zapcore.WriteSyncer
+func (f *preallocatedFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.buf)+len(p) > cap(f.buf) {
+		if err := f.flushLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	// Entries larger than the buffer are written directly
+	if len(p) > cap(f.buf) {
+		if err := f.writeLocked(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	f.buf = append(f.buf, p...)
+	return len(p), nil
+}
+
+// Sync writes buffered entries and syncs the file
+func (f *preallocatedFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.flushLocked(); err != nil {
+		return err
+	}
+	return f.file.Sync()
+}
+
+// Close stops the flusher, writes buffered entries and releases the
+// unused reserved space
+func (f *preallocatedFile) Close() error {
+	var err error
+	f.closed.Do(func() {
+		close(f.done)
+		<-f.stopped
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		err = errors.Join(f.flushLocked(), f.file.Truncate(f.offset), f.file.Close())
+	})
+	return err
+}
+
+// flushLocked writes the buffer to the file. The caller must hold the lock.
+func (f *preallocatedFile) flushLocked() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+	if err := f.writeLocked(f.buf); err != nil {
+		return err
+	}
+	f.buf = f.buf[:0]
+	return nil
+}
+
+// writeLocked writes p at the current offset, reserving another chunk
+// if needed. The caller must hold the lock.
+func (f *preallocatedFile) writeLocked(p []byte) error {
+	if need := f.offset + int64(len(p)); need > f.allocated {
+		size := f.allocated + f.cfg.ChunkSize
+		for size < need {
+			size += f.cfg.ChunkSize
+		}
+		if err := preallocate(f.file, f.allocated, size-f.allocated); err != nil {
+			return err
+		}
+		f.allocated = size
+	}
+
+	n, err := f.file.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return err
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPreallocatedFileHasNoPadding(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := openPreallocatedFile(path, PreallocConfig{ChunkSize: 1 << 20, BufferSize: 4096, FlushInterval: time.Hour}, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write([]byte("entry\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "entry\n" {
+		t.Errorf("file = %q, want the entry without padding", data)
+	}
+}
+
+func TestPreallocatedFileCloseFlushes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := openPreallocatedFile(path, PreallocConfig{ChunkSize: 1 << 20, BufferSize: 4096, FlushInterval: time.Hour}, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("entry\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	select {
+	case <-f.stopped:
+	default:
+		t.Error("flusher still running after Close")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "entry\n" {
+		t.Errorf("file = %q, want the buffered entry", data)
+	}
+}
+
+func TestPreallocatedFileTrimsPaddingOnReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	padded := append([]byte("first\n"), make([]byte, 4096)...)
+	if err := os.WriteFile(path, padded, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := openPreallocatedFile(path, PreallocConfig{ChunkSize: 1 << 20, BufferSize: 4096, FlushInterval: time.Hour}, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, []byte("first\nsecond\n")) {
+		t.Errorf("file = %q, want padding trimmed", data)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Profiles selectable with Config.Profile
+const (
+	ProfileDevelopment = "development"
+	ProfileStaging     = "staging"
+	ProfileProduction  = "production"
+)
+
+// productionThrottle samples entries below Warn during log storms
+var productionThrottle = ThrottleConfig{MaxPerSecond: 1000, SampleEvery: 100}
+
+// NewDevelopmentLogger creates a logger with the development profile:
+// colored human-readable console output at Debug level
+func NewDevelopmentLogger(name string) (*Logger, error) {
+	return NewLoggerWithConfig(Config{Name: name, Profile: ProfileDevelopment})
+}
+
+// NewProductionLogger creates a logger with the production profile: JSON
+// on stdout at Info level, sampling entries below Warn during log storms
+func NewProductionLogger(name string) (*Logger, error) {
+	return NewLoggerWithConfig(Config{Name: name, Profile: ProfileProduction})
+}
+
+// withProfile fills settings left unset with the defaults of the
+// configured profile. Level counts as unset at Info, its zero value.
+func (c Config) withProfile() Config {
+	switch strings.ToLower(c.Profile) {
+	case ProfileDevelopment:
+		c.Development = true
+		if c.Level == zapcore.InfoLevel {
+			c.Level = zapcore.DebugLevel
+		}
+		if c.ConsoleLevel == nil && len(c.Handlers) == 0 {
+			c.ConsoleLevel = &c.Level
+		}
+	case ProfileStaging, ProfileProduction:
+		if c.ConsoleLevel == nil && len(c.Handlers) == 0 {
+			c.ConsoleLevel = &c.Level
+		}
+		if c.ColorMode == ColorAuto {
+			c.ColorMode = ColorNever
+		}
+	}
+	return c
+}
+
+// applyProfile enables the logger features of a profile
+func (l *Logger) applyProfile(profile string) {
+	if strings.EqualFold(profile, ProfileProduction) {
+		l.EnableThrottling(productionThrottle)
+	}
+}
+
+// validateProfile checks that a profile is known
+func validateProfile(profile string) error {
+	switch strings.ToLower(profile) {
+	case "", ProfileDevelopment, ProfileStaging, ProfileProduction:
+		return nil
+	}
+	return fmt.Errorf("Profile: unknown profile %q; use development, staging or production", profile)
+}
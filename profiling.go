@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Do calls fn with pprof labels for the logger name and request ID set
+// on the goroutine, so CPU profiles can be correlated with log entries
+func (l *Logger) Do(ctx context.Context, fn func(ctx context.Context)) {
+	pprof.Do(ctx, l.pprofLabels(), fn)
+}
+
+// pprofLabels returns the profiling labels identifying this logger
+func (l *Logger) pprofLabels() pprof.LabelSet {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	labels := []string{"logger", l.name}
+	if l.requestID != "" {
+		labels = append(labels, RequestIDKey, l.requestID)
+	}
+	return pprof.Labels(labels...)
+}
+
+// EnableRuntimeTrace emits a runtime/trace user log event for every
+// Error and Fatal entry while an execution trace is being collected
+func (l *Logger) EnableRuntimeTrace() {
+	l.runtimeTrace.Store(true)
+}
+
+// DisableRuntimeTrace stops emitting runtime/trace events
+func (l *Logger) DisableRuntimeTrace() {
+	l.runtimeTrace.Store(false)
+}
+
+// WithPprofLabels runs each request's handler with pprof labels for the
+// logger name and request ID
+func WithPprofLabels() MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.pprofLabels = true
+	}
+}
+
+// traceEvent records an entry as a runtime/trace user log, if enabled
+func (l *Logger) traceEvent(ctx context.Context, level LogLevel, msg string) {
+	if !l.runtimeTrace.Load() || !trace.IsEnabled() {
+		return
+	}
+	trace.Log(ctx, "log."+level.String(), msg)
+}
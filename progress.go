@@ -0,0 +1,148 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressConfig configures a progress logger. Progress is logged every
+// Interval or every Items items, whichever comes first.
+type ProgressConfig struct {
+	// Interval between entries, defaults to 10s. Entries are logged on
+	// schedule even if no items were processed, as a heartbeat.
+	Interval time.Duration
+	// Items between entries; 0 logs on Interval only
+	Items int64
+	// Total expected items, if known, to report the percentage done
+	Total int64
+	// Level of progress entries; the zero value is Info
+	Level LogLevel
+}
+
+// Progress logs periodic progress of a long-running operation
+type Progress struct {
+	logger    *Logger
+	operation string
+	cfg       ProgressConfig
+	start     time.Time
+	processed atomic.Int64
+	nextItems atomic.Int64
+	ticker    *time.Ticker
+	stop      chan struct{}
+	done      sync.Once
+	mu        sync.Mutex
+	lastAt    time.Time
+	lastCount int64
+}
+
+// StartProgress starts logging progress of an operation:
+//
+//	p := logger.StartProgress("import", ProgressConfig{Items: 1000, Total: n})
+//	for ... { p.Add(1) }
+//	p.Done(nil)
+func (l *Logger) StartProgress(operation string, cfg ProgressConfig) *Progress {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+
+	now := time.Now()
+	p := &Progress{
+		logger:    l,
+		operation: operation,
+		cfg:       cfg,
+		start:     now,
+		lastAt:    now,
+		ticker:    time.NewTicker(cfg.Interval),
+		stop:      make(chan struct{}),
+	}
+	p.nextItems.Store(cfg.Items)
+	go p.run()
+	return p
+}
+
+// Add records n processed items, logging progress if Items were reached
+func (p *Progress) Add(n int64) {
+	processed := p.processed.Add(n)
+	if p.cfg.Items <= 0 {
+		return
+	}
+	next := p.nextItems.Load()
+	if processed >= next && p.nextItems.CompareAndSwap(next, processed+p.cfg.Items) {
+		p.report()
+	}
+}
+
+// Done stops periodic entries and logs a summary with fields, returning
+// the number of processed items
+func (p *Progress) Done(fields map[string]interface{}) int64 {
+	p.done.Do(func() { close(p.stop) })
+
+	elapsed := time.Since(p.start)
+	processed := p.processed.Load()
+
+	entryFields := p.fields(processed, rate(processed, elapsed))
+	for k, v := range fields {
+		entryFields[k] = v
+	}
+	entryFields[DurationKey] = float64(elapsed.Microseconds()) / 1000
+
+	p.logger.log(p.cfg.Level, p.operation+" finished", entryFields)
+	return processed
+}
+
+// run logs progress every Interval until Done
+func (p *Progress) run() {
+	defer p.ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.ticker.C:
+			p.report()
+		}
+	}
+}
+
+// report logs current progress with the rate since the last entry,
+// restarting the interval
+func (p *Progress) report() {
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+
+	p.mu.Lock()
+	now := time.Now()
+	processed := p.processed.Load()
+	current := rate(processed-p.lastCount, now.Sub(p.lastAt))
+	p.lastAt, p.lastCount = now, processed
+	p.ticker.Reset(p.cfg.Interval)
+	p.mu.Unlock()
+
+	p.logger.log(p.cfg.Level, p.operation+" progress", p.fields(processed, current))
+}
+
+// fields returns the count, rate and completion fields
+func (p *Progress) fields(processed int64, perSecond float64) map[string]interface{} {
+	fields := map[string]interface{}{
+		OperationKey:   p.operation,
+		"processed":    processed,
+		"rate_per_sec": perSecond,
+	}
+	if p.cfg.Total > 0 {
+		fields["total"] = p.cfg.Total
+		fields["percent"] = float64(processed) * 100 / float64(p.cfg.Total)
+	}
+	return fields
+}
+
+// rate returns items per second
+func rate(items int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(items) / elapsed.Seconds()
+}
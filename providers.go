@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+)
+
+// Dependency injection providers. They use plain function signatures so
+// they plug into Google wire and Uber fx without this module depending
+// on either, e.g.
+//
+//	wire.NewSet(ProvideLogger, wire.Value(cfg))
+//	fx.Provide(fx.Annotate(ChildProvider("db"), fx.ResultTags(`name:"db"`)))
+//	fx.Invoke(func(l *Logger, lc fx.Lifecycle) { lc.Append(fx.Hook{OnStop: l.Stop}) })
+
+// ProvideLogger builds a logger from cfg. Its signature is a wire
+// provider with a cleanup function that syncs the logger's handlers.
+func ProvideLogger(cfg Config) (*Logger, func(), error) {
+	l, err := NewLoggerWithConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, func() { l.Sync() }, nil
+}
+
+// ChildProvider returns a provider of the named child of the injected
+// logger, for components expecting their own logger
+func ChildProvider(name string) func(*Logger) *Logger {
+	return func(l *Logger) *Logger {
+		return l.Child(name)
+	}
+}
+
+// Stop syncs the logger's handlers, giving up when ctx is done. It
+// suits lifecycle stop hooks such as fx.Hook.OnStop.
+func (l *Logger) Stop(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- l.Sync()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
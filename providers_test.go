@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestProvideLoggerAndChildProvider(t *testing.T) {
+	l, cleanup, err := ProvideLogger(Config{Name: "app", Level: zapcore.InfoLevel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	logs := observe(l, zapcore.InfoLevel)
+	ChildProvider("db")(l).Info("connected")
+
+	if got := logs.All()[0].ContextMap()["logger"]; got != "app.db" {
+		t.Errorf("logger = %v, want the named child", got)
+	}
+}
+
+func TestStopGivesUpWhenContextIsDone(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := l.Stop(ctx); err != nil && err != context.Canceled {
+		t.Errorf("Stop = %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldTransform rewrites the values of a field
+type fieldTransform struct {
+	// kind names the transform in the effective configuration
+	kind  string
+	apply func(string) string
+}
+
+// fieldTransforms maps field keys to transforms of their values, such as
+// pseudonymization. The map is copied on write. It is shared by a logger
+// and its children.
+type fieldTransforms struct {
+	transforms atomic.Pointer[map[string]fieldTransform]
+	mu         sync.Mutex
+}
+
+// lookup returns the transform of a field key, if any
+func (t *fieldTransforms) lookup(key string) func(string) string {
+	if t == nil {
+		return nil
+	}
+	if transforms := t.transforms.Load(); transforms != nil {
+		return (*transforms)[key].apply
+	}
+	return nil
+}
+
+// active reports whether any field is transformed
+func (t *fieldTransforms) active() bool {
+	if t == nil {
+		return false
+	}
+	transforms := t.transforms.Load()
+	return transforms != nil && len(*transforms) > 0
+}
+
+// set assigns transform to field keys
+func (t *fieldTransforms) set(transform fieldTransform, keys ...string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	transforms := map[string]fieldTransform{}
+	if current := t.transforms.Load(); current != nil {
+		for k, v := range *current {
+			transforms[k] = v
+		}
+	}
+	for _, k := range keys {
+		transforms[k] = transform
+	}
+	t.transforms.Store(&transforms)
+	rulesGeneration.Add(1)
+}
+
+// keys returns the field keys with a transform of the kind, sorted
+func (t *fieldTransforms) keys(kind string) []string {
+	transforms := t.transforms.Load()
+	if transforms == nil {
+		return nil
+	}
+	var keys []string
+	for k, transform := range *transforms {
+		if transform.kind == kind {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// transformedValue marks a value that was already transformed, since
+// fields pass through redaction both per entry and per handler
+type transformedValue string
+
+// String implements fmt.Stringer
+func (v transformedValue) String() string {
+	return string(v)
+}
+
+// transform returns the field with its value rewritten, if its key has
+// a transform. Values that can't be rendered as text are redacted rather
+// than logged as they are.
+func (t *fieldTransforms) transform(f zapcore.Field) (zapcore.Field, bool) {
+	transform := t.lookup(f.Key)
+	if transform == nil {
+		return f, false
+	}
+	if _, ok := f.Interface.(transformedValue); ok {
+		return f, false
+	}
+	value, ok := fieldValueString(f)
+	if !ok {
+		return zap.Stringer(f.Key, transformedValue(RedactedValue)), true
+	}
+	return zap.Stringer(f.Key, transformedValue(transform(value))), true
+}
+
+// transformValues returns fields with transformed values, for rendering
+// them outside of fields
+func (r redactor) transformValues(fields map[string]interface{}) map[string]interface{} {
+	if !r.transforms.active() {
+		return fields
+	}
+	transformed := make(map[string]interface{}, len(fields))
+	for k, v := range fields {
+		if f, ok := r.transforms.transform(zap.Any(k, v)); ok {
+			v = f.Interface
+		}
+		transformed[k] = v
+	}
+	return transformed
+}
+
+// fieldValueString returns the value of a field as text. Strings and
+// integers are used as they are, other values in their encoded form,
+// such as "[1 2 3]" for a byte array.
+func fieldValueString(f zapcore.Field) (string, bool) {
+	switch f.Type {
+	case zapcore.StringType:
+		return f.String, true
+	case zapcore.ByteStringType:
+		return string(f.Interface.([]byte)), true
+	case zapcore.StringerType:
+		return f.Interface.(fmt.Stringer).String(), true
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		return strconv.FormatInt(f.Integer, 10), true
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		return strconv.FormatUint(uint64(f.Integer), 10), true
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	if err := encodeField(enc, f); err != nil {
+		return "", false
+	}
+	value, ok := enc.Fields[f.Key]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+// encodeField adds f to enc, returning the marshaling errors and panics
+// that AddTo would log as a separate field
+func encodeField(enc *zapcore.MapObjectEncoder, f zapcore.Field) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("encoding %s: %v", f.Key, r)
+		}
+	}()
+	switch f.Type {
+	case zapcore.ObjectMarshalerType:
+		return enc.AddObject(f.Key, f.Interface.(zapcore.ObjectMarshaler))
+	case zapcore.ArrayMarshalerType:
+		return enc.AddArray(f.Key, f.Interface.(zapcore.ArrayMarshaler))
+	case zapcore.ReflectType:
+		return enc.AddReflected(f.Key, f.Interface)
+	}
+	f.AddTo(enc)
+	return nil
+}
+
+// Pseudonymize replaces the values of identifier fields such as user_id,
+// email or client_ip with a keyed hash, see Pseudonym. Services sharing
+// the key log the same pseudonym for the same identifier, so entries can
+// be joined across services without storing raw identifiers. Keys
+// redacted with AddRedactFields stay redacted.
+func (l *Logger) Pseudonymize(key []byte, fields ...string) error {
+	if len(key) == 0 {
+		return fmt.Errorf("pseudonymization key must not be empty")
+	}
+	key = append([]byte{}, key...)
+	l.transforms.set(fieldTransform{kind: "pseudonymize", apply: func(value string) string {
+		return Pseudonym(key, value)
+	}}, fields...)
+	return nil
+}
+
+// pseudonymizeFromEnv pseudonymizes fields with the key in an
+// environment variable
+func (l *Logger) pseudonymizeFromEnv(env string, fields []string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := l.Pseudonymize([]byte(os.Getenv(env)), fields...); err != nil {
+		return fmt.Errorf("%s: %w", env, err)
+	}
+	return nil
+}
+
+// Pseudonym returns the pseudonym logged for an identifier: the first
+// 16 bytes of its HMAC-SHA256 under key, hex encoded. It lets tools find
+// the entries of a known identifier.
+func Pseudonym(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestPseudonymizeHashesAnyValue(t *testing.T) {
+	key := []byte("key")
+	l := NewLogger("app", zapcore.InfoLevel)
+	if err := l.Pseudonymize(key, "user_id", "device", "owner"); err != nil {
+		t.Fatal(err)
+	}
+	logs := observe(l, zapcore.InfoLevel)
+
+	uuid := [16]byte{1, 2, 3}
+	l.Sugar().Infow("login",
+		"user_id", uint(42),
+		"device", uuid,
+		"owner", struct{ Name string }{"alice"})
+
+	fields := logs.All()[0].ContextMap()
+	if got := fields["user_id"]; got != Pseudonym(key, "42") {
+		t.Errorf("user_id = %v", got)
+	}
+	if got := fields["device"]; got != Pseudonym(key, "[1 2 3 0 0 0 0 0 0 0 0 0 0 0 0 0]") {
+		t.Errorf("device = %v", got)
+	}
+	if got, ok := fields["owner"].(string); !ok || got == "" {
+		t.Errorf("owner = %v, want a pseudonym", fields["owner"])
+	}
+}
+
+func TestPseudonymizeRedactsUnencodableValues(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	if err := l.Pseudonymize([]byte("key"), "user_id"); err != nil {
+		t.Fatal(err)
+	}
+	logs := observe(l, zapcore.InfoLevel)
+
+	l.Sugar().Desugar().Info("login", zap.Object("user_id", panickingObject{}))
+
+	if got := logs.All()[0].ContextMap()["user_id"]; got != RedactedValue {
+		t.Errorf("user_id = %v, want redacted", got)
+	}
+}
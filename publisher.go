@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Publisher publishes messages to a subject on a message bus.
+// *nats.Conn satisfies this interface.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// AddPublisherHandler adds a handler publishing JSON-encoded entries to
+// subject, letting other systems subscribe to the log stream
+func (l *Logger) AddPublisherHandler(pub Publisher, subject string, level LogLevel, opts ...HandlerOption) error {
+	if pub == nil {
+		return fmt.Errorf("publisher must not be nil")
+	}
+	if subject == "" {
+		return fmt.Errorf("subject must not be empty")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("publisher", true); err != nil {
+		return err
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		return err
+	}
+
+	sink := &publisherWriter{pub: pub, subject: subject}
+	spec := handlerSpec{kind: "publisher", sink: subject, encoder: "json", level: level}
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), sink, handlerOpts)
+	return nil
+}
+
+// publisherWriter publishes each encoded entry as one message
+type publisherWriter struct {
+	pub     Publisher
+	subject string
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *publisherWriter) Write(p []byte) (int, error) {
+	// The encoder reuses its buffer, so publish a copy
+	data := append([]byte{}, p...)
+	if err := w.pub.Publish(w.subject, data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *publisherWriter) Sync() error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+// quietWindow suppresses entries below minLevel for matching loggers
+type quietWindow struct {
+	pattern    string
+	minLevel   LogLevel
+	start      time.Time
+	suppressed int
+}
+
+// quietRules holds the active quiet windows. It is shared by a logger
+// and its children.
+type quietRules struct {
+	windows map[int]*quietWindow
+	nextID  int
+	mu      sync.Mutex
+}
+
+// suppress reports whether an entry from the named logger should be
+// dropped because of an active quiet window
+func (q *quietRules) suppress(name string, level LogLevel) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, w := range q.windows {
+		if level >= w.minLevel {
+			continue
+		}
+		if w.pattern != "" {
+			if matched, _ := path.Match(w.pattern, name); !matched {
+				continue
+			}
+		}
+		w.suppressed++
+		return true
+	}
+	return false
+}
+
+// add registers a window and returns its ID
+func (q *quietRules) add(w *quietWindow) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	q.windows[q.nextID] = w
+	return q.nextID
+}
+
+// remove unregisters a window and returns it
+func (q *quietRules) remove(id int) *quietWindow {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w := q.windows[id]
+	delete(q.windows, id)
+	return w
+}
+
+// Quiet suppresses entries below minLevel from all loggers until ctx is
+// done, e.g. during a maintenance window. The suppression period is
+// recorded in a log entry when it ends.
+func (l *Logger) Quiet(ctx context.Context, minLevel LogLevel) {
+	l.QuietPattern(ctx, "", minLevel)
+}
+
+// QuietPattern suppresses entries below minLevel from loggers whose name
+// matches pattern (path.Match syntax, e.g. "app.db*") until ctx is done.
+// An empty pattern matches all loggers.
+func (l *Logger) QuietPattern(ctx context.Context, pattern string, minLevel LogLevel) {
+	window := &quietWindow{
+		pattern:  pattern,
+		minLevel: minLevel,
+		start:    time.Now(),
+	}
+
+	l.Info("Quiet period started", map[string]interface{}{
+		"quiet_pattern":   pattern,
+		"quiet_min_level": minLevel.String(),
+	})
+	id := l.quiet.add(window)
+
+	go func() {
+		<-ctx.Done()
+		// Removing the window under the lock makes its count final
+		suppressed := l.quiet.remove(id).suppressed
+		end := time.Now()
+
+		l.Info("Quiet period ended", map[string]interface{}{
+			"quiet_pattern":    pattern,
+			"quiet_min_level":  minLevel.String(),
+			"quiet_start":      window.start,
+			"quiet_end":        end,
+			"quiet_duration":   end.Sub(window.start),
+			"quiet_suppressed": suppressed,
+		})
+	}()
+}
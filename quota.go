@@ -0,0 +1,178 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// QuotaConfig configures a per-logger quota
+type QuotaConfig struct {
+	// MaxEntries is the number of entries allowed per window; zero is
+	// unlimited
+	MaxEntries int
+	// MaxBytes is the approximate size of entries allowed per window;
+	// zero is unlimited
+	MaxBytes int
+	// Window is the length of the sliding window, defaults to 1 minute
+	Window time.Duration
+	// SampleEvery keeps one in every SampleEvery entries over quota;
+	// zero or one drops them all
+	SampleEvery int
+	// Level is the lowest level exempt from the quota, defaults to Error
+	Level *LogLevel
+}
+
+// quota tracks usage of one quota over a sliding window, approximated
+// by weighting the previous fixed window by its overlap
+type quota struct {
+	cfg         QuotaConfig
+	level       LogLevel
+	windowStart time.Time
+	entries     int
+	bytes       int
+	prevEntries int
+	prevBytes   int
+	exceeded    bool
+	seen        int
+	dropped     int
+}
+
+// quotaRules holds quotas keyed by hierarchical logger name. A quota
+// covers a logger and its descendants. It is shared by a logger and its
+// children.
+type quotaRules struct {
+	quotas map[string]*quota
+	mu     sync.Mutex
+}
+
+// allow counts an entry from the named logger and reports whether it
+// should be written, along with a notice to emit if the quota was
+// exceeded or restored
+func (r *quotaRules) allow(name string, level LogLevel, msg string, fields map[string]interface{}, now time.Time) (bool, *throttleNotice) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.quotas) == 0 {
+		return true, nil
+	}
+
+	// Find the quota of the closest configured ancestor
+	owner := name
+	q, ok := r.quotas[owner]
+	for !ok {
+		i := strings.LastIndexByte(owner, '.')
+		if i < 0 {
+			return true, nil
+		}
+		owner = owner[:i]
+		q, ok = r.quotas[owner]
+	}
+
+	if level >= q.level {
+		return true, nil
+	}
+	size := entrySize(msg, fields)
+
+	// Roll the window, keeping the previous one if adjacent
+	var notice *throttleNotice
+	if elapsed := now.Sub(q.windowStart); elapsed >= q.cfg.Window {
+		if elapsed < 2*q.cfg.Window {
+			q.prevEntries, q.prevBytes = q.entries, q.bytes
+			q.windowStart = q.windowStart.Add(q.cfg.Window)
+		} else {
+			q.prevEntries, q.prevBytes = 0, 0
+			q.windowStart = now
+		}
+		q.entries, q.bytes = 0, 0
+	}
+
+	// Weight the previous window by how much of it the sliding window covers
+	weight := 1 - float64(now.Sub(q.windowStart))/float64(q.cfg.Window)
+	entries := q.entries + 1 + int(weight*float64(q.prevEntries))
+	bytes := q.bytes + size + int(weight*float64(q.prevBytes))
+	over := (q.cfg.MaxEntries > 0 && entries > q.cfg.MaxEntries) ||
+		(q.cfg.MaxBytes > 0 && bytes > q.cfg.MaxBytes)
+
+	switch {
+	case over && !q.exceeded:
+		q.exceeded = true
+		q.seen = 0
+		notice = &throttleNotice{
+			msg: "Log quota exceeded",
+			fields: []zap.Field{
+				zap.String("quota_logger", owner),
+				zap.Int("max_entries", q.cfg.MaxEntries),
+				zap.Int("max_bytes", q.cfg.MaxBytes),
+				zap.Duration("window", q.cfg.Window),
+			},
+		}
+	case !over && q.exceeded:
+		q.exceeded = false
+		notice = &throttleNotice{
+			msg: "Log quota restored",
+			fields: []zap.Field{
+				zap.String("quota_logger", owner),
+				zap.Int("dropped", q.dropped),
+			},
+		}
+		q.dropped = 0
+	}
+
+	if over {
+		q.seen++
+		if q.cfg.SampleEvery <= 1 || q.seen%q.cfg.SampleEvery != 0 {
+			q.dropped++
+			return false, notice
+		}
+	}
+	q.entries++
+	q.bytes += size
+	return true, notice
+}
+
+// SetQuota limits the entries written by the named logger and its
+// descendants over a sliding window. Entries over quota are sampled or
+// dropped, and a notice is logged when the quota is exceeded and restored.
+func (l *Logger) SetQuota(name string, cfg QuotaConfig) {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	level := zapcore.ErrorLevel
+	if cfg.Level != nil {
+		level = *cfg.Level
+	}
+
+	l.quotas.mu.Lock()
+	defer l.quotas.mu.Unlock()
+
+	if l.quotas.quotas == nil {
+		l.quotas.quotas = map[string]*quota{}
+	}
+	l.quotas.quotas[name] = &quota{cfg: cfg, level: level, windowStart: time.Now()}
+}
+
+// ClearQuota removes the quota of the named logger
+func (l *Logger) ClearQuota(name string) {
+	l.quotas.mu.Lock()
+	defer l.quotas.mu.Unlock()
+
+	delete(l.quotas.quotas, name)
+}
+
+// entrySize estimates the encoded size of an entry for byte quotas
+func entrySize(msg string, fields map[string]interface{}) int {
+	size := len(msg)
+	for k, v := range fields {
+		size += len(k) + 4
+		if s, ok := v.(string); ok {
+			size += len(s)
+		} else {
+			size += 8
+		}
+	}
+	return size
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+)
+
+// RecoverAndLog recovers from a panic and logs it at Error level together
+// with the stack trace and the logger's context fields.
+// It must be called directly via defer:
+//
+//	defer logger.RecoverAndLog()
+func (l *Logger) RecoverAndLog() {
+	if r := recover(); r != nil {
+		l.logPanic(r, false)
+	}
+}
+
+// RecoverAndLogFatal is like RecoverAndLog but logs at Fatal level,
+// which terminates the process after the entry is written, and after
+// a crash report if they are enabled.
+func (l *Logger) RecoverAndLogFatal() {
+	if r := recover(); r != nil {
+		l.logPanic(r, true)
+	}
+}
+
+// Go runs fn in a new goroutine, logging any panic instead of crashing
+func (l *Logger) Go(fn func()) {
+	go func() {
+		defer l.RecoverAndLog()
+		fn()
+	}()
+}
+
+// logPanic logs a recovered panic value with its stack trace
+func (l *Logger) logPanic(r interface{}, fatal bool) {
+	fields := map[string]interface{}{
+		"panic": fmt.Sprint(r),
+	}
+	trace, ref := l.stacks.capture()
+	if trace != "" {
+		fields["stacktrace"] = trace
+	}
+	fields[StackRefKey] = ref
+	if err, ok := r.(error); ok {
+		fields["error"] = err
+	}
+
+	if fatal {
+		l.Fatal("Recovered from panic", fields)
+		return
+	}
+	l.Error("Recovered from panic", fields)
+}
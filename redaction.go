@@ -2,43 +2,130 @@ package main
 
 import (
 	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 type redaction struct {
+	name        string
 	regex       *regexp.Regexp
 	replacement string
+	// replace computes the replacement of each match instead
+	replace func(match string) string
+	// perMatch is set when the replacement references per-match metadata
+	perMatch bool
 }
 
-// redactMessage applies all registered redactions to a message
-func (l *Logger) redactMessage(message string) string {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
+// RedactionRule is a named redaction pattern. Its replacement is a
+// template that may reference capture groups as $1 or ${group}, and
+// metadata as ${pattern_name} and ${length}, the length of the match.
+// For example, "[REDACTED]@$1" keeps the domain of an email matched by
+// `[^@\s]+@([\w.-]+)`.
+type RedactionRule struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// AddRedactionRule adds a named redaction pattern with a replacement
+// template
+func (l *Logger) AddRedactionRule(rule RedactionRule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.redactions = append(l.redactions, newRedaction(rule.Name, rule.Pattern, rule.Replacement))
+	l.publishRedactor()
+}
+
+// newRedaction creates a named redaction from a RedactionRule template
+func newRedaction(name string, pattern *regexp.Regexp, template string) redaction {
+	// Resolve static metadata once, escaping it from group expansion
+	replacement := strings.ReplaceAll(template, "${pattern_name}", strings.ReplaceAll(name, "$", "$$"))
+
+	return redaction{
+		name:        name,
+		regex:       pattern,
+		replacement: replacement,
+		perMatch:    strings.Contains(replacement, "${length}"),
+	}
+}
 
-	redacted := message
-	for _, r := range l.redactions {
-		redacted = r.regex.ReplaceAllString(redacted, r.replacement)
+// ruleName returns the name of the redaction, or its pattern
+func (rd redaction) ruleName() string {
+	if rd.name != "" {
+		return rd.name
 	}
-	return redacted
+	return rd.regex.String()
+}
+
+// expand replaces every match of the redaction in s, resolving
+// per-match metadata in the replacement template
+func (rd redaction) expand(s string) string {
+	matches := rd.regex.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		template := strings.ReplaceAll(rd.replacement, "${length}", strconv.Itoa(m[1]-m[0]))
+		out = append(out, s[last:m[0]]...)
+		out = rd.regex.ExpandString(out, template, s, m)
+		last = m[1]
+	}
+	return string(append(out, s[last:]...))
 }
 
-// redactField redacts string values in fields if needed
+// redactMessage applies all registered redactions to a message
+func (l *Logger) redactMessage(message string) string {
+	return l.publishedRedactor().string(message)
+}
+
+// redactField applies key and regex redactions to a field
 func (l *Logger) redactField(field zapcore.Field) zapcore.Field {
-	if field.Type == zapcore.StringType {
-		// Get the string value
-		str := field.String
+	redacted, _ := l.publishedRedactor().field(field)
+	return redacted
+}
 
-		// Apply redactions
-		redacted := l.redactMessage(str)
+// publishedRedactor returns the published redaction rules merged with
+// the redaction policy, without locking. The merged rules are kept until
+// the rules or the policy change.
+func (l *Logger) publishedRedactor() redactor {
+	rules, policy := l.rules.Load(), l.policy.Load()
+	if merged := l.mergedRules.Load(); merged != nil && merged.rules == rules && merged.policy == policy {
+		return merged.redactor
+	}
 
-		// Replace the field if it changed
-		if redacted != str {
-			return zap.String(field.Key, redacted)
-		}
+	merged := &mergedRules{rules: rules, policy: policy, redactor: *rules}
+	if policy != nil {
+		merged.redactor = rules.merge(policy.rules)
 	}
-	return field
+	l.mergedRules.Store(merged)
+	return merged.redactor
+}
+
+// mergedRules are published redaction rules merged with a policy
+type mergedRules struct {
+	rules    *redactor
+	policy   *appliedPolicy
+	redactor redactor
+}
+
+// rulesGeneration counts changes to the redaction rules and key mappings
+// of all loggers, so that context fields baked into handlers are
+// redacted again
+var rulesGeneration atomic.Uint64
+
+// publishRedactor makes the logger's current redaction rules available
+// to its handlers without locking. The caller must hold the lock.
+func (l *Logger) publishRedactor() {
+	r := l.redactor()
+	l.rules.Store(&r)
+	rulesGeneration.Add(1)
 }
 
 // AddRedaction adds a new redaction pattern
@@ -50,37 +137,22 @@ func (l *Logger) AddRedaction(pattern *regexp.Regexp, replacement string) {
 		regex:       pattern,
 		replacement: replacement,
 	})
+	l.publishRedactor()
 }
 
-// fieldRedactingCore redacts specific field keys
-type fieldRedactingCore struct {
-	zapcore.Core
-	redactKeys map[string]struct{}
-}
+// AddRedactFields adds field keys whose values are always redacted
+func (l *Logger) AddRedactFields(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-func (f *fieldRedactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
-	redactedFields := make([]zapcore.Field, 0, len(fields))
-	for _, field := range fields {
-		if _, ok := f.redactKeys[field.Key]; ok && field.Type == zapcore.StringType {
-			redactedFields = append(redactedFields, zap.String(field.Key, "***REDACTED***"))
-		} else {
-			redactedFields = append(redactedFields, field)
-		}
+	// Copy on write since the map is shared with child loggers
+	redactKeys := make(map[string]struct{}, len(l.redactKeys)+len(keys))
+	for k := range l.redactKeys {
+		redactKeys[k] = struct{}{}
 	}
-	return f.Core.Write(ent, redactedFields)
-}
-
-func createFieldRedactorCore(logger *Logger, keys []string) zapcore.Core {
-	keyMap := make(map[string]struct{}, len(keys))
 	for _, k := range keys {
-		keyMap[k] = struct{}{}
-	}
-
-	// Wrap a no-op core; it just intercepts logs to redact
-	// In practice, this should wrap real cores, but for this example, we intercept via redacting layer
-	noopCore := zapcore.NewNopCore()
-	return &fieldRedactingCore{
-		Core:       noopCore,
-		redactKeys: keyMap,
+		redactKeys[k] = struct{}{}
 	}
+	l.redactKeys = redactKeys
+	l.publishRedactor()
 }
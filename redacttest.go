@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RedactionSample is an input with the output expected after redaction
+type RedactionSample struct {
+	Name  string
+	Input string
+	Want  string
+}
+
+// RedactionFailure is a sample whose redacted output differs from Want
+type RedactionFailure struct {
+	Sample RedactionSample
+	Got    string
+}
+
+// RedactionOverlap reports two rules matching overlapping text of a
+// sample, where the first applied rule changes what the second sees
+type RedactionOverlap struct {
+	Sample string
+	Rules  [2]string
+	Text   string
+}
+
+// RedactionReport is the outcome of CheckRedactions
+type RedactionReport struct {
+	Failures []RedactionFailure
+	// Unmatched are samples expected to change that no rule matched
+	Unmatched []RedactionSample
+	Overlaps  []RedactionOverlap
+}
+
+// OK reports whether every sample passed without overlapping rules
+func (r RedactionReport) OK() bool {
+	return len(r.Failures) == 0 && len(r.Unmatched) == 0 && len(r.Overlaps) == 0
+}
+
+// String formats the report for test output
+func (r RedactionReport) String() string {
+	var b strings.Builder
+	for _, s := range r.Unmatched {
+		fmt.Fprintf(&b, "sample %q: no rule matched %q\n", s.Name, s.Input)
+	}
+	for _, f := range r.Failures {
+		fmt.Fprintf(&b, "sample %q: got %q, want %q\n", f.Sample.Name, f.Got, f.Sample.Want)
+	}
+	for _, o := range r.Overlaps {
+		fmt.Fprintf(&b, "sample %q: rules %q and %q both match %q\n", o.Sample, o.Rules[0], o.Rules[1], o.Text)
+	}
+	return b.String()
+}
+
+// CheckRedactions runs samples through the logger's redaction rules, so
+// teams can test their patterns in CI:
+//
+//	if report := logger.CheckRedactions(samples...); !report.OK() {
+//		t.Error(report)
+//	}
+func (l *Logger) CheckRedactions(samples ...RedactionSample) RedactionReport {
+	l.mu.RLock()
+	r := l.redactor()
+	l.mu.RUnlock()
+
+	var report RedactionReport
+	for _, sample := range samples {
+		if sample.Name == "" {
+			sample.Name = sample.Input
+		}
+
+		got := r.string(sample.Input)
+		switch {
+		case got == sample.Input && sample.Want != sample.Input:
+			report.Unmatched = append(report.Unmatched, sample)
+		case got != sample.Want:
+			report.Failures = append(report.Failures, RedactionFailure{Sample: sample, Got: got})
+		}
+
+		report.Overlaps = append(report.Overlaps, r.overlaps(sample)...)
+	}
+	return report
+}
+
+// overlaps finds pairs of rules matching overlapping text of the sample
+func (r redactor) overlaps(sample RedactionSample) []RedactionOverlap {
+	matches := make([][][]int, len(r.redactions))
+	for i, rd := range r.redactions {
+		matches[i] = rd.regex.FindAllStringIndex(sample.Input, -1)
+	}
+
+	var overlaps []RedactionOverlap
+	for i := range r.redactions {
+		for j := i + 1; j < len(r.redactions); j++ {
+			if a, b, ok := firstOverlap(matches[i], matches[j]); ok {
+				overlaps = append(overlaps, RedactionOverlap{
+					Sample: sample.Name,
+					Rules:  [2]string{r.redactions[i].ruleName(), r.redactions[j].ruleName()},
+					Text:   sample.Input[min(a[0], b[0]):max(a[1], b[1])],
+				})
+			}
+		}
+	}
+	return overlaps
+}
+
+// firstOverlap returns the first pair of intersecting match ranges
+func firstOverlap(a, b [][]int) ([]int, []int, bool) {
+	for _, x := range a {
+		for _, y := range b {
+			if x[0] < y[1] && y[0] < x[1] {
+				return x, y, true
+			}
+		}
+	}
+	return nil, nil, false
+}
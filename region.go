@@ -0,0 +1,81 @@
+package main
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// RegionKey is the field key tagging entries with their data residency
+// region
+const RegionKey = "region"
+
+// regionRules maps regions to the handlers allowed to receive their
+// entries. Entries of regions without a route are dropped unless strict
+// is unset. It is shared by a logger and its children.
+type regionRules struct {
+	routes map[string]map[string]struct{}
+	strict bool
+	mu     sync.RWMutex
+}
+
+// accepts returns a filter reporting whether the handler takes entries
+// of a region
+func (r *regionRules) accepts(handler *handlerState) func(region string) bool {
+	return func(region string) bool {
+		if region == "" {
+			return true
+		}
+
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		handlers, ok := r.routes[region]
+		if !ok {
+			return !r.strict
+		}
+		_, ok = handlers[handler.name]
+		return ok
+	}
+}
+
+// WithRegion creates a new logger tagging entries with a data residency
+// region, so they are only written to the handlers routed for it. Entries
+// of a region without a route are dropped, see RequireRegionRoutes.
+func (l *Logger) WithRegion(region string) *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	regionLogger := l.clone()
+	regionLogger.Logger = l.Logger.With(zap.String(l.mapKey(RegionKey), region))
+	return regionLogger
+}
+
+// RouteRegion restricts entries tagged with region to the named
+// handlers, e.g. RouteRegion("eu", "splunk-eu"). Entries may also be
+// tagged by logging a RegionKey field.
+func (l *Logger) RouteRegion(region string, handlers ...string) {
+	l.regions.mu.Lock()
+	defer l.regions.mu.Unlock()
+
+	if l.regions.routes == nil {
+		l.regions.routes = map[string]map[string]struct{}{}
+	}
+	routed := l.regions.routes[region]
+	if routed == nil {
+		routed = map[string]struct{}{}
+		l.regions.routes[region] = routed
+	}
+	for _, name := range handlers {
+		routed[name] = struct{}{}
+	}
+}
+
+// RequireRegionRoutes sets whether entries tagged with a region that has
+// no route are dropped, the default, or written to every handler
+func (l *Logger) RequireRegionRoutes(strict bool) {
+	l.regions.mu.Lock()
+	defer l.regions.mu.Unlock()
+
+	l.regions.strict = strict
+}
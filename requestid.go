@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// RequestIDKey is the context field used to store request IDs
+const RequestIDKey = "request_id"
+
+// NewRequestID generates a time-ordered UUIDv7 suitable for request
+// and correlation IDs
+func NewRequestID() string {
+	var u [16]byte
+
+	// 48-bit big-endian Unix timestamp in milliseconds
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(time.Now().UnixMilli()))
+	copy(u[0:6], ts[2:8])
+
+	// Random bits for the remainder
+	if _, err := rand.Read(u[6:]); err != nil {
+		panic("failed to generate request ID: " + err.Error())
+	}
+
+	// Set version (7) and variant (RFC 4122)
+	u[6] = (u[6] & 0x0f) | 0x70
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], u[10:])
+	return string(buf[:])
+}
+
+// WithRequestID creates a new logger carrying the given request ID.
+// A new ID is generated when id is empty. Child loggers inherit it.
+func (l *Logger) WithRequestID(id string) *Logger {
+	if id == "" {
+		id = NewRequestID()
+	}
+
+	requestLogger := l.WithContext(map[string]interface{}{
+		RequestIDKey: id,
+	})
+	requestLogger.requestID = id
+	return requestLogger
+}
+
+// RequestID returns the request ID attached to the logger, if any
+func (l *Logger) RequestID() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.requestID
+}
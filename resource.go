@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// AddResource attaches the attributes of an OpenTelemetry resource, such
+// as service.name, service.version and deployment.environment, to every
+// entry of this logger and loggers derived from it afterwards, so all
+// handlers describe the emitting service the same way as its traces and
+// metrics. Any value whose String method renders attributes as
+// "key=value,key=value" with backslash escapes, like *resource.Resource,
+// is accepted, so this module needn't depend on the OpenTelemetry SDK.
+func (l *Logger) AddResource(res fmt.Stringer) error {
+	if res == nil {
+		return nil
+	}
+	attrs, err := parseResourceAttributes(res.String())
+	if err != nil {
+		return err
+	}
+
+	fields := make([]zap.Field, 0, len(attrs))
+	for _, kv := range attrs {
+		fields = append(fields, zap.String(kv[0], kv[1]))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.context = append(l.context, fields...)
+	return nil
+}
+
+// parseResourceAttributes splits an encoded attribute set into its keys
+// and values, in order
+func parseResourceAttributes(s string) ([][2]string, error) {
+	var attrs [][2]string
+	var key, value strings.Builder
+	current := &key
+	inValue := false
+
+	flush := func() error {
+		if !inValue {
+			if key.Len() == 0 {
+				return nil
+			}
+			return fmt.Errorf("resource attribute %q has no value", key.String())
+		}
+		attrs = append(attrs, [2]string{key.String(), value.String()})
+		key.Reset()
+		value.Reset()
+		current, inValue = &key, false
+		return nil
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s):
+			i++
+			current.WriteByte(s[i])
+		case c == '=' && !inValue:
+			current, inValue = &value, true
+		case c == ',':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import "go.uber.org/zap/zapcore"
+
+// routingCore passes entries to a handler according to the value of a
+// routing field such as the tenant or region, found in fields baked in
+// with With or in the entry's fields. The field is looked up under its
+// key and under the name the key is currently mapped to.
+type routingCore struct {
+	zapcore.Core
+	key    string
+	mapKey func(key string) string
+	value  string
+	accept func(value string) bool
+}
+
+// With implements zapcore.Core
+func (r *routingCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *r
+	clone.Core = r.Core.With(fields)
+	if value, ok := mappedStringField(fields, r.key, r.mapKey); ok {
+		clone.value = value
+	}
+	return &clone
+}
+
+// Check implements zapcore.Core
+func (r *routingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if r.Enabled(ent.Level) {
+		return ce.AddCore(ent, r)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (r *routingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	value := r.value
+	if v, ok := mappedStringField(fields, r.key, r.mapKey); ok {
+		value = v
+	}
+	if !r.accept(value) {
+		return nil
+	}
+	return r.Core.Write(ent, fields)
+}
+
+// stringField returns the value of the string field with the given key
+func stringField(fields []zapcore.Field, key string) (string, bool) {
+	for _, f := range fields {
+		if f.Key == key && f.Type == zapcore.StringType {
+			return f.String, true
+		}
+	}
+	return "", false
+}
+
+// mappedStringField returns the value of the string field with the given
+// key, or with the name mapKey currently maps it to
+func mappedStringField(fields []zapcore.Field, key string, mapKey func(string) string) (string, bool) {
+	if mapped := mapKey(key); mapped != key {
+		if value, ok := stringField(fields, mapped); ok {
+			return value, true
+		}
+	}
+	return stringField(fields, key)
+}
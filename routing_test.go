@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestTenantRoutingFollowsLaterKeyMapping(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	shared := observe(l, zapcore.InfoLevel)
+	dedicated := observe(l, zapcore.InfoLevel, ForTenants("acme"))
+	l.IsolateTenants("acme")
+
+	// Map the tenant key after the handlers captured their routing
+	l.SetKeyMapping(map[string]string{TenantKey: "tenant"})
+	l.WithTenant("acme").Info("isolated")
+
+	if shared.Len() != 0 {
+		t.Errorf("shared handler got %d isolated entries", shared.Len())
+	}
+	entries := dedicated.All()
+	if len(entries) != 1 {
+		t.Fatalf("dedicated handler got %d entries, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["tenant"]; got != "acme" {
+		t.Errorf("tenant = %v, want mapped key", got)
+	}
+}
+
+func TestRegionRoutingFollowsLaterKeyMapping(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	eu := observe(l, zapcore.InfoLevel, WithName("eu"))
+	us := observe(l, zapcore.InfoLevel, WithName("us"))
+	l.RouteRegion("eu", "eu")
+
+	l.SetKeyMapping(map[string]string{RegionKey: "geo"})
+	l.WithRegion("eu").Info("regional")
+
+	if us.Len() != 0 {
+		t.Errorf("us handler got %d eu entries", us.Len())
+	}
+	if eu.Len() != 1 {
+		t.Errorf("eu handler got %d entries, want 1", eu.Len())
+	}
+}
+
+func TestUnroutedRegionIsDropped(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	eu := observe(l, zapcore.InfoLevel, WithName("eu"))
+	l.RouteRegion("eu", "eu")
+
+	l.WithRegion("us").Info("unrouted")
+	if eu.Len() != 0 {
+		t.Errorf("eu handler got %d unrouted entries", eu.Len())
+	}
+
+	l.RequireRegionRoutes(false)
+	l.WithRegion("us").Info("unrouted")
+	if eu.Len() != 1 {
+		t.Errorf("eu handler got %d entries, want 1 once routes are optional", eu.Len())
+	}
+}
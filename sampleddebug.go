@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// EnableSampledDebug writes Debug entries only in the scope of a sampled
+// trace, whatever the configured level, giving detailed logs for exactly
+// the requests that also have traces at a fraction of the volume. Debug
+// entries must be logged with DebugContext to be considered. sampled
+// reports whether the trace of a context is sampled; nil uses the
+// traceparent stored by HTTPMiddleware. With OpenTelemetry:
+//
+//	l.EnableSampledDebug(func(ctx context.Context) bool {
+//		return trace.SpanContextFromContext(ctx).IsSampled()
+//	})
+//
+// Loggers whose level was lowered with WithLevel keep their Debug entries.
+// Sampled Debug entries also reach handlers accepting Info, so a Debug
+// handler isn't needed.
+func (l *Logger) EnableSampledDebug(sampled func(ctx context.Context) bool) {
+	if sampled == nil {
+		sampled = func(ctx context.Context) bool {
+			tc, ok := TraceContextFromContext(ctx)
+			return ok && tc.Sampled()
+		}
+	}
+	l.sampledDebug.Store(&sampled)
+}
+
+// DisableSampledDebug filters Debug entries by level again
+func (l *Logger) DisableSampledDebug() {
+	l.sampledDebug.Store(nil)
+}
+
+// enabledIn reports whether an entry at level is written in the scope
+// of ctx, and whether it was raised: admitted by a debug token or a
+// sampled trace rather than by level
+func (l *Logger) enabledIn(ctx context.Context, level LogLevel) (enabled, raised bool) {
+	if level == zapcore.DebugLevel {
+		if l.debugToken {
+			return true, true
+		}
+		if sampled := l.sampledDebug.Load(); sampled != nil {
+			if l.levelOverride != nil && *l.levelOverride <= level {
+				return true, false
+			}
+			enabled = ctx != nil && (*sampled)(ctx)
+			return enabled, enabled
+		}
+	}
+	return l.enabled(level), false
+}
+
+// checkRaised prepares a raised Debug entry for the handlers accepting
+// Info, which would otherwise filter it. Handlers reserved for warnings
+// and errors, such as alerting, still don't receive it.
+func (l *Logger) checkRaised(msg string) *zapcore.CheckedEntry {
+	core, ok := l.Logger.Core().(raisingCore)
+	if !ok || !core.Enabled(zapcore.InfoLevel) {
+		return nil
+	}
+	ent := zapcore.Entry{
+		LoggerName: l.Logger.Name(),
+		Time:       time.Now(),
+		Level:      zapcore.DebugLevel,
+		Message:    msg,
+	}
+	return (*zapcore.CheckedEntry)(nil).AddCore(ent, raisedCore{core})
+}
+
+// raisingCore writes entries to the handlers accepting a level other
+// than the entry's own
+type raisingCore interface {
+	zapcore.Core
+	writeAt(lvl zapcore.Level, ent zapcore.Entry, fields []zapcore.Field) error
+}
+
+// raisedCore writes Debug entries to the handlers accepting Info
+type raisedCore struct {
+	raisingCore
+}
+
+// Write implements zapcore.Core
+func (r raisedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return r.writeAt(zapcore.InfoLevel, ent, fields)
+}
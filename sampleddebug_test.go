@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSampledDebugReachesInfoHandlers(t *testing.T) {
+	l := NewLogger("worker", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+	l.EnableSampledDebug(nil)
+
+	sampled := ContextWithTraceContext(context.Background(), TraceContext{TraceFlags: "01"})
+	unsampled := ContextWithTraceContext(context.Background(), TraceContext{TraceFlags: "00"})
+
+	l.DebugContext(unsampled, "unsampled")
+	l.DebugContext(sampled, "sampled")
+	l.Debug("no trace")
+
+	if n := logs.Len(); n != 1 {
+		t.Fatalf("got %d entries, want only the sampled one", n)
+	}
+	if entry := logs.All()[0]; entry.Message != "sampled" || entry.Level != zapcore.DebugLevel {
+		t.Errorf("got %q at %v, want sampled Debug entry", entry.Message, entry.Level)
+	}
+}
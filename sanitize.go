@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// newlineEscaper escapes line breaks as in Go string literals
+var newlineEscaper = strings.NewReplacer("\n", `\n`, "\r", `\r`)
+
+// sanitizer neutralizes untrusted text in messages and string values.
+// It is shared by a logger and its children.
+type sanitizer struct {
+	control  atomic.Bool
+	newlines atomic.Bool
+}
+
+// EnableSanitization strips ANSI escape sequences from messages and
+// string values and escapes other control characters, Unicode
+// direction overrides and invalid UTF-8, so untrusted input can't inject
+// terminal commands or disguise log content. Tabs and newlines are kept,
+// see EscapeNewlines.
+func (l *Logger) EnableSanitization(enabled bool) {
+	l.sanitizer.control.Store(enabled)
+	rulesGeneration.Add(1)
+}
+
+// EscapeNewlines replaces newlines and carriage returns in messages and
+// string values with the two-character escapes \n and \r, so untrusted
+// input can't forge additional entries in line-oriented output
+func (l *Logger) EscapeNewlines(enabled bool) {
+	l.sanitizer.newlines.Store(enabled)
+	rulesGeneration.Add(1)
+}
+
+// active reports whether the sanitizer changes any text
+func (s *sanitizer) active() bool {
+	return s != nil && (s.control.Load() || s.newlines.Load())
+}
+
+// apply returns s with unsafe characters neutralized
+func (s *sanitizer) apply(str string) string {
+	if s == nil {
+		return str
+	}
+	control, newlines := s.control.Load(), s.newlines.Load()
+	if !needsSanitizing(str, control, newlines) {
+		return str
+	}
+
+	if !control {
+		return newlineEscaper.Replace(str)
+	}
+
+	var b strings.Builder
+	b.Grow(len(str))
+	for i := 0; i < len(str); {
+		if str[i] == 0x1b {
+			i += ansiSequenceLen(str[i:])
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(str[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case newlines && (r == '\n' || r == '\r'):
+			b.WriteString(newlineEscaper.Replace(string(r)))
+		case r == '\t' || r == '\n' || r == '\r':
+			b.WriteByte(byte(r))
+		case unsafeRune(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteString(str[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// needsSanitizing reports whether str contains anything apply changes
+func needsSanitizing(str string, control, newlines bool) bool {
+	if !control {
+		return newlines && strings.ContainsAny(str, "\n\r")
+	}
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if newlines && (c == '\n' || c == '\r') {
+			return true
+		}
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' || c == 0x7f {
+			return true
+		}
+		if c >= utf8.RuneSelf {
+			// Check the remainder rune by rune
+			for _, r := range str[i:] {
+				if r == utf8.RuneError || newlines && (r == '\n' || r == '\r') || r < 0x20 && r != '\t' && r != '\n' && r != '\r' || unsafeRune(r) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// unsafeRune reports whether r is a control character or a Unicode
+// direction control that can reorder displayed text
+func unsafeRune(r rune) bool {
+	switch {
+	case r < 0x20, r == 0x7f, r >= 0x80 && r < 0xa0:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		return true
+	}
+	return false
+}
+
+// ansiSequenceLen returns the length of the escape sequence starting at
+// s[0], which is ESC: CSI sequences like "\x1b[31m", OSC sequences ended
+// by BEL or ST, or a single escaped character
+func ansiSequenceLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+
+	switch s[1] {
+	case '[':
+		// Parameter and intermediate bytes, then a final byte
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+			if s[i] < 0x20 || s[i] > 0x7e {
+				return i
+			}
+		}
+		return len(s)
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == 0x07 {
+				return i + 1
+			}
+			if s[i] == 0x1b && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	}
+	if s[1] >= utf8.RuneSelf {
+		return 1
+	}
+	return 2
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FieldType is the expected type of a field in a Schema
+type FieldType string
+
+// Supported field types
+const (
+	FieldAny      FieldType = "any"
+	FieldString   FieldType = "string"
+	FieldInt      FieldType = "int"
+	FieldFloat    FieldType = "float"
+	FieldBool     FieldType = "bool"
+	FieldTime     FieldType = "time"
+	FieldDuration FieldType = "duration"
+	FieldError    FieldType = "error"
+)
+
+// FieldSpec describes a single field in a Schema
+type FieldSpec struct {
+	Type     FieldType
+	Required bool
+}
+
+// Schema maps field names to their expected type
+type Schema map[string]FieldSpec
+
+// Validate checks fields against the schema and returns all violations
+func (s Schema) Validate(fields map[string]interface{}) error {
+	keys := make([]string, 0, len(s))
+	for key := range s {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		spec := s[key]
+		value, ok := fields[key]
+		if !ok {
+			if spec.Required {
+				errs = append(errs, fmt.Errorf("missing required field %q", key))
+			}
+			continue
+		}
+		if !spec.Type.matches(value) {
+			errs = append(errs, fmt.Errorf("field %q has type %T, expected %s", key, value, spec.Type))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// matches reports whether value is of the field type
+func (t FieldType) matches(value interface{}) bool {
+	switch t {
+	case FieldAny, "":
+		return true
+	case FieldTime:
+		_, ok := value.(time.Time)
+		return ok
+	case FieldDuration:
+		_, ok := value.(time.Duration)
+		return ok
+	case FieldError:
+		_, ok := value.(error)
+		return ok
+	}
+
+	if _, ok := value.(time.Duration); ok {
+		return false
+	}
+
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return t == FieldString
+	case reflect.Bool:
+		return t == FieldBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return t == FieldInt
+	case reflect.Float32, reflect.Float64:
+		return t == FieldFloat
+	}
+	return false
+}
+
+// schemaRules holds per-logger schemas and the strict-mode flag.
+// It is shared by a logger and its children.
+type schemaRules struct {
+	schemas map[string]Schema
+	strict  bool
+	mu      sync.RWMutex
+}
+
+// SetSchema registers a field schema for entries logged by this logger's name
+func (l *Logger) SetSchema(schema Schema) {
+	l.schemas.mu.Lock()
+	defer l.schemas.mu.Unlock()
+
+	l.schemas.schemas[l.name] = schema
+}
+
+// SetStrictSchema makes schema violations panic instead of being
+// reported through the internal error handler
+func (l *Logger) SetStrictSchema(strict bool) {
+	l.schemas.mu.Lock()
+	defer l.schemas.mu.Unlock()
+
+	l.schemas.strict = strict
+}
+
+// validateSchema checks fields against schema, reporting or panicking
+// on violations
+func (l *Logger) validateSchema(schema Schema, subject string, fields map[string]interface{}) {
+	if schema == nil {
+		return
+	}
+
+	err := schema.Validate(fields)
+	if err == nil {
+		return
+	}
+	err = fmt.Errorf("schema violation in %s: %w", subject, err)
+
+	l.schemas.mu.RLock()
+	strict := l.schemas.strict
+	l.schemas.mu.RUnlock()
+
+	if strict {
+		panic(err)
+	}
+	l.internalErrors.report(err)
+}
+
+// loggerSchema returns the schema registered for the logger's name
+func (l *Logger) loggerSchema() Schema {
+	l.schemas.mu.RLock()
+	defer l.schemas.mu.RUnlock()
+
+	return l.schemas.schemas[l.name]
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ScrubOptions configures ScrubFile
+type ScrubOptions struct {
+	// Values are literals to erase in addition to the logger's redaction
+	// rules, such as the ID and email of a user requesting deletion
+	Values []string
+	// DeleteEntries drops entries containing any of Values instead of
+	// masking them
+	DeleteEntries bool
+}
+
+// ScrubResult reports the outcome of scrubbing a file
+type ScrubResult struct {
+	Entries int
+	Changed int
+	Deleted int
+}
+
+// ScrubFile rewrites an existing log file, applying the logger's current
+// redaction rules and opts to every entry, e.g. to honour data deletion
+// requests for rotated logs. JSON entries keep their key order; other
+// lines are redacted as text. Files ending in .gz are read and written
+// compressed. The file is replaced atomically.
+func (l *Logger) ScrubFile(path string, opts ScrubOptions) (ScrubResult, error) {
+	l.mu.RLock()
+	r := l.redactor()
+	l.mu.RUnlock()
+
+	// Erase the requested values like any other redaction
+	var values *regexp.Regexp
+	if len(opts.Values) > 0 {
+		quoted := make([]string, len(opts.Values))
+		for i, v := range opts.Values {
+			quoted[i] = regexp.QuoteMeta(v)
+		}
+		values = regexp.MustCompile(strings.Join(quoted, "|"))
+		r.redactions = append(append([]redaction{}, r.redactions...), redaction{regex: values, replacement: RedactedValue})
+	}
+
+	var result ScrubResult
+	err := rewriteFile(path, func(in io.Reader, out io.Writer) error {
+		scanner := bufio.NewScanner(in)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			result.Entries++
+
+			if opts.DeleteEntries && values != nil && values.Match(line) {
+				result.Deleted++
+				continue
+			}
+
+			scrubbed, err := scrubLine(line, r)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", result.Entries, err)
+			}
+			if !bytes.Equal(scrubbed, line) {
+				result.Changed++
+			}
+			if _, err := out.Write(append(scrubbed, '\n')); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	})
+	return result, err
+}
+
+// rewriteFile streams path through fn into a temporary file that then
+// replaces it, handling gzip compression
+func rewriteFile(path string, fn func(in io.Reader, out io.Writer) error) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".scrub-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var in io.Reader = src
+	var out io.Writer = tmp
+	var zw *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		in = zr
+		zw = gzip.NewWriter(tmp)
+		out = zw
+	}
+
+	buffered := bufio.NewWriter(out)
+	if err := fn(in, buffered); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// scrubLine redacts a JSON entry value by value, or any other line as text
+func scrubLine(line []byte, r redactor) ([]byte, error) {
+	trimmed := bytes.TrimSpace(line)
+	if len(trimmed) == 0 || trimmed[0] != '{' || !json.Valid(trimmed) {
+		return []byte(r.string(string(line))), nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	var buf bytes.Buffer
+	if err := scrubJSONValue(dec, &buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scrubJSONValue copies the next JSON value from dec to buf, preserving
+// key order while redacting keys, string values and numbers
+func scrubJSONValue(dec *json.Decoder, buf *bytes.Buffer, r redactor) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			buf.WriteByte('{')
+			for i := 0; dec.More(); i++ {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key := keyTok.(string)
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				writeJSONString(buf, key)
+				buf.WriteByte(':')
+
+				if r.redactsKey(key) {
+					var skipped json.RawMessage
+					if err := dec.Decode(&skipped); err != nil {
+						return err
+					}
+					writeJSONString(buf, RedactedValue)
+					continue
+				}
+				if err := scrubJSONValue(dec, buf, r); err != nil {
+					return err
+				}
+			}
+			buf.WriteByte('}')
+		case '[':
+			buf.WriteByte('[')
+			for i := 0; dec.More(); i++ {
+				if i > 0 {
+					buf.WriteByte(',')
+				}
+				if err := scrubJSONValue(dec, buf, r); err != nil {
+					return err
+				}
+			}
+			buf.WriteByte(']')
+		}
+		// Consume the closing delimiter
+		_, err := dec.Token()
+		return err
+	case string:
+		writeJSONString(buf, r.string(t))
+	case json.Number:
+		// Numbers such as card numbers can match rules too; redacted
+		// numbers become strings
+		if scrubbed := r.string(t.String()); scrubbed != t.String() {
+			writeJSONString(buf, scrubbed)
+		} else {
+			buf.WriteString(t.String())
+		}
+	case bool:
+		fmt.Fprint(buf, t)
+	case nil:
+		buf.WriteString("null")
+	}
+	return nil
+}
+
+// writeJSONString writes s as a JSON string without HTML escaping,
+// matching the zap JSON encoder
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestScrubFileRedactsNumbers(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedaction(regexp.MustCompile(`^4\d{15}$`), "[CARD]")
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	entry := `{"msg":"charged","card":4111111111111111,"amount":42,"items":[4111111111111111]}` + "\n"
+	if err := os.WriteFile(path, []byte(entry), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := l.ScrubFile(path, ScrubOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Changed != 1 {
+		t.Errorf("changed %d entries, want 1", result.Changed)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"msg":"charged","card":"[CARD]","amount":42,"items":["[CARD]"]}`
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("scrubbed entry = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MinSecretLength is the length below which secret values are ignored,
+// since redacting short literals would mangle unrelated text
+const MinSecretLength = 6
+
+// SecretProvider fetches secret values such as API keys and tokens that
+// must never appear in logs, e.g. from Vault or AWS Secrets Manager
+type SecretProvider interface {
+	FetchSecrets(ctx context.Context) ([]string, error)
+}
+
+// SecretProviderFunc adapts a function to the SecretProvider interface
+type SecretProviderFunc func(ctx context.Context) ([]string, error)
+
+// FetchSecrets implements SecretProvider
+func (f SecretProviderFunc) FetchSecrets(ctx context.Context) ([]string, error) {
+	return f(ctx)
+}
+
+// EnvSecrets returns a provider reading secrets from the named
+// environment variables
+func EnvSecrets(names ...string) SecretProvider {
+	return SecretProviderFunc(func(ctx context.Context) ([]string, error) {
+		secrets := make([]string, 0, len(names))
+		for _, name := range names {
+			if value := os.Getenv(name); value != "" {
+				secrets = append(secrets, value)
+			}
+		}
+		return secrets, nil
+	})
+}
+
+// secretSet holds the secret values of every source and redacts their
+// occurrences. It is shared by a logger and its children.
+type secretSet struct {
+	sources  map[int][]string
+	nextID   int
+	replacer atomic.Pointer[strings.Replacer]
+	mu       sync.Mutex
+}
+
+// register reserves a source ID
+func (s *secretSet) register() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	return s.nextID
+}
+
+// set replaces the secrets of a source
+func (s *secretSet) set(id int, secrets []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer rulesGeneration.Add(1)
+
+	if s.sources == nil {
+		s.sources = map[int][]string{}
+	}
+	s.sources[id] = secrets
+
+	// Longer secrets first, so a secret containing another is fully replaced
+	var all []string
+	for _, values := range s.sources {
+		for _, value := range values {
+			if len(value) >= MinSecretLength {
+				all = append(all, value)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return len(all[i]) > len(all[j]) })
+
+	if len(all) == 0 {
+		s.replacer.Store(nil)
+		return
+	}
+	pairs := make([]string, 0, 2*len(all))
+	for _, value := range all {
+		pairs = append(pairs, value, RedactedValue)
+	}
+	s.replacer.Store(strings.NewReplacer(pairs...))
+}
+
+// replace redacts every known secret in str
+func (s *secretSet) replace(str string) string {
+	if s == nil {
+		return str
+	}
+	if replacer := s.replacer.Load(); replacer != nil {
+		return replacer.Replace(str)
+	}
+	return str
+}
+
+// empty reports whether there are no secrets to redact
+func (s *secretSet) empty() bool {
+	return s == nil || s.replacer.Load() == nil
+}
+
+// AddSecrets redacts every occurrence of the given values in messages
+// and fields of the logger and its relatives. Values shorter than
+// MinSecretLength are ignored.
+func (l *Logger) AddSecrets(secrets ...string) {
+	l.secrets.set(l.secrets.register(), secrets)
+}
+
+// WatchSecrets fetches secrets from provider and redacts them until ctx
+// is done, refreshing them every interval so rotated secrets are
+// covered. Provider errors keep the last fetched secrets.
+func (l *Logger) WatchSecrets(ctx context.Context, provider SecretProvider, interval time.Duration) error {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	id := l.secrets.register()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		secrets, err := provider.FetchSecrets(ctx)
+		if err != nil {
+			l.Warn("Secret provider failed, keeping current secrets", map[string]interface{}{
+				"error": err.Error(),
+			})
+		} else {
+			l.secrets.set(id, secrets)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
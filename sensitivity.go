@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sensitivity classifies how sensitive a field's value is
+type Sensitivity int
+
+const (
+	// SensitivityPublic values may be logged anywhere. Unclassified
+	// fields are public.
+	SensitivityPublic Sensitivity = iota
+	// SensitivityInternal values must stay within the organisation
+	SensitivityInternal
+	// SensitivityPII values identify a person
+	SensitivityPII
+	// SensitivitySecret values are credentials or keys
+	SensitivitySecret
+)
+
+// String returns the lowercase name of the class
+func (s Sensitivity) String() string {
+	switch s {
+	case SensitivityPublic:
+		return "public"
+	case SensitivityInternal:
+		return "internal"
+	case SensitivityPII:
+		return "pii"
+	case SensitivitySecret:
+		return "secret"
+	}
+	return fmt.Sprintf("Sensitivity(%d)", int(s))
+}
+
+// ParseSensitivity parses a class name such as "pii"
+func ParseSensitivity(name string) (Sensitivity, error) {
+	for s := SensitivityPublic; s <= SensitivitySecret; s++ {
+		if strings.EqualFold(name, s.String()) {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sensitivity %q", name)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Sensitivity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler for config files
+func (s *Sensitivity) UnmarshalText(text []byte) error {
+	parsed, err := ParseSensitivity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
+
+// fieldClasses maps field keys to their sensitivity. The map is copied
+// on write. It is shared by a logger and its children.
+type fieldClasses struct {
+	classes atomic.Pointer[map[string]Sensitivity]
+	// policy holds the classes of the redaction policy
+	policy *atomic.Pointer[appliedPolicy]
+	mu     sync.Mutex
+}
+
+// lookup returns the class of a field key, the higher one if both code
+// and the redaction policy classify it
+func (c *fieldClasses) lookup(key string) Sensitivity {
+	class := SensitivityPublic
+	if classes := c.classes.Load(); classes != nil {
+		class = (*classes)[key]
+	}
+	if policy := c.policy.Load(); policy != nil && policy.classes[key] > class {
+		class = policy.classes[key]
+	}
+	return class
+}
+
+// set classifies field keys
+func (c *fieldClasses) set(class Sensitivity, keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	classes := map[string]Sensitivity{}
+	if current := c.classes.Load(); current != nil {
+		for k, v := range *current {
+			classes[k] = v
+		}
+	}
+	for _, k := range keys {
+		classes[k] = class
+	}
+	c.classes.Store(&classes)
+}
+
+// ClassifyFields declares the sensitivity of field keys
+func (l *Logger) ClassifyFields(class Sensitivity, keys ...string) {
+	l.classes.set(class, keys...)
+}
+
+// ClassifyStruct declares field sensitivities from the struct tags of v,
+// e.g. `json:"email" sensitivity:"pii"`. Keys are taken from the json
+// tag, or the field name.
+func (l *Logger) ClassifyStruct(v interface{}) error {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return fmt.Errorf("ClassifyStruct needs a struct, got %T", v)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup("sensitivity")
+		if !ok {
+			continue
+		}
+		class, err := ParseSensitivity(tag)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		key := field.Name
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+			key = name
+		}
+		l.classes.set(class, key)
+	}
+	return nil
+}
+
+// WithMaxSensitivity makes a handler accept fields up to class, masking
+// more sensitive values, or dropping them if drop is set
+func WithMaxSensitivity(class Sensitivity, drop bool) HandlerOption {
+	return func(o *handlerOptions) {
+		o.maxSensitivity = &sensitivityLimit{max: class, drop: drop}
+	}
+}
+
+// sensitivityLimit is the most sensitive class a handler accepts
+type sensitivityLimit struct {
+	max  Sensitivity
+	drop bool
+}
+
+// sensitivityCore masks or drops fields above a handler's limit. Fields
+// reach it with their keys mapped, so classes declared for the original
+// keys apply to the mapped ones. Keys nested in maps and objects are
+// classified too.
+type sensitivityCore struct {
+	zapcore.Core
+	classes *fieldClasses
+	mapping *atomic.Pointer[map[string]string]
+	reverse *atomic.Pointer[reverseMapping]
+	limit   sensitivityLimit
+}
+
+// reverseMapping lists the original keys mapped to each key, built once
+// per key mapping
+type reverseMapping struct {
+	mapping *map[string]string
+	from    map[string][]string
+}
+
+func newSensitivityCore(core zapcore.Core, classes *fieldClasses, mapping *atomic.Pointer[map[string]string], limit sensitivityLimit) *sensitivityCore {
+	return &sensitivityCore{Core: core, classes: classes, mapping: mapping, reverse: &atomic.Pointer[reverseMapping]{}, limit: limit}
+}
+
+// With implements zapcore.Core
+func (s *sensitivityCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sensitivityCore{Core: s.Core.With(s.filter(fields)), classes: s.classes, mapping: s.mapping, reverse: s.reverse, limit: s.limit}
+}
+
+// Check implements zapcore.Core
+func (s *sensitivityCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.Enabled(ent.Level) {
+		return ce.AddCore(ent, s)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (s *sensitivityCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return s.Core.Write(ent, s.filter(fields))
+}
+
+// filter masks or drops fields above the limit, copying fields only if
+// one is affected
+func (s *sensitivityCore) filter(fields []zapcore.Field) []zapcore.Field {
+	var filtered []zapcore.Field
+	for i, f := range fields {
+		if s.fieldClass(f) <= s.limit.max {
+			if filtered != nil {
+				filtered = append(filtered, f)
+			}
+			continue
+		}
+		if filtered == nil {
+			filtered = append(make([]zapcore.Field, 0, len(fields)), fields[:i]...)
+		}
+		if !s.limit.drop {
+			filtered = append(filtered, zap.String(f.Key, RedactedValue))
+		}
+	}
+	if filtered == nil {
+		return fields
+	}
+	return filtered
+}
+
+// fieldClass returns the class of a field, the highest of its key and of
+// the keys nested in its value. Values that cannot be encoded are
+// treated as secret.
+func (s *sensitivityCore) fieldClass(f zapcore.Field) Sensitivity {
+	class := s.classOf(f.Key)
+	switch f.Type {
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+	default:
+		return class
+	}
+	if class == SensitivitySecret {
+		return class
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	if err := encodeField(enc, f); err != nil {
+		return SensitivitySecret
+	}
+	return max(class, s.nestedClass(enc.Fields[f.Key]))
+}
+
+// nestedClass returns the highest class of the keys within an encoded
+// value. Reflected values are classified by their JSON form.
+func (s *sensitivityCore) nestedClass(value interface{}) Sensitivity {
+	class := SensitivityPublic
+	switch v := value.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+		float32, float64, complex64, complex128, json.Number:
+	case map[string]interface{}:
+		for key, nested := range v {
+			class = max(class, s.classes.lookup(key), s.nestedClass(nested))
+		}
+	case []interface{}:
+		for _, nested := range v {
+			class = max(class, s.nestedClass(nested))
+		}
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return SensitivitySecret
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return SensitivitySecret
+		}
+		switch decoded.(type) {
+		case map[string]interface{}, []interface{}:
+			class = s.nestedClass(decoded)
+		}
+	}
+	return class
+}
+
+// classOf returns the class of a field key, the highest of the key and
+// of any key mapped to it
+func (s *sensitivityCore) classOf(key string) Sensitivity {
+	class := s.classes.lookup(key)
+	for _, from := range s.mappedFrom(key) {
+		class = max(class, s.classes.lookup(from))
+	}
+	return class
+}
+
+// mappedFrom returns the keys mapped to key, rebuilding the reverse
+// lookup when the key mapping changes
+func (s *sensitivityCore) mappedFrom(key string) []string {
+	mapping := s.mapping.Load()
+	reverse := s.reverse.Load()
+	if reverse == nil || reverse.mapping != mapping {
+		reverse = &reverseMapping{mapping: mapping, from: make(map[string][]string, len(*mapping))}
+		for from, to := range *mapping {
+			reverse.from[to] = append(reverse.from[to], from)
+		}
+		s.reverse.Store(reverse)
+	}
+	return reverse.from[key]
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSensitivityAppliesToMappedKeys(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.ClassifyFields(SensitivityPII, "email")
+	logs := observe(l, zapcore.InfoLevel, WithMaxSensitivity(SensitivityInternal, false))
+	l.SetKeyMapping(map[string]string{"email": "user.email"})
+
+	l.Info("signup", map[string]interface{}{"email": "alice@example.com", "plan": "pro"})
+
+	fields := logs.All()[0].ContextMap()
+	if got := fields["user.email"]; got != RedactedValue {
+		t.Errorf("user.email = %v, want masked", got)
+	}
+	if got := fields["plan"]; got != "pro" {
+		t.Errorf("plan = %v, want unclassified value", got)
+	}
+}
+
+func TestSensitivityDropsAboveLimit(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.ClassifyFields(SensitivitySecret, "api_key")
+	logs := observe(l, zapcore.InfoLevel, WithMaxSensitivity(SensitivityPII, true))
+
+	l.Info("call", map[string]interface{}{"api_key": "k-123"})
+
+	if _, ok := logs.All()[0].ContextMap()["api_key"]; ok {
+		t.Error("api_key was not dropped")
+	}
+}
+
+// account marshals an email nested under its own key
+type account struct{ email string }
+
+func (a account) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("email", a.email)
+	return nil
+}
+
+func TestSensitivityAppliesToNestedKeys(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.ClassifyFields(SensitivityPII, "email")
+	logs := observe(l, zapcore.InfoLevel, WithMaxSensitivity(SensitivityInternal, false))
+
+	l.Info("signup", map[string]interface{}{
+		"user":    map[string]interface{}{"email": "alice@example.com"},
+		"account": account{email: "alice@example.com"},
+		"plan":    map[string]interface{}{"name": "pro"},
+	})
+	l.Sugar().Desugar().Info("signup",
+		zap.Any("user", map[string]string{"email": "alice@example.com"}),
+		zap.Object("account", account{email: "alice@example.com"}))
+
+	for i, entry := range logs.All() {
+		fields := entry.ContextMap()
+		for _, key := range []string{"user", "account"} {
+			if got := fields[key]; got != RedactedValue {
+				t.Errorf("entry %d: %s = %v, want masked", i, key, got)
+			}
+		}
+	}
+	if got := logs.All()[0].ContextMap()["plan"]; got == RedactedValue {
+		t.Error("plan was masked without classified keys")
+	}
+}
+
+func TestSensitivityMasksUnencodableValues(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel, WithMaxSensitivity(SensitivityPII, false))
+
+	l.Sugar().Desugar().Info("call", zap.Object("obj", panickingObject{}))
+
+	if got := logs.All()[0].ContextMap()["obj"]; got != RedactedValue {
+		t.Errorf("obj = %v, want masked", got)
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"crypto/rand"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Field keys used for entry ordering
+const (
+	SequenceKey = "seq"
+	EntryIDKey  = "entry_id"
+)
+
+// crockfordAlphabet is the base32 alphabet used by ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// entrySequence numbers entries across all loggers of the process
+var entrySequence atomic.Uint64
+
+// sequencer controls entry numbering. It is shared by a logger and its
+// children.
+type sequencer struct {
+	enabled  atomic.Bool
+	entryIDs atomic.Bool
+}
+
+// fields returns the sequence fields for an entry being written, if
+// enabled, under keys mapped by mapKey
+func (s *sequencer) fields(mapKey func(string) string) []zap.Field {
+	if !s.enabled.Load() {
+		return nil
+	}
+
+	fields := []zap.Field{zap.Uint64(mapKey(SequenceKey), entrySequence.Add(1))}
+	if s.entryIDs.Load() {
+		fields = append(fields, zap.String(mapKey(EntryIDKey), NewULID()))
+	}
+	return fields
+}
+
+// EnableSequence attaches a per-process monotonically increasing
+// sequence number to every entry, and optionally a ULID entry ID, so
+// ordering can be reconstructed when timestamps collide
+func (l *Logger) EnableSequence(withEntryID bool) {
+	l.sequence.entryIDs.Store(withEntryID)
+	l.sequence.enabled.Store(true)
+}
+
+// DisableSequence stops attaching sequence numbers and entry IDs
+func (l *Logger) DisableSequence() {
+	l.sequence.enabled.Store(false)
+}
+
+// NewULID generates a lexicographically sortable ULID
+func NewULID() string {
+	var u [16]byte
+
+	// 48-bit big-endian Unix timestamp in milliseconds
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		u[i] = byte(ms)
+		ms >>= 8
+	}
+
+	// 80 random bits
+	if _, err := rand.Read(u[6:]); err != nil {
+		panic("failed to generate ULID: " + err.Error())
+	}
+
+	// Encode 128 bits as 26 base32 characters, most significant first
+	var buf [26]byte
+	var acc uint32
+	bits := 2 // 130 bits of output for 128 bits of input
+	j := 0
+	for _, b := range u {
+		acc = acc<<8 | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			buf[j] = crockfordAlphabet[(acc>>uint(bits))&0x1f]
+			j++
+		}
+	}
+	return string(buf[:])
+}
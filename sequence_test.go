@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSequenceIsPerProcessWithoutGaps(t *testing.T) {
+	a := NewLogger("a", zapcore.DebugLevel)
+	b := NewLogger("b", zapcore.DebugLevel)
+	a.EnableSequence(false)
+	b.EnableSequence(false)
+	logs := observe(a, zapcore.InfoLevel)
+	observe(b, zapcore.InfoLevel)
+
+	a.Info("first")
+	a.Debug("rejected")
+	b.Info("other logger")
+	a.Info("second")
+
+	entries := logs.All()
+	first := entries[0].ContextMap()[SequenceKey].(uint64)
+	if got := entries[1].ContextMap()[SequenceKey]; got != first+2 {
+		t.Errorf("second entry has sequence %v, want %d", got, first+2)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ShadowHandler mirrors every entry written to the primary handler to
+// the shadow handler instead of the shadow receiving entries on its own,
+// e.g. to validate a new pipeline before switching over. The shadow is
+// written by its own worker, and entries are dropped when its queue is
+// full, so a slow shadow never delays the primary. Shadow failures and
+// drops never reach the caller; they show in the shadow's Stats and
+// Health. Removing the primary promotes the shadow to a regular handler.
+func (l *Logger) ShadowHandler(primary, shadow string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, s := l.handlers.lookup(primary), l.handlers.lookup(shadow)
+	switch {
+	case p == nil:
+		return fmt.Errorf("unknown handler %q", primary)
+	case s == nil:
+		return fmt.Errorf("unknown handler %q", shadow)
+	case p == s:
+		return fmt.Errorf("handler %q cannot shadow itself", primary)
+	case p.shadow != nil || p.primary != nil:
+		return fmt.Errorf("handler %q is already part of a shadow pair", primary)
+	case s.shadow != nil || s.primary != nil:
+		return fmt.Errorf("handler %q is already part of a shadow pair", shadow)
+	}
+
+	p.mirrorWorker = newHandlerWorker(AsyncConfig{Capacity: shadowQueueCapacity, Policy: OverflowDropNewest}, func(error) {})
+	p.mirror = &teeCore{primary: p.core, shadow: s.core, worker: p.mirrorWorker, dropped: &s.mirrorDropped}
+	p.shadow, s.primary = s, p
+	l.coreWrapper.RemoveCore(s.core)
+	l.coreWrapper.ReplaceCore(p.core, p.mirror)
+	return nil
+}
+
+// UnshadowHandler stops mirroring to the shadow handler, which receives
+// entries on its own again
+func (l *Logger) UnshadowHandler(shadow string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.handlers.lookup(shadow)
+	if s == nil {
+		return fmt.Errorf("unknown handler %q", shadow)
+	}
+	if s.primary == nil {
+		return fmt.Errorf("handler %q is not a shadow", shadow)
+	}
+	l.detachShadow(s.primary)
+	l.coreWrapper.AddCore(s.core)
+	return nil
+}
+
+// detachShadow restores the primary's own core in place of its mirror,
+// writing the entries still queued for the shadow
+func (l *Logger) detachShadow(p *handlerState) {
+	l.coreWrapper.ReplaceCore(p.mirror, p.core)
+	p.mirrorWorker.stop()
+	p.shadow.primary = nil
+	p.shadow, p.mirror, p.mirrorWorker = nil, nil, nil
+}
+
+// shadowQueueCapacity is the number of entries queued for a shadow
+// before further entries are dropped
+const shadowQueueCapacity = 1024
+
+// teeCore writes to a primary core and queues accepted entries for a
+// shadow core, ignoring the shadow's errors
+type teeCore struct {
+	primary zapcore.Core
+	shadow  zapcore.Core
+	worker  *handlerWorker
+	// dropped counts entries the shadow's queue had no room for
+	dropped *atomic.Int64
+}
+
+// Enabled implements zapcore.Core
+func (t *teeCore) Enabled(lvl zapcore.Level) bool {
+	return t.primary.Enabled(lvl)
+}
+
+// With implements zapcore.Core
+func (t *teeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &teeCore{primary: t.primary.With(fields), shadow: t.shadow.With(fields), worker: t.worker, dropped: t.dropped}
+}
+
+// Check implements zapcore.Core
+func (t *teeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if t.Enabled(ent.Level) {
+		return ce.AddCore(ent, t)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core
+func (t *teeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := t.primary.Write(ent, fields)
+	// Shadow errors are recorded in its handler state only
+	if !t.worker.push(asyncEntry{core: t.shadow, ent: ent, fields: append([]zapcore.Field{}, fields...)}) {
+		t.dropped.Add(1)
+	}
+	return err
+}
+
+// Sync implements zapcore.Core
+func (t *teeCore) Sync() error {
+	t.worker.wait()
+	t.shadow.Sync()
+	return t.primary.Sync()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// stalledCore blocks writes until released
+type stalledCore struct {
+	zapcore.Core
+	release chan struct{}
+}
+
+func (c *stalledCore) With(fields []zapcore.Field) zapcore.Core {
+	return &stalledCore{Core: c.Core.With(fields), release: c.release}
+}
+
+func (c *stalledCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *stalledCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	<-c.release
+	return c.Core.Write(ent, fields)
+}
+
+func TestSlowShadowDoesNotDelayPrimary(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	primary, primaryLogs := observer.New(zapcore.InfoLevel)
+	shadowed, shadowLogs := observer.New(zapcore.InfoLevel)
+	stalled := &stalledCore{Core: shadowed, release: make(chan struct{})}
+
+	l.mu.Lock()
+	l.registerHandler(newHandlerState(handlerSpec{kind: "primary"}, handlerOptions{}), primary)
+	l.registerHandler(newHandlerState(handlerSpec{kind: "shadow"}, handlerOptions{}), stalled)
+	l.mu.Unlock()
+	if err := l.ShadowHandler("primary", "shadow"); err != nil {
+		t.Fatal(err)
+	}
+
+	const n = shadowQueueCapacity + 10
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			l.Info("entry")
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked on the shadow")
+	}
+	if got := primaryLogs.Len(); got != n {
+		t.Errorf("primary got %d entries, want %d", got, n)
+	}
+
+	var dropped int64
+	for _, h := range l.Handlers() {
+		if h.Name == "shadow" {
+			dropped = h.Dropped
+		}
+	}
+	if dropped == 0 {
+		t.Error("shadow reported no dropped entries")
+	}
+
+	close(stalled.release)
+	if err := l.UnshadowHandler("shadow"); err != nil {
+		t.Fatal(err)
+	}
+	if got := int64(shadowLogs.Len()) + dropped; got != n {
+		t.Errorf("shadow wrote and dropped %d entries, want %d", got, n)
+	}
+}
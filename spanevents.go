@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrorSpan is the subset of a tracing span used to mirror failures.
+// An OpenTelemetry adapter records an event named after the message
+// with the fields as attributes and sets the span status to Error:
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) RecordLogEntry(level LogLevel, msg string, fields map[string]interface{}) {
+//		s.AddEvent(msg, trace.WithAttributes(attribute.String("log.severity", level.String())))
+//		s.SetStatus(codes.Error, msg)
+//	}
+type ErrorSpan interface {
+	IsRecording() bool
+	RecordLogEntry(level LogLevel, msg string, fields map[string]interface{})
+}
+
+// spanMirror holds the function resolving the active span of a context.
+// It is shared by a logger and its children.
+type spanMirror struct {
+	fromContext func(ctx context.Context) ErrorSpan
+	mu          sync.RWMutex
+}
+
+// EnableSpanEvents mirrors Error and Fatal entries logged with a context
+// onto the context's active span, as resolved by fromContext, keeping
+// traces and logs consistent for failures. The message and fields are
+// redacted as in the entry.
+func (l *Logger) EnableSpanEvents(fromContext func(ctx context.Context) ErrorSpan) {
+	l.spans.mu.Lock()
+	defer l.spans.mu.Unlock()
+
+	l.spans.fromContext = fromContext
+}
+
+// mirrorToSpan records the entry on the context's active span, if any
+func (l *Logger) mirrorToSpan(ctx context.Context, level LogLevel, msg string, fields map[string]interface{}) {
+	l.spans.mu.RLock()
+	fromContext := l.spans.fromContext
+	l.spans.mu.RUnlock()
+
+	if fromContext == nil || ctx == nil {
+		return
+	}
+
+	span := fromContext(ctx)
+	if span == nil || !span.IsRecording() {
+		return
+	}
+
+	// Spans are exported like logs, so record the message and fields
+	// as redacted for the entry
+	l.mu.RLock()
+	r := l.entryRedactor()
+	l.mu.RUnlock()
+
+	span.RecordLogEntry(level, r.string(msg), r.fieldMap(fields))
+}
+
+// fieldMap returns a copy of fields with values redacted and encoded as
+// for handlers, e.g. errors expanded and marshalers rendered as maps
+func (r redactor) fieldMap(fields map[string]interface{}) map[string]interface{} {
+	if len(fields) == 0 {
+		return fields
+	}
+
+	zapFields := make([]zap.Field, 0, len(fields))
+	for k, v := range fields {
+		zapFields = r.appendValueFields(zapFields, k, v)
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range r.fields(zapFields) {
+		f.AddTo(enc)
+	}
+	return enc.Fields
+}
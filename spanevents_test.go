@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// recordedSpan captures the entries mirrored onto it
+type recordedSpan struct {
+	msg    string
+	fields map[string]interface{}
+}
+
+func (s *recordedSpan) IsRecording() bool { return true }
+
+func (s *recordedSpan) RecordLogEntry(level LogLevel, msg string, fields map[string]interface{}) {
+	s.msg, s.fields = msg, fields
+}
+
+func TestSpanEventsAreRedacted(t *testing.T) {
+	l := NewLogger("api", zapcore.InfoLevel)
+	l.AddRedaction(regexp.MustCompile(`card=\d+`), "card=[CARD]")
+	l.AddRedactFields("password")
+
+	span := &recordedSpan{}
+	l.EnableSpanEvents(func(ctx context.Context) ErrorSpan { return span })
+
+	l.ErrorContext(context.Background(), "charge failed card=4111", map[string]interface{}{
+		"password": "hunter2",
+		"detail":   "retry card=4111",
+		"attempt":  3,
+	})
+
+	if span.msg != "charge failed card=[CARD]" {
+		t.Errorf("span message = %q", span.msg)
+	}
+	if got := span.fields["password"]; got != RedactedValue {
+		t.Errorf("password = %v, want redacted", got)
+	}
+	if got := span.fields["detail"]; got != "retry card=[CARD]" {
+		t.Errorf("detail = %v, want redacted", got)
+	}
+	if got := span.fields["attempt"]; got != int64(3) {
+		t.Errorf("attempt = %v, want 3", got)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// spillAckRetry is the delay before retrying spilled entries the sink
+// didn't confirm
+const spillAckRetry = time.Second
+
+// spillAck persists the offset of the spill file up to which entries
+// were confirmed by the sink
+type spillAck struct {
+	file   *os.File
+	offset int64
+}
+
+// openSpillAck opens the ack file at path, reading the stored offset
+func openSpillAck(path string) (*spillAck, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	a := &spillAck{file: file}
+	if text := strings.TrimSpace(string(data)); text != "" {
+		if a.offset, err = strconv.ParseInt(text, 10, 64); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("ack file %s: %w", path, err)
+		}
+	}
+	return a, nil
+}
+
+// store persists offset. The fixed width keeps each update a single
+// in-place write, synced so it survives a crash.
+func (a *spillAck) store(offset int64) error {
+	if _, err := a.file.WriteAt([]byte(fmt.Sprintf("%020d\n", offset)), 0); err != nil {
+		return err
+	}
+	if err := a.file.Sync(); err != nil {
+		return err
+	}
+	a.offset = offset
+	return nil
+}
+
+// resumeSpill opens the spill and ack files of an acknowledging buffer,
+// scheduling entries spilled but unconfirmed before a restart for
+// delivery ahead of new entries
+func (b *boundedBuffer) resumeSpill() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ack, err := openSpillAck(b.cfg.SpillPath + ".ack")
+	if err != nil {
+		return err
+	}
+	spill, err := os.OpenFile(b.cfg.SpillPath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		ack.file.Close()
+		return err
+	}
+	info, err := spill.Stat()
+	if err != nil {
+		ack.file.Close()
+		spill.Close()
+		return err
+	}
+
+	// A spill file truncated behind our back restarts from its beginning
+	if ack.offset > info.Size() {
+		ack.offset = 0
+	}
+	b.ack, b.spill = ack, spill
+	b.spilling = info.Size() > ack.offset
+	return nil
+}
+
+// readUnacked returns the spilled entries after the acknowledged offset,
+// empty but not nil if there are none. The caller must hold the lock.
+func (b *boundedBuffer) readUnacked() ([]byte, error) {
+	info, err := b.spill.Stat()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, info.Size()-b.ack.offset)
+	if _, err := b.spill.ReadAt(data, b.ack.offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data, nil
+}
+
+// acknowledge records that the sink confirmed spilled entries up to offset
+func (b *boundedBuffer) acknowledge(offset int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.ack.store(offset)
+}
+
+// spillDrained truncates the spill file and stops spilling once all of
+// it was confirmed. Entries spilled meanwhile, or left after a failed
+// delivery, are delivered next.
+func (b *boundedBuffer) spillDrained(failed bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if failed {
+		return nil
+	}
+	info, err := b.spill.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > b.ack.offset {
+		return nil
+	}
+	if err := b.spill.Truncate(0); err != nil {
+		return err
+	}
+	b.spilling = false
+	b.changed.Broadcast()
+	return b.ack.store(0)
+}
+
+// writeAcked writes spilled entries one by one, persisting the offset of
+// each entry the sink accepted. It stops at the first failure, leaving
+// the rest to be retried.
+func (w *asyncWriter) writeAcked(spilled []byte) {
+	offset := w.ack.offset
+	failed := false
+	for len(spilled) > 0 {
+		entry, n, ok := spilledEntry(spilled)
+		if !ok {
+			// Entries are spilled whole under the lock, so only a crash
+			// leaves a truncated one; skip it rather than retry forever
+			w.onError(errSpillTruncated)
+		} else if _, err := w.sink.Write(entry); err != nil {
+			w.onError(err)
+			failed = true
+			break
+		}
+		offset += int64(n)
+		if err := w.acknowledge(offset); err != nil {
+			w.onError(err)
+		}
+		spilled = spilled[n:]
+	}
+
+	if err := w.spillDrained(failed); err != nil {
+		w.onError(err)
+	}
+	if failed {
+		time.Sleep(spillAckRetry)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SplunkHECConfig configures a Splunk HTTP Event Collector handler
+type SplunkHECConfig struct {
+	// URL is the HEC base URL, e.g. "https://splunk.example.com:8088"
+	URL string
+	// Token is the HEC token
+	Token      string
+	Index      string
+	Source     string
+	SourceType string
+	// Host defaults to the machine's hostname
+	Host string
+	// Gzip compresses each batch
+	Gzip bool
+	// Channel is the request channel GUID, required when UseAck is set
+	Channel string
+	// UseAck polls the ack endpoint until each batch is indexed
+	UseAck bool
+	// AckTimeout bounds ack polling, defaults to 30 seconds
+	AckTimeout time.Duration
+	// BatchSize and FlushInterval control batching, defaulting to 500
+	// entries and 5 seconds
+	BatchSize     int
+	FlushInterval time.Duration
+	// Client is the HTTP client used for uploads, defaults to one with a
+	// 10 second timeout
+	Client *http.Client
+}
+
+// AddSplunkHandler adds a handler sending batches of entries to a
+// Splunk HTTP Event Collector
+func (l *Logger) AddSplunkHandler(cfg SplunkHECConfig, level LogLevel, opts ...HandlerOption) error {
+	if cfg.URL == "" || cfg.Token == "" {
+		return fmt.Errorf("splunk HEC URL and token are required")
+	}
+	if cfg.UseAck && cfg.Channel == "" {
+		return fmt.Errorf("splunk HEC channel is required when acks are enabled")
+	}
+	if cfg.Host == "" {
+		cfg.Host, _ = os.Hostname()
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("splunk", true); err != nil {
+		return err
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		return err
+	}
+	// Splunk expects epoch seconds for event time
+	encoderConfig.EncodeTime = zapcore.EpochTimeEncoder
+
+	uploader := &splunkUploader{cfg: cfg, timeKey: encoderConfig.TimeKey}
+	sink := newBatchWriter(batchConfig{
+		maxEntries:    cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+	}, uploader.upload, l.internalErrors.report)
+
+	spec := handlerSpec{kind: "splunk", sink: cfg.URL, encoder: "json", level: level}
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), sink, handlerOpts).onClose(sink)
+	return nil
+}
+
+// splunkUploader posts batches to the HEC event endpoint
+type splunkUploader struct {
+	cfg     SplunkHECConfig
+	timeKey string
+}
+
+// splunkEvent is a single HEC event
+type splunkEvent struct {
+	Time       json.RawMessage `json:"time,omitempty"`
+	Host       string          `json:"host,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	SourceType string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// upload wraps each entry in a HEC event and posts the batch
+func (u *splunkUploader) upload(batch [][]byte) error {
+	var body bytes.Buffer
+	for _, line := range batch {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return err
+		}
+
+		event, err := json.Marshal(splunkEvent{
+			Time:       fields[u.timeKey],
+			Host:       u.cfg.Host,
+			Source:     u.cfg.Source,
+			SourceType: u.cfg.SourceType,
+			Index:      u.cfg.Index,
+			Event:      bytes.TrimSpace(line),
+		})
+		if err != nil {
+			return err
+		}
+		body.Write(event)
+	}
+
+	resp, err := u.post("/services/collector/event", body.Bytes())
+	if err != nil {
+		return err
+	}
+	if !u.cfg.UseAck {
+		return nil
+	}
+
+	var result struct {
+		AckID *int64 `json:"ackId"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return err
+	}
+	if result.AckID == nil {
+		return fmt.Errorf("splunk HEC: response has no ack ID")
+	}
+	return u.waitForAck(*result.AckID)
+}
+
+// waitForAck polls the ack endpoint until the batch is indexed
+func (u *splunkUploader) waitForAck(ackID int64) error {
+	deadline := time.Now().Add(u.cfg.AckTimeout)
+	body, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return err
+	}
+
+	for {
+		resp, err := u.post("/services/collector/ack", body)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Acks map[string]bool `json:"acks"`
+		}
+		if err := json.Unmarshal(resp, &result); err != nil {
+			return err
+		}
+		if result.Acks[fmt.Sprint(ackID)] {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("splunk HEC: ack %d not received within %s", ackID, u.cfg.AckTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// post sends a request to the HEC and returns the response body
+func (u *splunkUploader) post(path string, payload []byte) ([]byte, error) {
+	var body bytes.Buffer
+	if u.cfg.Gzip {
+		zw := gzip.NewWriter(&body)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	} else {
+		body.Write(payload)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.cfg.URL+path, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Splunk "+u.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+	if u.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if u.cfg.Channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", u.cfg.Channel)
+	}
+
+	resp, err := u.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var respBody bytes.Buffer
+	if _, err := respBody.ReadFrom(resp.Body); err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("splunk HEC: unexpected status %s: %s", resp.Status, strings.TrimSpace(respBody.String()))
+	}
+	return respBody.Bytes(), nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SQL dialects supported by the database handler
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// validTableName restricts table names interpolated into statements
+var validTableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLConfig configures a database handler
+type SQLConfig struct {
+	// DB is an open database handle; the caller registers the driver
+	DB *sql.DB
+	// Dialect is DialectPostgres or DialectSQLite
+	Dialect string
+	// Table defaults to "logs"
+	Table string
+	// CreateTable creates the table and its indexes if missing
+	CreateTable bool
+	// BatchSize and FlushInterval control batching, defaulting to 500
+	// entries and 5 seconds
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// AddSQLHandler adds a handler inserting entries into a database table
+// with indexed time, level and logger columns and a JSON fields column,
+// batched in transactions
+func (l *Logger) AddSQLHandler(cfg SQLConfig, level LogLevel, opts ...HandlerOption) error {
+	if cfg.DB == nil {
+		return fmt.Errorf("database handle must not be nil")
+	}
+	if cfg.Dialect != DialectPostgres && cfg.Dialect != DialectSQLite {
+		return fmt.Errorf("unsupported SQL dialect %q", cfg.Dialect)
+	}
+	if cfg.Table == "" {
+		cfg.Table = "logs"
+	}
+	if !validTableName.MatchString(cfg.Table) {
+		return fmt.Errorf("invalid table name %q", cfg.Table)
+	}
+
+	sink := &sqlSink{cfg: cfg}
+	if cfg.CreateTable {
+		if err := sink.createTable(); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handlerOpts := newHandlerOptions(opts)
+	if err := handlerOpts.validate("sql", true); err != nil {
+		return err
+	}
+	encoderConfig, err := l.newEncoderConfig(zapcore.CapitalLevelEncoder, handlerOpts)
+	if err != nil {
+		return err
+	}
+	encoderConfig.EncodeTime = zapcore.RFC3339NanoTimeEncoder
+	sink.keys = encoderConfig
+
+	writer := newBatchWriter(batchConfig{
+		maxEntries:    cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+	}, sink.insert, l.internalErrors.report)
+
+	spec := handlerSpec{kind: "sql", sink: cfg.Table, encoder: "json", level: level}
+	l.addHandlerCore(spec, zapcore.NewJSONEncoder(encoderConfig), writer, handlerOpts).onClose(writer)
+	return nil
+}
+
+// sqlSink inserts batches of entries
+type sqlSink struct {
+	cfg  SQLConfig
+	keys zapcore.EncoderConfig
+}
+
+// createTable creates the log table and its indexes
+func (s *sqlSink) createTable() error {
+	idColumn, fieldsType := "INTEGER PRIMARY KEY AUTOINCREMENT", "TEXT"
+	if s.cfg.Dialect == DialectPostgres {
+		idColumn, fieldsType = "BIGSERIAL PRIMARY KEY", "JSONB"
+	}
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id %s,
+	time TIMESTAMP NOT NULL,
+	level VARCHAR(16) NOT NULL,
+	logger VARCHAR(255),
+	message TEXT,
+	fields %s
+)`, s.cfg.Table, idColumn, fieldsType),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_time_idx ON %s (time)", indexPrefix(s.cfg.Table), s.cfg.Table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_level_idx ON %s (level)", indexPrefix(s.cfg.Table), s.cfg.Table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_logger_idx ON %s (logger)", indexPrefix(s.cfg.Table), s.cfg.Table),
+	}
+	for _, stmt := range statements {
+		if _, err := s.cfg.DB.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insert writes a batch of encoded entries in a single transaction
+func (s *sqlSink) insert(batch [][]byte) error {
+	placeholders := "?, ?, ?, ?, ?"
+	if s.cfg.Dialect == DialectPostgres {
+		placeholders = "$1, $2, $3, $4, $5"
+	}
+	query := fmt.Sprintf("INSERT INTO %s (time, level, logger, message, fields) VALUES (%s)", s.cfg.Table, placeholders)
+
+	ctx := context.Background()
+	tx, err := s.cfg.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, line := range batch {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return err
+		}
+
+		ts, _ := time.Parse(time.RFC3339Nano, popString(entry, s.keys.TimeKey))
+		level := popString(entry, s.keys.LevelKey)
+		logger := popString(entry, s.keys.NameKey)
+		message := popString(entry, s.keys.MessageKey)
+
+		fields, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.ExecContext(ctx, ts, level, logger, message, string(fields)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// popString removes key from m and returns its string value
+func popString(m map[string]interface{}, key string) string {
+	v, _ := m[key].(string)
+	delete(m, key)
+	return v
+}
+
+// indexPrefix derives an index name prefix from a possibly schema-qualified table
+func indexPrefix(table string) string {
+	return regexp.MustCompile(`\W`).ReplaceAllString(table, "_")
+}
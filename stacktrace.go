@@ -0,0 +1,142 @@
+package main
+
+import (
+	"hash/fnv"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StackRefKey is the field identifying a stack trace. Entries repeating
+// a recently logged trace carry only its reference.
+const StackRefKey = "stack_ref"
+
+// maxCachedStacks bounds the number of traces remembered for
+// deduplication; the cache starts over once it is full
+const maxCachedStacks = 4096
+
+// stackTraces captures deduplicated stack traces. It is shared by a
+// logger and its children.
+type stackTraces struct {
+	enabled atomic.Bool
+	level   atomic.Int32
+	reemit  time.Duration
+	seen    map[uint64]time.Time
+	mu      sync.Mutex
+}
+
+// EnableStackTraces attaches the caller's stack trace to entries at or
+// above level, including recovered panics. Identical traces, keyed by
+// their program counters, are written in full once and then only as a
+// stack_ref until reemit has passed; zero never re-emits them.
+func (l *Logger) EnableStackTraces(level LogLevel, reemit time.Duration) {
+	l.stacks.mu.Lock()
+	defer l.stacks.mu.Unlock()
+
+	l.stacks.reemit = reemit
+	if l.stacks.seen == nil {
+		l.stacks.seen = map[uint64]time.Time{}
+	}
+	l.stacks.level.Store(int32(level))
+	l.stacks.enabled.Store(true)
+}
+
+// DisableStackTraces stops attaching stack traces to entries
+func (l *Logger) DisableStackTraces() {
+	l.stacks.enabled.Store(false)
+}
+
+// wants reports whether entries at level get a stack trace
+func (s *stackTraces) wants(level LogLevel) bool {
+	return s.enabled.Load() && int32(level) >= s.level.Load()
+}
+
+// capture returns the current stack outside the logger and its
+// reference. The trace is empty if the same one was emitted recently.
+// Without deduplication the full trace is always returned.
+func (s *stackTraces) capture() (trace, ref string) {
+	pcs := callerStack()
+	hash := stackRef(pcs)
+
+	s.mu.Lock()
+	repeated := false
+	if s.enabled.Load() {
+		now := time.Now()
+		if at, ok := s.seen[hash]; ok && (s.reemit <= 0 || now.Sub(at) < s.reemit) {
+			repeated = true
+		} else {
+			if len(s.seen) >= maxCachedStacks {
+				s.seen = map[uint64]time.Time{}
+			}
+			s.seen[hash] = now
+		}
+	}
+	s.mu.Unlock()
+
+	ref = strconv.FormatUint(hash, 16)
+	if repeated {
+		return "", ref
+	}
+	return formatStack(pcs), ref
+}
+
+// callerStack returns the program counters of the stack below the
+// logger's own frames
+func callerStack() []uintptr {
+	pcs := make([]uintptr, 64)
+	// Skip runtime.Callers and callerStack
+	n := runtime.Callers(2, pcs)
+	pcs = pcs[:n]
+
+	// Drop frames up to and including the logging path
+	frames := runtime.CallersFrames(pcs)
+	skip, inLogger := 0, false
+	for i := 0; ; i++ {
+		frame, more := frames.Next()
+		if isLoggerFrame(frame.Function) {
+			inLogger, skip = true, i+1
+		} else if inLogger {
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	return pcs[skip:]
+}
+
+// stackRef hashes program counters into a trace reference
+func stackRef(pcs []uintptr) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, pc := range pcs {
+		for i := range b {
+			b[i] = byte(pc >> (8 * i))
+		}
+		h.Write(b[:])
+	}
+	return h.Sum64()
+}
+
+// formatStack renders program counters like zap's stack traces
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		if !more {
+			return b.String()
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// loggerStats counts entries written and dropped.
+// It is shared by a logger and its children.
+type loggerStats struct {
+	entries [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Int64
+	dropped atomic.Int64
+	// exemplars holds the latest entry per level once MetricsHandler
+	// has been created
+	exemplars   [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Pointer[exemplar]
+	exemplarsOn atomic.Bool
+}
+
+// recordEntry counts an entry written at the given level
+func (s *loggerStats) recordEntry(level LogLevel) {
+	if level >= zapcore.DebugLevel && level <= zapcore.FatalLevel {
+		s.entries[level-zapcore.DebugLevel].Add(1)
+	}
+}
+
+// LoggerStats is a snapshot of a logger's internal statistics
+type LoggerStats struct {
+	Entries     map[string]int64 `json:"entries"`
+	Dropped     int64            `json:"dropped"`
+	LastError   string           `json:"last_error,omitempty"`
+	LastErrorAt *time.Time       `json:"last_error_at,omitempty"`
+	Handlers    []HandlerStats   `json:"handlers"`
+}
+
+// HandlerStats is a snapshot of a handler's statistics
+type HandlerStats struct {
+	Name        string     `json:"name"`
+	Entries     int64      `json:"entries"`
+	Bytes       int64      `json:"bytes"`
+	Errors      int64      `json:"errors"`
+	Dropped     int64      `json:"dropped"`
+	QueueDepth  int        `json:"queue_depth"`
+	Muted       bool       `json:"muted,omitempty"`
+	Degraded    bool       `json:"degraded,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastWriteAt *time.Time `json:"last_write_at,omitempty"`
+}
+
+// Stats returns a snapshot of the statistics shared by the logger and its
+// children. Dropped counts entries removed by throttling, quotas, quiet
+// windows or caller filters.
+func (l *Logger) Stats() LoggerStats {
+	stats := LoggerStats{
+		Entries:  map[string]int64{},
+		Dropped:  l.stats.dropped.Load(),
+		Handlers: []HandlerStats{},
+	}
+	for i := range l.stats.entries {
+		level := zapcore.DebugLevel + LogLevel(i)
+		stats.Entries[level.String()] = l.stats.entries[i].Load()
+	}
+
+	if err, at := l.internalErrors.last(); err != nil {
+		stats.LastError = err.Error()
+		stats.LastErrorAt = &at
+	}
+
+	for _, h := range l.handlers.all() {
+		stats.Handlers = append(stats.Handlers, h.stats())
+	}
+	return stats
+}
+
+// stats returns a snapshot of the handler's statistics
+func (s *handlerState) stats() HandlerStats {
+	stats := HandlerStats{
+		Name:    s.name,
+		Entries: s.entries.Load(),
+		Bytes:   s.bytes.Load(),
+		Errors:  s.errors.Load(),
+		Muted:   s.muted.Load(),
+	}
+	if s.queue != nil {
+		stats.QueueDepth = s.queue()
+	}
+	if s.dropped != nil {
+		stats.Dropped = s.dropped()
+	}
+	stats.Dropped += s.mirrorDropped.Load()
+	if s.degraded != nil {
+		stats.Degraded = s.degraded()
+	}
+	if err := s.lastError.Load(); err != nil {
+		stats.LastError = (*err).Error()
+	}
+	if nanos := s.lastWrite.Load(); nanos != 0 {
+		at := time.Unix(0, nanos)
+		stats.LastWriteAt = &at
+	}
+	return stats
+}
+
+// PublishExpvar publishes the logger's statistics as an expvar variable,
+// served under /debug/vars by the default HTTP mux.
+// Like expvar.Publish, it panics if the name is already in use.
+func (l *Logger) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return l.Stats()
+	}))
+}
+
+// StatsHandler returns an HTTP handler serving the logger's statistics
+// as JSON
+func (l *Logger) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(l.Stats()); err != nil {
+			l.internalErrors.report(err)
+		}
+	})
+}
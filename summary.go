@@ -0,0 +1,181 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SummaryTopProblems is the number of most frequent problems reported
+// in a summary entry
+const SummaryTopProblems = 5
+
+// summarySampleItems is the number of example items kept per problem
+const summarySampleItems = 3
+
+// Summary collects per-item outcomes of a batch job and logs them as a
+// single entry at the end instead of one entry per item
+type Summary struct {
+	logger    *Logger
+	operation string
+	start     time.Time
+	succeeded int64
+	warnings  int64
+	errors    int64
+	problems  map[string]*summaryProblem
+	mu        sync.Mutex
+}
+
+// summaryProblem groups identical warnings or errors
+type summaryProblem struct {
+	level LogLevel
+	text  string
+	count int64
+	items []string
+}
+
+// NewSummary starts collecting outcomes of a batch operation
+func (l *Logger) NewSummary(operation string) *Summary {
+	return &Summary{
+		logger:    l,
+		operation: operation,
+		start:     time.Now(),
+		problems:  map[string]*summaryProblem{},
+	}
+}
+
+// Success records a successfully processed item
+func (s *Summary) Success() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.succeeded++
+}
+
+// Warn records a warning for an item. Identical messages are grouped.
+func (s *Summary) Warn(item, msg string) {
+	s.record(zapcore.WarnLevel, item, msg)
+}
+
+// Error records a failed item. Identical errors are grouped; a nil err
+// still counts the item as failed, grouped as "unknown error".
+func (s *Summary) Error(item string, err error) {
+	text := "unknown error"
+	if err != nil {
+		text = err.Error()
+	}
+	s.record(zapcore.ErrorLevel, item, text)
+}
+
+// record counts a problem for an item
+func (s *Summary) record(level LogLevel, item, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if level >= zapcore.ErrorLevel {
+		s.errors++
+	} else {
+		s.warnings++
+	}
+
+	key := level.String() + "\x00" + text
+	problem, ok := s.problems[key]
+	if !ok {
+		problem = &summaryProblem{level: level, text: text}
+		s.problems[key] = problem
+	}
+	problem.count++
+	if len(problem.items) < summarySampleItems && item != "" {
+		problem.items = append(problem.items, item)
+	}
+}
+
+// Done logs the summary with fields: counts, the most frequent problems
+// and the duration. The entry is logged at Error if any item failed, at
+// Warn if any had warnings, and at Info otherwise.
+func (s *Summary) Done(fields map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.start)
+
+	level := zapcore.InfoLevel
+	if s.errors > 0 {
+		level = zapcore.ErrorLevel
+	} else if s.warnings > 0 {
+		level = zapcore.WarnLevel
+	}
+
+	entryFields := make(map[string]interface{}, len(fields)+6)
+	for k, v := range fields {
+		entryFields[k] = v
+	}
+	entryFields[OperationKey] = s.operation
+	entryFields["succeeded"] = s.succeeded
+	entryFields["warnings"] = s.warnings
+	entryFields["errors"] = s.errors
+	entryFields[DurationKey] = float64(elapsed.Microseconds()) / 1000
+	if top := s.topProblems(); len(top) > 0 {
+		entryFields["top_problems"] = top
+	}
+
+	s.logger.log(level, s.operation+" summary", entryFields)
+}
+
+// topProblems returns the most frequent problems, errors first on ties
+func (s *Summary) topProblems() summaryProblems {
+	problems := make(summaryProblems, 0, len(s.problems))
+	for _, p := range s.problems {
+		problems = append(problems, *p)
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].count != problems[j].count {
+			return problems[i].count > problems[j].count
+		}
+		if problems[i].level != problems[j].level {
+			return problems[i].level > problems[j].level
+		}
+		return problems[i].text < problems[j].text
+	})
+	if len(problems) > SummaryTopProblems {
+		problems = problems[:SummaryTopProblems]
+	}
+	return problems
+}
+
+// summaryProblems encodes problems as an array of objects
+type summaryProblems []summaryProblem
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (p summaryProblems) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, problem := range p {
+		if err := enc.AppendObject(problem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler
+func (p summaryProblem) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("level", p.level.String())
+	enc.AddString("message", p.text)
+	enc.AddInt64("count", p.count)
+	if len(p.items) > 0 {
+		return enc.AddArray("items", stringArray(p.items))
+	}
+	return nil
+}
+
+// stringArray encodes a string slice
+type stringArray []string
+
+// MarshalLogArray implements zapcore.ArrayMarshaler
+func (a stringArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, s := range a {
+		enc.AppendString(s)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSummaryErrorAcceptsNil(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	logs := observe(l, zapcore.InfoLevel)
+
+	s := l.NewSummary("import")
+	s.Success()
+	s.Error("row 2", nil)
+	s.Error("row 3", errors.New("bad date"))
+	s.Done(nil)
+
+	entry := logs.All()[0]
+	if entry.Level != zapcore.ErrorLevel {
+		t.Errorf("level = %v, want error", entry.Level)
+	}
+	if got := entry.ContextMap()["errors"]; got != int64(2) {
+		t.Errorf("errors = %v, want 2", got)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// processStart is the reference for relative timestamps
+var processStart = time.Now()
+
+// levelSymbols are the compact level markers of WithSymbols
+var levelSymbols = map[LogLevel]string{
+	zapcore.DebugLevel:  "·",
+	zapcore.InfoLevel:   "ℹ",
+	zapcore.WarnLevel:   "⚠",
+	zapcore.ErrorLevel:  "✖",
+	zapcore.DPanicLevel: "✖",
+	zapcore.PanicLevel:  "✖",
+	zapcore.FatalLevel:  "✖",
+}
+
+// defaultSymbolColors matches the colors of zap's colored levels
+var defaultSymbolColors = map[LogLevel]string{
+	zapcore.DebugLevel:  "35",
+	zapcore.InfoLevel:   "34",
+	zapcore.WarnLevel:   "33",
+	zapcore.ErrorLevel:  "31",
+	zapcore.DPanicLevel: "31",
+	zapcore.PanicLevel:  "31",
+	zapcore.FatalLevel:  "31",
+}
+
+// WithSymbols gives a development console handler a compact style for
+// local development: levels are shown as symbols such as ✖ for errors
+// and ⚠ for warnings, colored by the handler's theme, and timestamps as
+// seconds since the process started, e.g. "   2.047s ⚠ slow query".
+func WithSymbols() HandlerOption {
+	return func(o *handlerOptions) {
+		o.symbols = true
+	}
+}
+
+// symbolLevelEncoder returns a level encoder writing colored symbols
+func symbolLevelEncoder(theme *ConsoleTheme) zapcore.LevelEncoder {
+	colors := defaultSymbolColors
+	if theme != nil {
+		colors = theme.Levels
+	}
+	return func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		symbol, ok := levelSymbols[level]
+		if !ok {
+			symbol = level.CapitalString()
+		}
+		enc.AppendString(ansiColor(symbol, colors[level]))
+	}
+}
+
+// relativeTimeEncoder writes the time since the process started as
+// right-aligned seconds with millisecond precision
+func relativeTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	seconds := strconv.FormatFloat(t.Sub(processStart).Seconds(), 'f', 3, 64)
+	for len(seconds) < 8 {
+		seconds = " " + seconds
+	}
+	enc.AppendString(seconds + "s")
+}
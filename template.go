@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// MessageTemplateKey is the field holding the raw template of messages
+// logged with InfoT and friends, for grouping entries downstream
+const MessageTemplateKey = "message_template"
+
+// DebugT logs at Debug level a message rendered from template, see InfoT
+func (l *Logger) DebugT(template string, fields ...map[string]interface{}) {
+	l.logTemplate(zapcore.DebugLevel, template, fields...)
+}
+
+// InfoT logs at Info level a message rendered from a template such as
+// "user {user_id} logged in from {client_ip}", replacing placeholders
+// with field values. The raw template is logged under message_template
+// alongside the fields. Placeholders without a field are kept, values of
+// redacted keys and PII or secret fields are masked, and "{{" and "}}"
+// write literal braces.
+func (l *Logger) InfoT(template string, fields ...map[string]interface{}) {
+	l.logTemplate(zapcore.InfoLevel, template, fields...)
+}
+
+// WarnT logs at Warn level a message rendered from template, see InfoT
+func (l *Logger) WarnT(template string, fields ...map[string]interface{}) {
+	l.logTemplate(zapcore.WarnLevel, template, fields...)
+}
+
+// ErrorT logs at Error level a message rendered from template, see InfoT
+func (l *Logger) ErrorT(template string, fields ...map[string]interface{}) {
+	l.logTemplate(zapcore.ErrorLevel, template, fields...)
+}
+
+// logTemplate renders template and writes it with the template field
+func (l *Logger) logTemplate(level LogLevel, template string, fields ...map[string]interface{}) {
+	// Skip rendering for disabled levels
+	if !l.enabled(level) {
+		return
+	}
+
+	entryFields := map[string]interface{}{}
+	if len(fields) > 0 {
+		for k, v := range fields[0] {
+			entryFields[k] = v
+		}
+	}
+
+	if !l.noLock {
+		l.mu.RLock()
+	}
+	r := l.entryRedactor()
+	if !l.noLock {
+		l.mu.RUnlock()
+	}
+
+	// Classified values may be withheld from some handlers, so they
+	// are masked like redacted keys
+	masked := func(key string) bool {
+		return r.redactsKey(key) || l.classes.lookup(key) >= SensitivityPII
+	}
+	msg := renderTemplate(template, r.transformValues(entryFields), masked)
+	entryFields[MessageTemplateKey] = template
+	l.write(context.Background(), level, msg, entryFields)
+}
+
+// renderTemplate replaces {key} placeholders with field values
+func renderTemplate(template string, fields map[string]interface{}, masked func(key string) bool) string {
+	if !strings.ContainsAny(template, "{}") {
+		return template
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '{' && strings.HasPrefix(template[i:], "{{"),
+			c == '}' && strings.HasPrefix(template[i:], "}}"):
+			b.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(template[i:], '}')
+			if end < 0 {
+				b.WriteString(template[i:])
+				return b.String()
+			}
+			key := template[i+1 : i+end]
+			if value, ok := fields[key]; ok {
+				if masked(key) {
+					b.WriteString(RedactedValue)
+				} else {
+					fmt.Fprint(&b, value)
+				}
+			} else {
+				b.WriteString(template[i : i+end+1])
+			}
+			i += end
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"regexp"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// TenantKey is the field key tagging entries with their tenant
+const TenantKey = "tenant_id"
+
+// tenantRules holds per-tenant redactions and the tenants isolated to
+// their dedicated handlers. It is shared by a logger and its children.
+type tenantRules struct {
+	active     atomic.Bool
+	redactions map[string]redactor
+	isolated   map[string]struct{}
+	mu         sync.RWMutex
+}
+
+// redactor returns the redactions of a tenant
+func (t *tenantRules) redactor(tenant string) (redactor, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	r, ok := t.redactions[tenant]
+	return r, ok
+}
+
+// isIsolated reports whether the tenant's entries only go to its
+// dedicated handlers
+func (t *tenantRules) isIsolated(tenant string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, ok := t.isolated[tenant]
+	return ok
+}
+
+// WithTenant creates a new logger tagging entries with the tenant ID,
+// applying the tenant's redactions and routing its entries to the
+// tenant's dedicated handlers
+func (l *Logger) WithTenant(id string) *Logger {
+	l.tenants.active.Store(true)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	tenantLogger := l.clone()
+	tenantLogger.tenant = id
+	tenantLogger.Logger = l.Logger.With(zap.String(l.mapKey(TenantKey), id))
+	return tenantLogger
+}
+
+// AddTenantRedaction adds a redaction pattern applied to entries of one
+// tenant only
+func (l *Logger) AddTenantRedaction(tenant string, pattern *regexp.Regexp, replacement string) {
+	l.updateTenantRedactor(tenant, func(r *redactor) {
+		r.redactions = append(append([]redaction{}, r.redactions...), redaction{regex: pattern, replacement: replacement})
+	})
+}
+
+// AddTenantRedactFields adds field keys whose values are redacted in
+// entries of one tenant only
+func (l *Logger) AddTenantRedactFields(tenant string, keys ...string) {
+	l.updateTenantRedactor(tenant, func(r *redactor) {
+		redactKeys := make(map[string]struct{}, len(r.keys)+len(keys))
+		for k := range r.keys {
+			redactKeys[k] = struct{}{}
+		}
+		for _, k := range keys {
+			redactKeys[k] = struct{}{}
+		}
+		r.keys = redactKeys
+	})
+}
+
+// updateTenantRedactor applies update to a copy of a tenant's redactions
+func (l *Logger) updateTenantRedactor(tenant string, update func(r *redactor)) {
+	l.tenants.mu.Lock()
+	defer l.tenants.mu.Unlock()
+
+	if l.tenants.redactions == nil {
+		l.tenants.redactions = map[string]redactor{}
+	}
+	r := l.tenants.redactions[tenant]
+	update(&r)
+	l.tenants.redactions[tenant] = r
+	rulesGeneration.Add(1)
+}
+
+// IsolateTenants restricts the entries of the given tenants to handlers
+// dedicated to them with ForTenants, keeping them out of shared handlers
+func (l *Logger) IsolateTenants(ids ...string) {
+	l.tenants.mu.Lock()
+	defer l.tenants.mu.Unlock()
+
+	if l.tenants.isolated == nil {
+		l.tenants.isolated = map[string]struct{}{}
+	}
+	for _, id := range ids {
+		l.tenants.isolated[id] = struct{}{}
+	}
+}
+
+// ForTenants dedicates a handler to the given tenants, so it only
+// receives their entries
+func ForTenants(ids ...string) HandlerOption {
+	return func(o *handlerOptions) {
+		if o.tenants == nil {
+			o.tenants = map[string]struct{}{}
+		}
+		for _, id := range ids {
+			o.tenants[id] = struct{}{}
+		}
+	}
+}
+
+// accepts returns a filter reporting whether a handler dedicated to
+// tenants, or a shared handler if tenants is nil, takes a tenant's entries
+func (t *tenantRules) accepts(tenants map[string]struct{}) func(tenant string) bool {
+	return func(tenant string) bool {
+		if tenants != nil {
+			_, ok := tenants[tenant]
+			return ok
+		}
+		return tenant == "" || !t.active.Load() || !t.isIsolated(tenant)
+	}
+}
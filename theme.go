@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// ConsoleTheme colors console output. Colors are ANSI SGR parameters
+// such as "31" for red or "1;33" for bold yellow; empty leaves text
+// uncolored.
+type ConsoleTheme struct {
+	// Levels colors the level of entries at each level
+	Levels map[LogLevel]string
+	// Messages colors the message of entries at each level
+	Messages map[LogLevel]string
+	// Keys highlights fields, which are moved next to the message
+	Keys map[string]string
+}
+
+// Built-in themes, selectable by name with ThemeByName
+var (
+	// ThemeDark suits terminals with dark backgrounds
+	ThemeDark = ConsoleTheme{
+		Levels: map[LogLevel]string{
+			zapcore.DebugLevel:  "90",
+			zapcore.InfoLevel:   "36",
+			zapcore.WarnLevel:   "33",
+			zapcore.ErrorLevel:  "31",
+			zapcore.DPanicLevel: "1;31",
+			zapcore.PanicLevel:  "1;31",
+			zapcore.FatalLevel:  "1;31",
+		},
+		Messages: map[LogLevel]string{
+			zapcore.DebugLevel:  "90",
+			zapcore.WarnLevel:   "33",
+			zapcore.ErrorLevel:  "1",
+			zapcore.DPanicLevel: "1;31",
+			zapcore.PanicLevel:  "1;31",
+			zapcore.FatalLevel:  "1;31",
+		},
+		Keys: map[string]string{
+			RequestIDKey: "35",
+			"trace_id":   "35",
+		},
+	}
+
+	// ThemeLight suits terminals with light backgrounds
+	ThemeLight = ConsoleTheme{
+		Levels: map[LogLevel]string{
+			zapcore.DebugLevel:  "2",
+			zapcore.InfoLevel:   "34",
+			zapcore.WarnLevel:   "35",
+			zapcore.ErrorLevel:  "31",
+			zapcore.DPanicLevel: "1;31",
+			zapcore.PanicLevel:  "1;31",
+			zapcore.FatalLevel:  "1;31",
+		},
+		Messages: map[LogLevel]string{
+			zapcore.DebugLevel:  "2",
+			zapcore.ErrorLevel:  "1",
+			zapcore.DPanicLevel: "1;31",
+			zapcore.PanicLevel:  "1;31",
+			zapcore.FatalLevel:  "1;31",
+		},
+		Keys: map[string]string{
+			RequestIDKey: "34",
+			"trace_id":   "34",
+		},
+	}
+
+	// ThemeMonochrome uses no colors, only bold for errors
+	ThemeMonochrome = ConsoleTheme{
+		Levels: map[LogLevel]string{
+			zapcore.ErrorLevel:  "1",
+			zapcore.DPanicLevel: "1",
+			zapcore.PanicLevel:  "1",
+			zapcore.FatalLevel:  "1",
+		},
+	}
+)
+
+// ThemeByName returns the built-in theme dark, light or monochrome
+func ThemeByName(name string) (ConsoleTheme, error) {
+	switch strings.ToLower(name) {
+	case "dark":
+		return ThemeDark, nil
+	case "light":
+		return ThemeLight, nil
+	case "monochrome":
+		return ThemeMonochrome, nil
+	}
+	return ConsoleTheme{}, fmt.Errorf("unknown theme %q, use dark, light or monochrome", name)
+}
+
+// WithTheme colors a console handler's levels, messages and highlighted
+// fields. Messages and fields are colored by development console
+// encodings only.
+func WithTheme(theme ConsoleTheme) HandlerOption {
+	return func(o *handlerOptions) {
+		o.theme = &theme
+	}
+}
+
+// ansiColor wraps s in the SGR color sequence, if any
+func ansiColor(s, color string) string {
+	if color == "" {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// levelEncoder returns a level encoder using the theme's colors
+func (t ConsoleTheme) levelEncoder() zapcore.LevelEncoder {
+	return func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(ansiColor(level.CapitalString(), t.Levels[level]))
+	}
+}
+
+// themedEncoder colors messages and moves highlighted fields next to
+// them
+type themedEncoder struct {
+	zapcore.Encoder
+	cfg   zapcore.EncoderConfig
+	theme ConsoleTheme
+	// highlighted holds the rendered highlighted fields added with With
+	highlighted string
+}
+
+// Clone implements zapcore.Encoder
+func (e *themedEncoder) Clone() zapcore.Encoder {
+	return &themedEncoder{Encoder: e.Encoder.Clone(), cfg: e.cfg, theme: e.theme, highlighted: e.highlighted}
+}
+
+// AddString implements zapcore.ObjectEncoder, keeping highlighted string
+// fields added with With out of the regular fields
+func (e *themedEncoder) AddString(key, value string) {
+	if _, ok := e.theme.Keys[key]; ok {
+		e.highlighted += e.highlight(zapcore.Field{Key: key, Type: zapcore.StringType, String: value})
+		return
+	}
+	e.Encoder.AddString(key, value)
+}
+
+// EncodeEntry implements zapcore.Encoder
+func (e *themedEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	ent.Message = ansiColor(ent.Message, e.theme.Messages[ent.Level]) + e.highlighted
+	if len(e.theme.Keys) == 0 {
+		return e.Encoder.EncodeEntry(ent, fields)
+	}
+
+	rest := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if _, ok := e.theme.Keys[f.Key]; ok {
+			ent.Message += e.highlight(f)
+			continue
+		}
+		rest = append(rest, f)
+	}
+	return e.Encoder.EncodeEntry(ent, rest)
+}
+
+// highlight renders a field as a colored key=value pair
+func (e *themedEncoder) highlight(f zapcore.Field) string {
+	pair := &fastConsoleEncoder{cfg: &e.cfg, buf: fastConsoleBufferPool.Get()}
+	defer pair.buf.Free()
+
+	f.AddTo(pair)
+	return " " + ansiColor(pair.buf.String(), e.theme.Keys[f.Key])
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ThrottleConfig configures adaptive throttling under log storms
+type ThrottleConfig struct {
+	// MaxPerSecond is the entry rate above which throttling starts
+	MaxPerSecond int
+	// Level is the lowest level exempt from throttling, defaults to Warn
+	Level *LogLevel
+	// SampleEvery keeps one in every SampleEvery throttled entries;
+	// zero or one drops them all
+	SampleEvery int
+}
+
+// throttle tracks the entry rate and decides which entries to drop.
+// It is shared by a logger and its children.
+type throttle struct {
+	cfg         ThrottleConfig
+	level       LogLevel
+	enabled     bool
+	active      bool
+	windowStart time.Time
+	count       int
+	seen        int
+	dropped     int
+	mu          sync.Mutex
+}
+
+// throttleNotice is an entry emitted when throttling starts or stops
+type throttleNotice struct {
+	msg    string
+	fields []zap.Field
+}
+
+// allow counts an entry and reports whether it should be written,
+// along with a notice to emit if throttling started or stopped
+func (t *throttle) allow(level LogLevel, now time.Time) (bool, *throttleNotice) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.enabled {
+		return true, nil
+	}
+
+	var notice *throttleNotice
+
+	// Roll the one-second window
+	if now.Sub(t.windowStart) >= time.Second {
+		if t.active && t.count <= t.cfg.MaxPerSecond {
+			t.active = false
+			notice = &throttleNotice{
+				msg: "Log throttling stopped",
+				fields: []zap.Field{
+					zap.Int("dropped", t.dropped),
+				},
+			}
+			t.dropped = 0
+		}
+		t.windowStart = now
+		t.count = 0
+	}
+
+	t.count++
+	if !t.active && t.count > t.cfg.MaxPerSecond {
+		t.active = true
+		t.seen = 0
+		notice = &throttleNotice{
+			msg: "Log throttling started",
+			fields: []zap.Field{
+				zap.Int("max_per_second", t.cfg.MaxPerSecond),
+				zap.Stringer("min_level", t.level),
+			},
+		}
+	}
+
+	if !t.active || level >= t.level {
+		return true, notice
+	}
+
+	t.seen++
+	if t.cfg.SampleEvery > 1 && t.seen%t.cfg.SampleEvery == 0 {
+		return true, notice
+	}
+	t.dropped++
+	return false, notice
+}
+
+// EnableThrottling enables adaptive throttling: while the entry rate
+// exceeds cfg.MaxPerSecond, entries below cfg.Level are sampled or dropped
+// and a notice is logged when throttling starts and stops
+func (l *Logger) EnableThrottling(cfg ThrottleConfig) {
+	level := zapcore.WarnLevel
+	if cfg.Level != nil {
+		level = *cfg.Level
+	}
+
+	l.throttle.mu.Lock()
+	defer l.throttle.mu.Unlock()
+
+	l.throttle.cfg = cfg
+	l.throttle.level = level
+	l.throttle.enabled = cfg.MaxPerSecond > 0
+	l.throttle.active = false
+	l.throttle.count = 0
+	l.throttle.dropped = 0
+}
+
+// DisableThrottling turns adaptive throttling off
+func (l *Logger) DisableThrottling() {
+	l.throttle.mu.Lock()
+	defer l.throttle.mu.Unlock()
+
+	l.throttle.enabled = false
+	l.throttle.active = false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Supported named time formats. Any other value is used as a
+// time.Format layout string.
+const (
+	TimeFormatISO8601     = "iso8601"
+	TimeFormatRFC3339     = "rfc3339"
+	TimeFormatRFC3339Nano = "rfc3339nano"
+	TimeFormatEpoch       = "epoch"
+	TimeFormatEpochMillis = "epoch_millis"
+	TimeFormatEpochNanos  = "epoch_nanos"
+)
+
+// newTimeEncoder returns a time encoder for the named format in the
+// given IANA time zone. Empty values select ISO8601 in local time.
+func newTimeEncoder(format, zone string) (zapcore.TimeEncoder, error) {
+	var loc *time.Location
+	if zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", zone, err)
+		}
+	}
+
+	var enc zapcore.TimeEncoder
+	switch strings.ToLower(format) {
+	case "", TimeFormatISO8601:
+		enc = zapcore.ISO8601TimeEncoder
+	case TimeFormatRFC3339:
+		enc = zapcore.RFC3339TimeEncoder
+	case TimeFormatRFC3339Nano:
+		enc = zapcore.RFC3339NanoTimeEncoder
+	case TimeFormatEpoch:
+		enc = zapcore.EpochTimeEncoder
+	case TimeFormatEpochMillis:
+		enc = zapcore.EpochMillisTimeEncoder
+	case TimeFormatEpochNanos:
+		enc = zapcore.EpochNanosTimeEncoder
+	default:
+		enc = zapcore.TimeEncoderOfLayout(format)
+	}
+
+	if loc == nil {
+		return enc, nil
+	}
+	return func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		enc(t.In(loc), pae)
+	}, nil
+}
+
+// SetTimeFormat sets the timestamp format and time zone used by handlers
+// added after the call. See the TimeFormat constants for named formats;
+// other values are treated as time.Format layouts.
+func (l *Logger) SetTimeFormat(format, zone string) error {
+	if _, err := newTimeEncoder(format, zone); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.timeFormat = format
+	l.timeZone = zone
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DurationKey is the field holding elapsed milliseconds, as in access logs
+const DurationKey = "duration_ms"
+
+// OperationKey is the field naming the timed operation
+const OperationKey = "operation"
+
+// Timer measures an operation and logs its duration when done
+type Timer struct {
+	logger    *Logger
+	operation string
+	start     time.Time
+	slow      time.Duration
+	level     LogLevel
+	slowLevel LogLevel
+}
+
+// StartTimer starts timing an operation, logged at Info by Done:
+//
+//	timer := logger.StartTimer("db_query")
+//	defer timer.Done(nil)
+func (l *Logger) StartTimer(operation string) *Timer {
+	return &Timer{
+		logger:    l,
+		operation: operation,
+		start:     time.Now(),
+		level:     zapcore.InfoLevel,
+		slowLevel: zapcore.WarnLevel,
+	}
+}
+
+// WarnAfter escalates the entry to Warn when the operation takes at
+// least threshold
+func (t *Timer) WarnAfter(threshold time.Duration) *Timer {
+	return t.EscalateAfter(threshold, zapcore.WarnLevel)
+}
+
+// EscalateAfter logs the entry at level when the operation takes at
+// least threshold
+func (t *Timer) EscalateAfter(threshold time.Duration, level LogLevel) *Timer {
+	t.slow, t.slowLevel = threshold, level
+	return t
+}
+
+// Elapsed returns the time since the timer started
+func (t *Timer) Elapsed() time.Duration {
+	return time.Since(t.start)
+}
+
+// Done logs the operation's duration with fields and returns it
+func (t *Timer) Done(fields map[string]interface{}) time.Duration {
+	elapsed := t.Elapsed()
+
+	level := t.level
+	if t.slow > 0 && elapsed >= t.slow {
+		level = t.slowLevel
+	}
+
+	entryFields := make(map[string]interface{}, len(fields)+2)
+	for k, v := range fields {
+		entryFields[k] = v
+	}
+	entryFields[OperationKey] = t.operation
+	entryFields[DurationKey] = float64(elapsed.Microseconds()) / 1000
+
+	t.logger.log(level, t.operation+" completed", entryFields)
+	return elapsed
+}
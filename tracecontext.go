@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// W3C trace context and baggage header names
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+	BaggageHeader     = "baggage"
+)
+
+// Limits on trace context taken from incoming headers, which are under
+// the caller's control
+const (
+	// maxTracestateLength is the length above which W3C allows
+	// tracestate to be dropped
+	maxTracestateLength = 512
+	// maxBaggageMembers bounds the baggage members logged per request
+	maxBaggageMembers = 16
+	// maxBaggageKeyLength and maxBaggageValueLength bound each member;
+	// longer members are skipped
+	maxBaggageKeyLength   = 64
+	maxBaggageValueLength = 256
+)
+
+// TraceContext holds the fields of W3C traceparent and tracestate
+// headers
+type TraceContext struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags string
+	TraceState string
+}
+
+// Sampled reports whether the sampled flag is set
+func (tc TraceContext) Sampled() bool {
+	return len(tc.TraceFlags) == 2 && fromHex(tc.TraceFlags[1])&0x1 == 1
+}
+
+type traceContextKey struct{}
+
+// ContextWithTraceContext returns a copy of ctx carrying the trace context
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context stored in ctx
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
+
+// ParseTraceparent parses a traceparent header of the form
+// "version-traceid-spanid-flags"
+func ParseTraceparent(header string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 {
+		return TraceContext{}, false
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" {
+		return TraceContext{}, false
+	}
+	// Version 00 must have exactly four parts
+	if version == "00" && len(parts) != 4 {
+		return TraceContext{}, false
+	}
+	if !isLowerHex(traceID, 32) || traceID == strings.Repeat("0", 32) {
+		return TraceContext{}, false
+	}
+	if !isLowerHex(spanID, 16) || spanID == strings.Repeat("0", 16) {
+		return TraceContext{}, false
+	}
+	if !isLowerHex(flags, 2) {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: flags,
+	}, true
+}
+
+// ParseTraceContext parses the traceparent and tracestate headers looked
+// up by get. Tracestate longer than 512 characters is dropped.
+func ParseTraceContext(get func(key string) string) (TraceContext, bool) {
+	tc, ok := ParseTraceparent(get(TraceparentHeader))
+	if !ok {
+		return TraceContext{}, false
+	}
+	// tracestate is only meaningful alongside a valid traceparent
+	if state := strings.TrimSpace(get(TracestateHeader)); len(state) <= maxTracestateLength {
+		tc.TraceState = state
+	}
+	return tc, true
+}
+
+// ParseBaggage parses a W3C baggage header into key/value pairs.
+// Properties attached to members are ignored. Since the header comes
+// from the caller, only the first 16 members are kept, and members with
+// invalid keys, keys over 64 bytes or values over 256 bytes are skipped.
+func ParseBaggage(header string) map[string]string {
+	baggage := make(map[string]string)
+	for _, member := range strings.Split(header, ",") {
+		if len(baggage) == maxBaggageMembers {
+			break
+		}
+		// Drop member properties
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if !isToken(key) || len(key) > maxBaggageKeyLength {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			value = unescaped
+		}
+		if len(value) > maxBaggageValueLength {
+			continue
+		}
+		baggage[key] = value
+	}
+	return baggage
+}
+
+// TraceFields extracts correlation fields from trace context headers.
+// get looks up a header by name, which allows the same extraction to be
+// used with HTTP headers and gRPC metadata alike. This module doesn't
+// depend on gRPC, so gRPC servers call it from their own interceptors:
+//
+//	func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+//		md, _ := metadata.FromIncomingContext(ctx)
+//		get := func(key string) string {
+//			if v := md.Get(key); len(v) > 0 {
+//				return v[0]
+//			}
+//			return ""
+//		}
+//		ctx = ContextWithLogger(ctx, logger.WithContext(TraceFields(get)))
+//		if tc, ok := ParseTraceContext(get); ok {
+//			ctx = ContextWithTraceContext(ctx, tc)
+//		}
+//		return handler(ctx, req)
+//	}
+func TraceFields(get func(key string) string) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	if tc, ok := ParseTraceContext(get); ok {
+		fields["trace_id"] = tc.TraceID
+		fields["span_id"] = tc.SpanID
+		fields["trace_flags"] = tc.TraceFlags
+		if tc.TraceState != "" {
+			fields["tracestate"] = tc.TraceState
+		}
+	}
+
+	if header := get(BaggageHeader); header != "" {
+		for key, value := range ParseBaggage(header) {
+			fields["baggage."+key] = value
+		}
+	}
+
+	return fields
+}
+
+// isLowerHex reports whether s is n lowercase hexadecimal characters
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isToken reports whether s is a non-empty RFC 7230 token, as baggage
+// keys are
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fromHex converts a single lowercase hexadecimal character to its value
+func fromHex(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
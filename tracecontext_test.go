@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceContextFillsTraceState(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Set(TracestateHeader, "vendor=abc")
+
+	tc, ok := ParseTraceContext(header.Get)
+	if !ok || tc.TraceState != "vendor=abc" {
+		t.Fatalf("ParseTraceContext = %+v, %v", tc, ok)
+	}
+
+	header.Set(TracestateHeader, strings.Repeat("x", maxTracestateLength+1))
+	if tc, _ := ParseTraceContext(header.Get); tc.TraceState != "" {
+		t.Errorf("oversized tracestate kept: %d bytes", len(tc.TraceState))
+	}
+}
+
+func TestParseBaggageIsBounded(t *testing.T) {
+	var members []string
+	for i := 0; i < 100; i++ {
+		members = append(members, fmt.Sprintf("k%d=v", i))
+	}
+	if got := len(ParseBaggage(strings.Join(members, ","))); got != maxBaggageMembers {
+		t.Errorf("kept %d members, want %d", got, maxBaggageMembers)
+	}
+
+	baggage := ParseBaggage("user=alice,big=" + strings.Repeat("x", maxBaggageValueLength+1) + ",bad key=1,a/b=2")
+	if len(baggage) != 1 || baggage["user"] != "alice" {
+		t.Errorf("baggage = %v, want only user", baggage)
+	}
+}
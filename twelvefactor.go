@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// ErrFileHandlersDisabled is returned when adding a file handler to a
+// logger in twelve-factor mode
+var ErrFileHandlersDisabled = errors.New("file handlers are disabled in twelve-factor mode")
+
+// EnableTwelveFactor makes the logger follow twelve-factor apps, for
+// platforms such as Heroku, Cloud Foundry and Kubernetes that collect
+// stdout: file handlers are refused, and handlers added afterwards
+// write each entry before the logging call returns, without async
+// workers, batching or backpressure buffers, so nothing is lost when
+// the platform stops the process. Handlers are synced when the process
+// receives SIGTERM or SIGINT, which is then raised again so the default
+// action or the application's own handling proceeds; Close stops this.
+// Config.TwelveFactor also makes stdout the single JSON stream.
+func (l *Logger) EnableTwelveFactor() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.twelveFactor = true
+	l.exitFlush.start(l.coreWrapper.Sync)
+}
+
+// exitSignals are the signals platforms stop processes with
+var exitSignals = []os.Signal{syscall.SIGTERM, os.Interrupt}
+
+// exitFlush syncs handlers when the process is asked to stop. It is
+// shared by a logger and its children.
+type exitFlush struct {
+	signals chan os.Signal
+	mu      sync.Mutex
+}
+
+// start syncs on the first exit signal, unless already started
+func (f *exitFlush) start(sync func() error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.signals != nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, exitSignals...)
+	f.signals = signals
+
+	go func() {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		signal.Stop(signals)
+		sync()
+		if process, err := os.FindProcess(os.Getpid()); err == nil {
+			process.Signal(sig)
+		}
+	}()
+}
+
+// stop stops waiting for exit signals
+func (f *exitFlush) stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.signals == nil {
+		return
+	}
+	signal.Stop(f.signals)
+	close(f.signals)
+	f.signals = nil
+}
+
+// withTwelveFactor configures a single uncolored JSON stream on stdout
+func (c Config) withTwelveFactor() Config {
+	if !c.TwelveFactor {
+		return c
+	}
+	c.Development = false
+	c.ColorMode = ColorNever
+	if c.ConsoleLevel == nil {
+		c.ConsoleLevel = &c.Level
+	}
+
+	// The console handler is the stream, configured handlers would
+	// duplicate it
+	c.FileConfig = nil
+	c.Handlers = nil
+	return c
+}
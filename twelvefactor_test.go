@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewLoggerWithConfigValidates(t *testing.T) {
+	_, err := NewLoggerWithConfig(Config{
+		Name:         "app",
+		TwelveFactor: true,
+		FileConfig:   map[string]LogLevel{filepath.Join(t.TempDir(), "app.log"): zapcore.InfoLevel},
+	})
+	if err == nil {
+		t.Fatal("file handlers were accepted in twelve-factor mode")
+	}
+}
+
+func TestCloseRemovesHandlers(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.EnableTwelveFactor()
+	observed := observe(l, zapcore.InfoLevel)
+	l.Info("written")
+
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(l.Handlers()); n != 0 {
+		t.Errorf("%d handlers left after Close", n)
+	}
+	if l.exitFlush.signals != nil {
+		t.Error("still waiting for exit signals after Close")
+	}
+	if observed.Len() != 1 {
+		t.Errorf("got %d entries, want 1", observed.Len())
+	}
+	if err := l.AddFileHandler("app.log", zapcore.InfoLevel); !errors.Is(err, ErrFileHandlersDisabled) {
+		t.Errorf("AddFileHandler = %v, want file handlers disabled", err)
+	}
+}
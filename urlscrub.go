@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultURLParams are the query parameters masked by EnableURLScrubbing
+// when none are given
+var DefaultURLParams = []string{"token", "access_token", "password", "api_key", "apikey", "signature", "secret"}
+
+// URLRedactedValue replaces masked URL passwords and query values. Unlike
+// RedactedValue it needs no escaping inside URLs.
+const URLRedactedValue = "REDACTED"
+
+// urlPattern matches URLs embedded in messages and field values
+var urlPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s"'<>]+`)
+
+// URLScrubConfig configures EnableURLScrubbing
+type URLScrubConfig struct {
+	// Params are the query parameter names to scrub, case-insensitively.
+	// Defaults to DefaultURLParams.
+	Params []string
+	// Strip removes the parameters instead of masking their values
+	Strip bool
+}
+
+// EnableURLScrubbing scrubs sensitive query parameters and passwords of
+// URLs in messages and fields, keeping the rest of the URL for debugging
+func (l *Logger) EnableURLScrubbing(cfg URLScrubConfig) {
+	if len(cfg.Params) == 0 {
+		cfg.Params = DefaultURLParams
+	}
+	params := make(map[string]struct{}, len(cfg.Params))
+	for _, p := range cfg.Params {
+		params[strings.ToLower(p)] = struct{}{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.redactions = append(l.redactions, redaction{
+		name:  "url",
+		regex: urlPattern,
+		replace: func(raw string) string {
+			return scrubURL(raw, params, cfg.Strip)
+		},
+	})
+	l.publishRedactor()
+}
+
+// scrubURL masks or strips the given query parameters and any password
+// of raw. Values that don't parse as URLs are returned unchanged.
+func scrubURL(raw string, params map[string]struct{}, strip bool) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), URLRedactedValue)
+	}
+
+	// Rewrite the raw query to keep parameter order and encoding
+	if u.RawQuery != "" {
+		pairs := strings.Split(u.RawQuery, "&")
+		kept := pairs[:0]
+		for _, pair := range pairs {
+			key, _, _ := strings.Cut(pair, "=")
+			name, err := url.QueryUnescape(key)
+			if err != nil {
+				name = key
+			}
+			if _, ok := params[strings.ToLower(name)]; ok {
+				if strip {
+					continue
+				}
+				pair = key + "=" + URLRedactedValue
+			}
+			kept = append(kept, pair)
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	return u.String()
+}
+
+// requestURI redacts a request URI such as "/callback?token=...": its
+// query is scrubbed as in absolute URLs, then the other redactions apply
+func (r redactor) requestURI(uri string) string {
+	for _, rd := range r.redactions {
+		if rd.regex == urlPattern && rd.replace != nil {
+			uri = rd.replace(uri)
+		}
+	}
+	return r.string(uri)
+}
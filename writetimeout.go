@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrWriteTimeout is returned by handlers whose sink didn't complete a
+// write within the handler's write timeout
+var ErrWriteTimeout = errors.New("write timed out")
+
+// WithWriteTimeout bounds every write to the handler's sink, so a hung
+// mount or connection can't block the logging pipeline. Entries written
+// while a timed out write is still pending are rejected.
+func WithWriteTimeout(timeout time.Duration) HandlerOption {
+	return func(o *handlerOptions) {
+		o.writeTimeout = timeout
+	}
+}
+
+// deadlineWriter is implemented by sinks supporting write deadlines,
+// such as network connections and pipes
+type deadlineWriter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// timeoutWriter bounds writes to a sink, using write deadlines where the
+// sink supports them and abandoning the write otherwise.
+// It implements zapcore.WriteSyncer.
+type timeoutWriter struct {
+	zapcore.WriteSyncer
+	timeout time.Duration
+	pending atomic.Bool
+}
+
+// Write implements zapcore.WriteSyncer
+func (w *timeoutWriter) Write(p []byte) (int, error) {
+	if d, ok := w.WriteSyncer.(deadlineWriter); ok {
+		// Regular files don't support deadlines and fall through
+		if err := d.SetWriteDeadline(time.Now().Add(w.timeout)); err == nil {
+			return w.WriteSyncer.Write(p)
+		}
+	}
+
+	// The encoder reuses its buffer, so keep a copy for abandoned writes
+	entry := append([]byte{}, p...)
+	return w.call(func() (int, error) {
+		return w.WriteSyncer.Write(entry)
+	})
+}
+
+// Sync implements zapcore.WriteSyncer
+func (w *timeoutWriter) Sync() error {
+	_, err := w.call(func() (int, error) {
+		return 0, w.WriteSyncer.Sync()
+	})
+	return err
+}
+
+// call runs fn, giving up after the timeout. Only one call may be
+// pending at a time, so a hung sink doesn't accumulate goroutines.
+func (w *timeoutWriter) call(fn func() (int, error)) (int, error) {
+	if !w.pending.CompareAndSwap(false, true) {
+		return 0, ErrWriteTimeout
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer w.pending.Store(false)
+		n, err := fn()
+		done <- result{n, err}
+	}()
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.n, r.err
+	case <-timer.C:
+		return 0, ErrWriteTimeout
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// With creates a new logger with additional zap fields, redacted and
+// key-mapped like WithContext. It shadows zap.Logger.With so context
+// added this way stays within the logging pipeline.
+func (l *Logger) With(fields ...zap.Field) *Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	withLogger := l.clone()
+	withLogger.Logger = withLogger.withBakedFields(staticFields(fields))
+	return withLogger
+}
+
+// Named creates a child logger with the given name, as Child does. It
+// shadows zap.Logger.Named.
+func (l *Logger) Named(name string) *Logger {
+	return l.Child(name)
+}
+
+// Sugar returns a zap.SugaredLogger writing through this logger: it
+// carries the logger's context, honours its levels and applies its
+// redaction and key mapping to every entry
+func (l *Logger) Sugar() *zap.SugaredLogger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	context := append([]zap.Field{}, l.context...)
+	return l.Logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &pipelineCore{Core: core, logger: l}
+	})).With(context...).Sugar()
+}
+
+// pipelineCore applies a Logger's levels, caller filters, redaction and
+// key mapping to entries written through the zap API. It runs outside the
+// logger's lock, so it reads the published rules and key mapping.
+type pipelineCore struct {
+	zapcore.Core
+	logger *Logger
+}
+
+// Enabled implements zapcore.Core
+func (p *pipelineCore) Enabled(lvl zapcore.Level) bool {
+	return p.logger.enabled(lvl) && p.Core.Enabled(lvl)
+}
+
+// With implements zapcore.Core
+func (p *pipelineCore) With(fields []zapcore.Field) zapcore.Core {
+	return &pipelineCore{Core: p.Core.With(p.prepare(fields)), logger: p.logger}
+}
+
+// Check implements zapcore.Core
+func (p *pipelineCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !p.Enabled(ent.Level) {
+		return ce
+	}
+	if p.logger.callerFilters.drop(ent.Level) {
+		p.logger.stats.dropped.Add(1)
+		return ce
+	}
+	return ce.AddCore(ent, p)
+}
+
+// Write implements zapcore.Core
+func (p *pipelineCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	r := p.logger.publishedEntryRedactor()
+	if skipsRedaction(fields) {
+		r = p.logger.unredacted()
+	}
+	ent.Message = r.string(ent.Message)
+	p.logger.stats.recordEntry(ent.Level)
+	return p.Core.Write(ent, p.prepareWith(r, fields))
+}
+
+// prepare redacts and key-maps a copy of fields
+func (p *pipelineCore) prepare(fields []zapcore.Field) []zapcore.Field {
+	return p.prepareWith(p.logger.publishedEntryRedactor(), fields)
+}
+
+// prepareWith redacts a copy of fields with r and maps their keys
+func (p *pipelineCore) prepareWith(r redactor, fields []zapcore.Field) []zapcore.Field {
+	prepared := r.fields(append([]zapcore.Field{}, fields...))
+	p.logger.mapFieldKeys(prepared)
+	return prepared
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCheckGatesHandlersByLevel(t *testing.T) {
+	l := NewLogger("app", zapcore.DebugLevel)
+	info := observe(l, zapcore.InfoLevel)
+	warn := observe(l, zapcore.WarnLevel)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+
+	if got := messages(info); !equalStrings(got, []string{"info", "warn"}) {
+		t.Errorf("Info handler got %q", got)
+	}
+	if got := messages(warn); !equalStrings(got, []string{"warn"}) {
+		t.Errorf("Warn handler got %q", got)
+	}
+}
+
+func TestWriteGatesAndRedactsLikeCheck(t *testing.T) {
+	l := NewLogger("app", zapcore.DebugLevel)
+	l.AddRedaction(regexp.MustCompile(`secret-\w+`), "[SECRET]")
+	info := observe(l, zapcore.InfoLevel)
+	warn := observe(l, zapcore.WarnLevel)
+
+	// Writing to the core directly skips Check
+	core := l.Logger.Core()
+	ent := zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "token secret-abc"}
+	if err := core.Write(ent, []zapcore.Field{zap.String("token", "secret-def")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if warn.Len() != 0 {
+		t.Errorf("Warn handler got %d Info entries", warn.Len())
+	}
+	entries := info.All()
+	if len(entries) != 1 {
+		t.Fatalf("Info handler got %d entries, want 1", len(entries))
+	}
+	if entries[0].Message != "token [SECRET]" {
+		t.Errorf("message = %q, want redacted", entries[0].Message)
+	}
+	if got := entries[0].ContextMap()["token"]; got != "[SECRET]" {
+		t.Errorf("token = %v, want redacted", got)
+	}
+}
+
+func TestCheckFansOutToEveryAcceptingHandler(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("password")
+	first := observe(l, zapcore.InfoLevel)
+	second := observe(l, zapcore.InfoLevel)
+
+	l.Info("login", map[string]interface{}{"password": "hunter2"})
+
+	for i, logs := range []*observer.ObservedLogs{first, second} {
+		entries := logs.All()
+		if len(entries) != 1 {
+			t.Fatalf("handler %d got %d entries, want exactly 1", i, len(entries))
+		}
+		if got := entries[0].ContextMap()["password"]; got != RedactedValue {
+			t.Errorf("handler %d: password = %v, want redacted", i, got)
+		}
+	}
+}
+
+// messages returns the messages of the observed entries
+func messages(logs *observer.ObservedLogs) []string {
+	var msgs []string
+	for _, entry := range logs.All() {
+		msgs = append(msgs, entry.Message)
+	}
+	return msgs
+}
+
+// equalStrings reports whether a and b hold the same strings in order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSugarStaysInPipeline(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddRedactFields("password")
+	logs := observe(l, zapcore.InfoLevel)
+
+	sugar := l.Sugar()
+	sugar.Debugw("hidden")
+	sugar.Infow("login", "password", "hunter2")
+	l.With(zap.String("password", "hunter2")).Logger.Info("with")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		if got := entry.ContextMap()["password"]; got != RedactedValue {
+			t.Errorf("%s: password = %v, want redacted", entry.Message, got)
+		}
+	}
+}
+
+func TestSugarRacesWithRuleChanges(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	observe(l, zapcore.InfoLevel)
+	sugar := l.Sugar()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			l.AddRedaction(regexp.MustCompile(`secret-\w+`), "[SECRET]")
+			l.SetKeyMapping(map[string]string{"user_id": "usr.id"})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		sugar.Infow("login secret-abc", "user_id", 7)
+	}
+	<-done
+}
+
+func TestErrorsAreRedacted(t *testing.T) {
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddSecrets("sk-live-999")
+	logs := observe(l, zapcore.InfoLevel)
+	err := errors.New("auth with sk-live-999 failed")
+
+	l.Sugar().Errorw("failed", "error", err)
+	l.With(zap.Error(err)).Info("failed")
+
+	for i, entry := range logs.All() {
+		if got := entry.ContextMap()["error"]; got != "auth with "+RedactedValue+" failed" {
+			t.Errorf("entry %d error = %v", i, got)
+		}
+	}
+}
+
+func TestReflectedValuesAreRedacted(t *testing.T) {
+	type credentials struct {
+		User     string   `json:"user"`
+		Password string   `json:"password"`
+		Notes    []string `json:"notes"`
+	}
+	l := NewLogger("app", zapcore.InfoLevel)
+	l.AddSecrets("sk-live-999")
+	l.AddRedactFields("password")
+	logs := observe(l, zapcore.InfoLevel)
+
+	l.Sugar().Infow("login",
+		"creds", credentials{User: "alice", Password: "hunter2", Notes: []string{"key sk-live-999"}},
+		"unencodable", func() {})
+
+	fields := logs.All()[0].ContextMap()
+	creds := fields["creds"].(map[string]interface{})
+	if creds["user"] != "alice" || creds["password"] != RedactedValue {
+		t.Errorf("creds = %v", creds)
+	}
+	if notes := creds["notes"].([]interface{}); notes[0] != "key "+RedactedValue {
+		t.Errorf("notes = %v", notes)
+	}
+	if fields["unencodable"] != RedactedValue {
+		t.Errorf("unencodable = %v, want withheld", fields["unencodable"])
+	}
+}